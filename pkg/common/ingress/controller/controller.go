@@ -56,10 +56,12 @@ type GenericController struct {
 type Configuration struct {
 	Client       types.Client
 	MasterSocket string
+	AdminSocket  string
 
 	RateLimitUpdate  float32
 	ReloadInterval   time.Duration
 	ResyncPeriod     time.Duration
+	UpdateWindow     time.Duration
 	WaitBeforeUpdate time.Duration
 
 	DefaultService           string
@@ -74,9 +76,12 @@ type Configuration struct {
 	ReloadStrategy    string
 	MaxOldConfigFiles int
 	ValidateConfig    bool
+	ConfigTimestamp   bool
 
 	ForceNamespaceIsolation bool
 	WaitBeforeShutdown      int
+	ShutdownCmd             string
+	ShutdownTimeout         time.Duration
 	AllowCrossNamespace     bool
 	DisablePodList          bool
 	DisableExternalName     bool
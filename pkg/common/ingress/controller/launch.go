@@ -66,6 +66,11 @@ kubernetes.io/ingress.class annotation if both are defined and conflicting.`)
 are cleaned up. A value <= 0 indicates only a single non-timestamped config
 file will be retained.`)
 
+		configTimestamp = flags.Bool("config-timestamp", false,
+			`Defines if the generation time should be added in the version comment on top
+of the HAProxy configuration file. Default value is false, which means that
+only the controller version is added.`)
+
 		validateConfig = flags.Bool("validate-config", false,
 			`Define if the resulting configuration files should be validated when a dynamic
 update was applied. Default value is false, which means the validation will
@@ -92,6 +97,11 @@ declare neither the kubernetes.io/ingress.class annotation nor the
 			`Defines the master CLI unix socket of an external HAProxy running in
 master-worker mode. Defaults to use the embedded HAProxy if not declared.`)
 
+		adminSocket = flags.String("admin-socket", "/var/run/haproxy/admin.sock",
+			`Defines the path of the admin socket of the embedded HAProxy, declared as a
+stats socket in the global section and used by the controller to apply dynamic
+updates and by the reload script to read the listening sockets`)
+
 		configMap = flags.String("configmap", "",
 			`Name of the ConfigMap that contains the custom configuration to use`)
 
@@ -157,6 +167,12 @@ that if two distinct and consecutive configuration changes enforce a reload,
 the second reload will be enqueued until 30 seconds have passed from the first
 one, applying every new configuration changes made between this interval`)
 
+		updateWindow = flags.Duration("update-window", 0,
+			`Amount of time to coalesce consecutive haproxy updates into a single one. The
+default value is 0, which means to update haproxy just after every reconciliation.
+If configured, the first update starts a window of this duration and all the
+updates requested within this window are merged, using the last configuration`)
+
 		waitBeforeUpdate = flags.Duration("wait-before-update", 200*time.Millisecond,
 			`Amount of time to wait before start a reconciliation and update haproxy, giving
 the time to receive all/most of the changes of a batch update.`)
@@ -205,6 +221,15 @@ namespace than the specified in the flag --watch-namespace.`)
 			`Define time controller waits until it shuts down when SIGTERM signal was
 received`)
 
+		shutdownCmd = flags.String("shutdown-cmd", "",
+			`Command used to gracefully stop the embedded HAProxy when the controller
+is shutting down, eg /haproxy-shutdown.sh which soft-stops HAProxy and waits
+for its connections to drain. HAProxy is left running by default.`)
+
+		shutdownTimeout = flags.Duration("shutdown-timeout", 30*time.Second,
+			`Maximum time to wait for the command configured in --shutdown-cmd to
+finish. The command is killed after this time.`)
+
 		allowCrossNamespace = flags.Bool("allow-cross-namespace", false,
 			`Defines if the ingress controller can reference resources of another
 namespaces. Cannot be used if force-namespace-isolation is true`)
@@ -444,6 +469,10 @@ tracked.`)
 			sortEndpoints = "endpoint"
 		}
 	}
+	if *adminSocket == "" {
+		glog.Fatalf("--admin-socket cannot be empty")
+	}
+
 	if !stringInSlice(sortEndpoints, []string{"ep", "endpoint", "ip", "name", "random"}) {
 		glog.Fatalf("Unsupported --sort-endpoint-by option: %s", sortEndpoints)
 	}
@@ -453,6 +482,7 @@ tracked.`)
 		ElectionID:               *electionID,
 		Client:                   kubeClient,
 		MasterSocket:             *masterSocket,
+		AdminSocket:              *adminSocket,
 		AcmeServer:               *acmeServer,
 		AcmeCheckPeriod:          *acmeCheckPeriod,
 		AcmeElectionID:           *acmeElectionID,
@@ -465,6 +495,7 @@ tracked.`)
 		RateLimitUpdate:          *rateLimitUpdate,
 		ReloadInterval:           *reloadInterval,
 		ResyncPeriod:             *resyncPeriod,
+		UpdateWindow:             *updateWindow,
 		WaitBeforeUpdate:         *waitBeforeUpdate,
 		DefaultService:           *defaultSvc,
 		IngressClass:             *ingressClass,
@@ -477,6 +508,7 @@ tracked.`)
 		ReloadStrategy:           *reloadStrategy,
 		MaxOldConfigFiles:        *maxOldConfigFiles,
 		ValidateConfig:           *validateConfig,
+		ConfigTimestamp:          *configTimestamp,
 		TCPConfigMapName:         *tcpConfigMapName,
 		AnnPrefix:                annPrefixList,
 		DefaultSSLCertificate:    *defSSLCertificate,
@@ -487,6 +519,8 @@ tracked.`)
 		Backend:                  backend,
 		ForceNamespaceIsolation:  *forceIsolation,
 		WaitBeforeShutdown:       *waitBeforeShutdown,
+		ShutdownCmd:              *shutdownCmd,
+		ShutdownTimeout:          *shutdownTimeout,
 		AllowCrossNamespace:      *allowCrossNamespace,
 		DisablePodList:           *disablePodList,
 		DisableExternalName:      *disableExternalName,
@@ -21,6 +21,7 @@ import (
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -391,6 +392,9 @@ func (c *k8scache) buildResourceName(defaultNamespace, kind, resourceName string
 func (c *k8scache) GetTLSSecretPath(defaultNamespace, secretName string, track []convtypes.TrackingRef) (file convtypes.CrtFile, err error) {
 	proto, content := getContentProtocol(secretName)
 	if proto == "file" {
+		if files := strings.Split(content, ","); len(files) > 1 {
+			return buildCombinedPEM(files)
+		}
 		if _, err := os.Stat(content); err != nil {
 			return file, err
 		}
@@ -414,14 +418,72 @@ func (c *k8scache) GetTLSSecretPath(defaultNamespace, secretName string, track [
 		return file, fmt.Errorf("secret '%s/%s' does not have keys 'tls.crt' and 'tls.key'", namespace, name)
 	}
 	file = convtypes.CrtFile{
-		Filename:   sslCert.PemFileName,
-		SHA1Hash:   sslCert.PemSHA,
-		CommonName: sslCert.Certificate.Subject.CommonName,
-		NotAfter:   sslCert.Certificate.NotAfter,
+		Filename:    sslCert.PemFileName,
+		SHA1Hash:    sslCert.PemSHA,
+		CommonName:  sslCert.Certificate.Subject.CommonName,
+		NotAfter:    sslCert.Certificate.NotAfter,
+		Certificate: sslCert.Certificate,
 	}
 	return file, nil
 }
 
+// buildCombinedPEM concatenates a certificate and its private key, declared
+// in distinct files, into a single pem file which can be used by haproxy.
+func buildCombinedPEM(files []string) (file convtypes.CrtFile, err error) {
+	if len(files) != 2 {
+		return file, fmt.Errorf("a certificate and a private key filename should be used")
+	}
+	crt, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		return file, err
+	}
+	key, err := ioutil.ReadFile(files[1])
+	if err != nil {
+		return file, err
+	}
+	name := "file_" + strings.Replace(strings.Trim(files[0], "/"), "/", "_", -1)
+	sslCert, err := ssl.AddOrUpdateCertAndKey(name, crt, key, []byte{})
+	if err != nil {
+		return file, fmt.Errorf("error combining '%s' and '%s': %v", files[0], files[1], err)
+	}
+	return convtypes.CrtFile{
+		Filename:    sslCert.PemFileName,
+		SHA1Hash:    sslCert.PemSHA,
+		CommonName:  sslCert.Certificate.Subject.CommonName,
+		NotAfter:    sslCert.Certificate.NotAfter,
+		Certificate: sslCert.Certificate,
+	}, nil
+}
+
+// buildCABundle concatenates CA certificates, declared in distinct files,
+// into a single bundle which can be used by haproxy as a ca-file.
+func buildCABundle(files []string) (file convtypes.File, err error) {
+	var bundle []byte
+	for _, f := range files {
+		ca, err := ioutil.ReadFile(f)
+		if err != nil {
+			return file, err
+		}
+		if block, _ := pem.Decode(ca); block == nil || block.Type != "CERTIFICATE" {
+			return file, fmt.Errorf("file '%s' does not have a PEM formatted certificate", f)
+		}
+		bundle = append(bundle, ca...)
+		if len(ca) > 0 && ca[len(ca)-1] != '\n' {
+			bundle = append(bundle, '\n')
+		}
+	}
+	// the name should identify the whole list, distinct bundles can share the first file
+	name := fmt.Sprintf("file_%x_bundle", sha1.Sum([]byte(strings.Join(files, "+"))))
+	sslCert, err := ssl.AddCertAuth(name, bundle, []byte{})
+	if err != nil {
+		return file, fmt.Errorf("error building CA bundle from '%s': %v", strings.Join(files, "+"), err)
+	}
+	return convtypes.File{
+		Filename: sslCert.CAFileName,
+		SHA1Hash: sslCert.PemSHA,
+	}, nil
+}
+
 func (c *k8scache) GetCASecretPath(defaultNamespace, secretName string, track []convtypes.TrackingRef) (ca, crl convtypes.File, err error) {
 	proto, content := getContentProtocol(secretName)
 	if proto == "file" {
@@ -432,12 +494,19 @@ func (c *k8scache) GetCASecretPath(defaultNamespace, secretName string, track []
 		if len(files) > 2 {
 			return ca, crl, fmt.Errorf("only one or two filenames should be used")
 		}
-		if _, err := os.Stat(files[0]); err != nil {
-			return ca, crl, err
-		}
-		ca = convtypes.File{
-			Filename: files[0],
-			SHA1Hash: "-",
+		if cafiles := strings.Split(files[0], "+"); len(cafiles) > 1 {
+			ca, err = buildCABundle(cafiles)
+			if err != nil {
+				return ca, crl, err
+			}
+		} else {
+			if _, err := os.Stat(files[0]); err != nil {
+				return ca, crl, err
+			}
+			ca = convtypes.File{
+				Filename: files[0],
+				SHA1Hash: "-",
+			}
 		}
 		if len(files) == 2 {
 			if _, err := os.Stat(files[1]); err != nil {
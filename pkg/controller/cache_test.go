@@ -17,7 +17,19 @@ limitations under the License.
 package controller
 
 import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/common/ingress"
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/common/net/ssl"
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy"
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/types/helper_test"
 )
 
 func TestGetContentProtocol(t *testing.T) {
@@ -79,3 +91,265 @@ func TestGetContentProtocol(t *testing.T) {
 		}
 	}
 }
+
+func TestGetTLSSecretPathCombined(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	crtDir := ingress.DefaultCrtDirectory
+	ingress.DefaultCrtDirectory = tempdir
+	defer func() { ingress.DefaultCrtDirectory = crtDir }()
+
+	crt, key := ssl.GetFakeSSLCert([]string{"haproxy-ingress"}, "d1.local", []string{"d1.local"})
+	crtFile := filepath.Join(tempdir, "tls.crt")
+	keyFile := filepath.Join(tempdir, "tls.key")
+	if err := ioutil.WriteFile(crtFile, crt, 0644); err != nil {
+		t.Fatalf("error writing crt file: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+	testCases := []struct {
+		input  string
+		expErr string
+	}{
+		// 0
+		{
+			input: "file://<dir>/tls.crt,<dir>/tls.key",
+		},
+		// 1
+		{
+			input:  "file://<dir>/tls.crt,<dir>/missing.key",
+			expErr: "open <dir>/missing.key: no such file or directory",
+		},
+		// 2
+		{
+			input:  "file://<dir>/tls.crt,<dir>/tls.key,<dir>/ca.crt",
+			expErr: "a certificate and a private key filename should be used",
+		},
+	}
+	replace := func(s string) string {
+		return strings.Replace(s, "<dir>", tempdir, -1)
+	}
+	cache := &k8scache{}
+	for i, test := range testCases {
+		file, err := cache.GetTLSSecretPath("default", replace(test.input), nil)
+		var errStr string
+		if err != nil {
+			errStr = strings.Replace(err.Error(), tempdir, "<dir>", -1)
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d, expected '%s' but was '%s'", i, test.expErr, errStr)
+			continue
+		}
+		if test.expErr != "" {
+			continue
+		}
+		expFile := filepath.Join(tempdir, "file_"+strings.Replace(strings.Trim(crtFile, "/"), "/", "_", -1)+".pem")
+		if file.Filename != expFile {
+			t.Errorf("filename differs on %d, expected %s but was %s", i, expFile, file.Filename)
+		}
+		if file.CommonName != "d1.local" {
+			t.Errorf("common name differs on %d, expected d1.local but was %s", i, file.CommonName)
+		}
+		content, err := ioutil.ReadFile(file.Filename)
+		if err != nil {
+			t.Errorf("error reading combined pem on %d: %v", i, err)
+		}
+		expContent := string(crt) + "\n" + string(key)
+		if string(content) != expContent {
+			t.Errorf("combined pem differs on %d, expected:\n%s\nbut was:\n%s", i, expContent, string(content))
+		}
+		// the combined pem should be the one referenced by haproxy
+		crtList := renderCrtList(t, tempdir, file.Filename)
+		expCrtList := []string{file.Filename + " !*"}
+		if !reflect.DeepEqual(crtList, expCrtList) {
+			t.Errorf("crt list differs on %d, expected %v but was %v", i, expCrtList, crtList)
+		}
+	}
+}
+
+// renderCrtList renders the crt list of a haproxy instance using crtFile as the
+// default certificate, and returns its entries without the comments.
+func renderCrtList(t *testing.T, tempdir, crtFile string) []string {
+	instance := haproxy.CreateInstance(&helper_test.LoggerMock{T: t}, haproxy.InstanceOptions{
+		HAProxyMapsDir: tempdir,
+		Metrics:        helper_test.NewMetricsMock(),
+		TemplatesDir:   "../../rootfs/etc/templates",
+	})
+	if err := instance.ParseTemplates(); err != nil {
+		t.Fatalf("error parsing templates: %v", err)
+	}
+	config := instance.Config()
+	config.Frontend().DefaultCrtFile = crtFile
+	if err := config.WriteFrontendMaps(); err != nil {
+		t.Fatalf("error writing frontend maps: %v", err)
+	}
+	content, err := ioutil.ReadFile(config.Frontend().CrtListFile)
+	if err != nil {
+		t.Fatalf("error reading crt list: %v", err)
+	}
+	var entries []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+func TestGetDHSecretPathFile(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	dhFile := filepath.Join(tempdir, "dhparam.pem")
+	if err := ioutil.WriteFile(dhFile, []byte("dh"), 0644); err != nil {
+		t.Fatalf("error writing dh file: %v", err)
+	}
+	testCases := []struct {
+		input   string
+		expFile string
+		expErr  string
+	}{
+		// 0
+		{
+			input:   "file://<dir>/dhparam.pem",
+			expFile: "<dir>/dhparam.pem",
+		},
+		// 1
+		{
+			input:  "file://<dir>/missing.pem",
+			expErr: "stat <dir>/missing.pem: no such file or directory",
+		},
+	}
+	replace := func(s string) string {
+		return strings.Replace(s, "<dir>", tempdir, -1)
+	}
+	cache := &k8scache{}
+	for i, test := range testCases {
+		dh, err := cache.GetDHSecretPath("default", replace(test.input))
+		var errStr string
+		if err != nil {
+			errStr = strings.Replace(err.Error(), tempdir, "<dir>", -1)
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d, expected '%s' but was '%s'", i, test.expErr, errStr)
+			continue
+		}
+		if dh.Filename != replace(test.expFile) {
+			t.Errorf("dh filename differs on %d, expected %s but was %s", i, replace(test.expFile), dh.Filename)
+		}
+	}
+}
+
+func TestGetCASecretPathBundle(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	caDir := ingress.DefaultCACertsDirectory
+	ingress.DefaultCACertsDirectory = tempdir
+	defer func() { ingress.DefaultCACertsDirectory = caDir }()
+
+	ca1, _ := ssl.GetFakeSSLCert([]string{"haproxy-ingress"}, "ca1", []string{})
+	ca2, _ := ssl.GetFakeSSLCert([]string{"haproxy-ingress"}, "ca2", []string{})
+	ca1File := filepath.Join(tempdir, "ca1.crt")
+	ca2File := filepath.Join(tempdir, "ca2.crt")
+	ca3File := filepath.Join(tempdir, "ca3.crt")
+	invalidFile := filepath.Join(tempdir, "invalid.crt")
+	if err := ioutil.WriteFile(ca1File, ca1, 0644); err != nil {
+		t.Fatalf("error writing ca1 file: %v", err)
+	}
+	if err := ioutil.WriteFile(ca2File, ca2, 0644); err != nil {
+		t.Fatalf("error writing ca2 file: %v", err)
+	}
+	if err := ioutil.WriteFile(ca3File, ca2, 0644); err != nil {
+		t.Fatalf("error writing ca3 file: %v", err)
+	}
+	if err := ioutil.WriteFile(invalidFile, []byte("invalid"), 0644); err != nil {
+		t.Fatalf("error writing invalid file: %v", err)
+	}
+	testCases := []struct {
+		input   string
+		expFile string
+		expCRL  string
+		expErr  string
+	}{
+		// 0
+		{
+			input:   "file://<dir>/ca1.crt",
+			expFile: "<dir>/ca1.crt",
+		},
+		// 1
+		{
+			input:   "file://<dir>/ca1.crt+<dir>/ca2.crt",
+			expFile: "<dir>/ca_file_<hash:<dir>/ca1.crt+<dir>/ca2.crt>_bundle.pem",
+		},
+		// 2
+		{
+			input:   "file://<dir>/ca1.crt+<dir>/ca2.crt,<dir>/ca1.crt",
+			expFile: "<dir>/ca_file_<hash:<dir>/ca1.crt+<dir>/ca2.crt>_bundle.pem",
+			expCRL:  "<dir>/ca1.crt",
+		},
+		// 3
+		{
+			input:   "file://<dir>/ca1.crt+<dir>/ca3.crt",
+			expFile: "<dir>/ca_file_<hash:<dir>/ca1.crt+<dir>/ca3.crt>_bundle.pem",
+		},
+		// 4
+		{
+			input:  "file://<dir>/ca1.crt+<dir>/missing.crt",
+			expErr: "open <dir>/missing.crt: no such file or directory",
+		},
+		// 5
+		{
+			input:  "file://<dir>/ca1.crt+<dir>/invalid.crt",
+			expErr: "file '<dir>/invalid.crt' does not have a PEM formatted certificate",
+		},
+	}
+	replace := func(s string) string {
+		s = strings.Replace(s, "<dir>", tempdir, -1)
+		if i := strings.Index(s, "<hash:"); i >= 0 {
+			j := strings.Index(s[i:], ">") + i
+			s = s[:i] + fmt.Sprintf("%x", sha1.Sum([]byte(s[i+6:j]))) + s[j+1:]
+		}
+		return s
+	}
+	cache := &k8scache{}
+	for i, test := range testCases {
+		ca, crl, err := cache.GetCASecretPath("default", replace(test.input), nil)
+		var errStr string
+		if err != nil {
+			errStr = strings.Replace(err.Error(), tempdir, "<dir>", -1)
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d, expected '%s' but was '%s'", i, test.expErr, errStr)
+			continue
+		}
+		if test.expErr != "" {
+			continue
+		}
+		if ca.Filename != replace(test.expFile) {
+			t.Errorf("ca filename differs on %d, expected %s but was %s", i, replace(test.expFile), ca.Filename)
+		}
+		if crl.Filename != replace(test.expCRL) {
+			t.Errorf("crl filename differs on %d, expected %s but was %s", i, replace(test.expCRL), crl.Filename)
+		}
+		if !strings.Contains(test.input, "+") {
+			continue
+		}
+		content, err := ioutil.ReadFile(ca.Filename)
+		if err != nil {
+			t.Errorf("error reading ca bundle on %d: %v", i, err)
+		}
+		expContent := string(ca1) + string(ca2)
+		if string(content) != expContent {
+			t.Errorf("ca bundle differs on %d, expected:\n%s\nbut was:\n%s", i, expContent, string(content))
+		}
+	}
+}
@@ -123,8 +123,9 @@ func (hc *HAProxyController) configController() {
 		HAProxyCfgDir:     "/etc/haproxy",
 		HAProxyMapsDir:    ingress.DefaultMapsDirectory,
 		MasterSocket:      hc.cfg.MasterSocket,
-		AdminSocket:       "/var/run/haproxy/admin.sock",
+		AdminSocket:       hc.cfg.AdminSocket,
 		BackendShards:     hc.cfg.BackendShards,
+		ConfigTimestamp:   hc.cfg.ConfigTimestamp,
 		AcmeSigner:        acmeSigner,
 		AcmeQueue:         hc.acmeQueue,
 		ReloadQueue:       hc.reloadQueue,
@@ -132,10 +133,14 @@ func (hc *HAProxyController) configController() {
 		Metrics:           hc.metrics,
 		ReloadStrategy:    hc.cfg.ReloadStrategy,
 		MaxOldConfigFiles: hc.cfg.MaxOldConfigFiles,
+		ShutdownCmd:       hc.cfg.ShutdownCmd,
 		SortEndpointsBy:   hc.cfg.SortEndpointsBy,
 		StopCh:            hc.stopCh,
 		TrackInstances:    hc.cfg.TrackOldInstances,
+		UpdateLocker:      &hc.writeModelMutex,
+		UpdateWindow:      hc.cfg.UpdateWindow,
 		ValidateConfig:    hc.cfg.ValidateConfig,
+		Version:           version.RELEASE,
 	}
 	hc.instance = haproxy.CreateInstance(hc.logger, instanceOptions)
 	if err := hc.instance.ParseTemplates(); err != nil {
@@ -253,6 +258,13 @@ func (hc *HAProxyController) Stop() error {
 		time.Sleep(waitBeforeShutdown)
 	}
 	err := hc.controller.Stop()
+	if hc.instance != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), hc.cfg.ShutdownTimeout)
+		defer cancel()
+		if errShutdown := hc.instance.Shutdown(ctx); errShutdown != nil {
+			glog.Warningf("error shutting down haproxy: %v", errShutdown)
+		}
+	}
 	return err
 }
 
@@ -327,7 +339,15 @@ func (hc *HAProxyController) syncIngress(item interface{}) {
 	//
 	// update proxy
 	//
-	hc.instance.Update(timer)
+	if err := hc.instance.Update(timer); err != nil {
+		// the failure is already logged by the instance
+		return
+	}
+	if hc.cfg.UpdateWindow > 0 {
+		// the instance logs when the scheduled update is applied
+		hc.logger.Info("haproxy update id=%d scheduled in %s: %s", hc.updateCount, hc.cfg.UpdateWindow, timer.AsString("total"))
+		return
+	}
 	hc.logger.Info("finish haproxy update id=%d: %s", hc.updateCount, timer.AsString("total"))
 }
 
@@ -339,6 +359,9 @@ func (hc *HAProxyController) reloadHAProxy(item interface{}) {
 	hc.logger.Info("starting haproxy reload id=%d", hc.reloadCount)
 	timer := utils.NewTimer(hc.metrics.ControllerProcTime)
 
-	hc.instance.Reload(timer)
+	if err := hc.instance.Reload(timer); err != nil {
+		// the failure is already logged by the instance
+		return
+	}
 	hc.logger.Info("finish haproxy reload id=%d: %s", hc.reloadCount, timer.AsString("total"))
 }
@@ -18,6 +18,7 @@ package helper_test
 
 import (
 	"crypto/sha1"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"strings"
@@ -49,6 +50,7 @@ type CacheMock struct {
 	TermPodList   map[string][]*api.Pod
 	PodList       map[string]*api.Pod
 	SecretTLSPath map[string]string
+	SecretTLSSAN  map[string][]string
 	SecretCAPath  map[string]string
 	SecretCRLPath map[string]string
 	SecretDHPath  map[string]string
@@ -200,12 +202,16 @@ func (c *CacheMock) GetTLSSecretPath(defaultNamespace, secretName string, track
 	fullname := c.buildResourceName(defaultNamespace, secretName)
 	c.tracker.TrackRefName(track, convtypes.ResourceSecret, fullname)
 	if path, found := c.SecretTLSPath[fullname]; found {
-		return convtypes.CrtFile{
+		file := convtypes.CrtFile{
 			Filename:   path,
 			SHA1Hash:   fmt.Sprintf("%x", sha1.Sum([]byte(path))),
 			CommonName: "localhost.localdomain",
 			NotAfter:   time.Now().AddDate(0, 0, 30),
-		}, nil
+		}
+		if san, found := c.SecretTLSSAN[fullname]; found {
+			file.Certificate = &x509.Certificate{DNSNames: san}
+		}
+		return file, nil
 	}
 	return convtypes.CrtFile{}, fmt.Errorf("secret not found: '%s'", fullname)
 }
@@ -19,6 +19,7 @@ package annotations
 import (
 	"fmt"
 	"net"
+	"os"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -37,6 +38,10 @@ func (c *updater) buildBackendAffinity(d *backData) {
 	if affinity.Source == nil {
 		return
 	}
+	if affinity.Value == "source-ip" {
+		c.buildBackendSourceAffinity(d)
+		return
+	}
 	if affinity.Value != "cookie" {
 		c.logger.Error("unsupported affinity type on %v: %s", affinity.Source, affinity.Value)
 		return
@@ -87,6 +92,22 @@ func (c *updater) buildBackendAffinity(d *backData) {
 	}
 }
 
+var stickTableSizeRegex = regexp.MustCompile(`^[1-9][0-9]*[kmg]?$`)
+
+func (c *updater) buildBackendSourceAffinity(d *backData) {
+	size := d.mapper.Get(ingtypes.BackSessionTableSize)
+	if !stickTableSizeRegex.MatchString(size.Value) {
+		c.logger.Warn("ignoring source-ip affinity on %v: invalid table size: %s", size.Source, size.Value)
+		return
+	}
+	expire := c.validateTime(d.mapper.Get(ingtypes.BackSessionTableExpire))
+	if expire == "" {
+		return
+	}
+	d.backend.SourceAffinity.Size = size.Value
+	d.backend.SourceAffinity.Expire = expire
+}
+
 var authRequestSanitizeHeaderRegex = regexp.MustCompile(`[^a-zA-Z0-9]`)
 var authRequestSrcIsVar = regexp.MustCompile(`^(proc|sess|txn|req|res)\.`)
 
@@ -106,6 +127,30 @@ var (
 	authHeaderRegex  = regexp.MustCompile(`^[A-Za-z0-9-]+(:[^:'" ]+)?$`)
 )
 
+var httpMethodRegex = regexp.MustCompile(`^[A-Z]+$`)
+
+func (c *updater) buildBackendAllowedMethods(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		methods := config.Get(ingtypes.BackAllowedMethods)
+		if methods == nil || methods.Value == "" {
+			continue
+		}
+		var allowed []string
+		for _, method := range utils.Split(methods.Value, ",") {
+			if !httpMethodRegex.MatchString(method) {
+				c.logger.Warn("ignoring invalid http method on %v: %s", methods.Source, method)
+				continue
+			}
+			allowed = append(allowed, method)
+		}
+		path.AllowedMethods = allowed
+	}
+}
+
 func (c *updater) buildBackendAuthExternal(d *backData) {
 	for _, path := range d.backend.Paths {
 		config := d.mapper.GetConfig(path.Link)
@@ -301,7 +346,8 @@ func extractUserlist(source, secret, users string) ([]hatypes.User, []error) {
 		}
 		sep := strings.Index(usr, ":")
 		if sep == -1 {
-			err = append(err, fmt.Errorf("missing password of user '%s' line %d", usr, i+1))
+			// the whole line is unparseable and might be a password, so it is not logged
+			err = append(err, fmt.Errorf("missing user/password separator line %d", i+1))
 			continue
 		}
 		username := usr[:sep]
@@ -334,6 +380,51 @@ func extractUserlist(source, secret, users string) ([]hatypes.User, []error) {
 	return userlist, err
 }
 
+var hashTypeRegex = regexp.MustCompile(`^\s*(map-based|consistent)(\s+(sdbm|djb2|wt6|crc32)(\s+avalanche)?)?\s*$`)
+
+func (c *updater) buildBackendBalance(d *backData) {
+	balance := d.mapper.Get(ingtypes.BackBalanceAlgorithm).Value
+	if balance == "uri" {
+		for _, param := range []struct {
+			key, name string
+		}{
+			{ingtypes.BackBalanceURIDepth, "depth"},
+			{ingtypes.BackBalanceURILen, "len"},
+		} {
+			cfg := d.mapper.Get(param.key)
+			if cfg.Value == "" {
+				continue
+			}
+			value, err := strconv.Atoi(cfg.Value)
+			if err != nil || value <= 0 {
+				c.logger.Warn("ignoring invalid balance uri %s on %v: %s", param.name, cfg.Source, cfg.Value)
+				continue
+			}
+			balance += fmt.Sprintf(" %s %d", param.name, value)
+		}
+		// minimize redistribution of requests on servers changes
+		d.backend.HashType = "consistent"
+	} else if balance == "random" {
+		if draws := d.mapper.Get(ingtypes.BackBalanceRandomDraws); draws.Value != "" {
+			if value, err := strconv.Atoi(draws.Value); err == nil && value > 0 {
+				balance += fmt.Sprintf("(%d)", value)
+			} else {
+				c.logger.Warn("ignoring invalid balance random draws on %v: %s", draws.Source, draws.Value)
+			}
+		}
+	}
+	d.backend.BalanceAlgorithm = balance
+	hashType := d.mapper.Get(ingtypes.BackHashType)
+	if hashType.Value == "" {
+		return
+	}
+	if !hashTypeRegex.MatchString(hashType.Value) {
+		c.logger.Warn("ignoring invalid hash type on %v: %s", hashType.Source, hashType.Value)
+		return
+	}
+	d.backend.HashType = strings.Join(strings.Fields(hashType.Value), " ")
+}
+
 func (c *updater) buildBackendBlueGreenBalance(d *backData) {
 	balance := d.mapper.Get(ingtypes.BackBlueGreenBalance)
 	if balance.Source == nil || balance.Value == "" {
@@ -503,6 +594,85 @@ func (c *updater) buildBackendBodySize(d *backData) {
 	}
 }
 
+var cacheNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func (c *updater) buildBackendCache(d *backData) {
+	name := d.mapper.Get(ingtypes.BackCacheName)
+	if name.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring cache on %v: backend is in tcp mode", name.Source)
+		return
+	}
+	if !cacheNameRegex.MatchString(name.Value) {
+		c.logger.Warn("ignoring invalid cache name on %v: %s", name.Source, name.Value)
+		return
+	}
+	totalMaxSize := d.mapper.Get(ingtypes.BackCacheTotalMaxSize)
+	totalMaxSizeMB, err := strconv.Atoi(totalMaxSize.Value)
+	if err != nil || totalMaxSizeMB <= 0 || totalMaxSizeMB > 4095 {
+		c.logger.Warn("ignoring cache on %v: invalid total max size, should be a number of megabytes between 1 and 4095: %s", totalMaxSize.Source, totalMaxSize.Value)
+		return
+	}
+	maxAge := d.mapper.Get(ingtypes.BackCacheMaxAge)
+	maxAgeSecs, err := strconv.Atoi(maxAge.Value)
+	if err != nil || maxAgeSecs <= 0 {
+		c.logger.Warn("ignoring cache on %v: invalid max age, should be a positive number of seconds: %s", maxAge.Source, maxAge.Value)
+		return
+	}
+	var maxObjectSizeBytes int64
+	if maxObjectSize := d.mapper.Get(ingtypes.BackCacheMaxObjectSize); maxObjectSize.Value != "" {
+		maxObjectSizeBytes, err = utils.SizeSuffixToInt64(maxObjectSize.Value)
+		// haproxy refuses objects bigger than half of the cache size
+		if err != nil || maxObjectSizeBytes <= 0 || maxObjectSizeBytes > int64(totalMaxSizeMB)*1024*1024/2 {
+			c.logger.Warn("ignoring cache on %v: invalid max object size, should be a positive size up to half of the total max size: %s", maxObjectSize.Source, maxObjectSize.Value)
+			return
+		}
+	}
+	d.backend.Cache = hatypes.BackendCache{
+		Name:          name.Value,
+		MaxAge:        maxAgeSecs,
+		MaxObjectSize: maxObjectSizeBytes,
+		TotalMaxSize:  totalMaxSizeMB,
+	}
+	// the storage is declared only once, using the options of the first backend
+	for _, backend := range c.haproxy.Backends().BuildSortedItems() {
+		if backend != d.backend && backend.Cache.Name == name.Value && backend.Cache != d.backend.Cache {
+			c.logger.Warn("cache '%s' on %v conflicts with the cache options of backend '%s', using the options of the backend sorted first", name.Value, name.Source, backend.ID)
+			break
+		}
+	}
+}
+
+func (c *updater) buildBackendConnectionPool(d *backData) {
+	maxConn := d.mapper.Get(ingtypes.BackPoolMaxConn)
+	if maxConn.Value != "" {
+		value, err := strconv.Atoi(maxConn.Value)
+		if err != nil || value < -1 {
+			c.logger.Warn("ignoring invalid pool max connections on %v: %s", maxConn.Source, maxConn.Value)
+		} else {
+			d.backend.Server.PoolMaxConn = &value
+		}
+	}
+	d.backend.Server.PoolPurge = c.validateTime(d.mapper.Get(ingtypes.BackPoolPurgeDelay))
+}
+
+var connectionModeRegex = regexp.MustCompile(`^(http-keep-alive|http-server-close|httpclose)$`)
+
+func (c *updater) buildBackendConnectionMode(d *backData) {
+	mode := d.mapper.Get(ingtypes.BackHTTPConnectionMode)
+	if mode.Source == nil || mode.Value == "" {
+		// use the mode of the defaults section
+		return
+	}
+	if !connectionModeRegex.MatchString(mode.Value) {
+		c.logger.Warn("ignoring invalid http connection mode on %v: %s", mode.Source, mode.Value)
+		return
+	}
+	d.backend.ConnectionMode = mode.Value
+}
+
 func (c *updater) buildBackendCors(d *backData) {
 	for _, path := range d.backend.Paths {
 		config := d.mapper.GetConfig(path.Link)
@@ -566,6 +736,9 @@ func firstToken(s string) string {
 	return s[start:end]
 }
 
+// IANA service name: lowercase letters, digits and hyphens, having at least one letter
+var srvPortRegex = regexp.MustCompile(`^[a-z0-9]*[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
 func (c *updater) buildBackendDNS(d *backData) {
 	resolverName := d.mapper.Get(ingtypes.BackUseResolver).Value
 	if resolverName == "" {
@@ -584,6 +757,27 @@ func (c *updater) buildBackendDNS(d *backData) {
 		return
 	}
 	d.backend.Resolver = resolverName
+	if srvPort := d.mapper.Get(ingtypes.BackDNSSRVPort); srvPort.Value != "" {
+		if len(srvPort.Value) <= 15 && srvPortRegex.MatchString(srvPort.Value) {
+			// a port name makes server-template query the SRV record
+			d.backend.DNSPort = srvPort.Value
+		} else {
+			c.logger.Warn("ignoring invalid SRV port name on %v: %s", srvPort.Source, srvPort.Value)
+		}
+	}
+	initAddr := d.mapper.Get(ingtypes.BackInitAddr)
+	if initAddr.Value == "" {
+		return
+	}
+	methods := strings.Split(initAddr.Value, ",")
+	for _, method := range methods {
+		if method != "last" && method != "libc" && method != "none" && net.ParseIP(method) == nil {
+			c.logger.Warn("ignoring invalid init-addr on %v: %s", initAddr.Source, initAddr.Value)
+			d.backend.InitAddr = "none"
+			return
+		}
+	}
+	d.backend.InitAddr = initAddr.Value
 }
 
 func (c *updater) buildBackendDynamic(d *backData) {
@@ -594,14 +788,127 @@ func (c *updater) buildBackendDynamic(d *backData) {
 	}
 }
 
+// findServiceBackend finds the backend of a `[<namespace>/]<name>:<port>`
+// service reference. The namespace is mandatory if the reference comes from
+// the global config. A nil backend without an error means that the service
+// or its backend wasn't found, which was already logged when the ingress
+// parser tried to acquire the backend.
+func (c *updater) findServiceBackend(cfg *ConfigValue) (*hatypes.Backend, error) {
+	svcName, svcPort, err := ingutils.ParseService(cfg.Value)
+	if err != nil {
+		return nil, err
+	}
+	var namespace string
+	if cfg.Source != nil {
+		namespace = cfg.Source.Namespace
+	}
+	if ssvc := strings.Split(svcName, "/"); len(ssvc) == 2 {
+		namespace = ssvc[0]
+		svcName = ssvc[1]
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("a '<namespace>/<name>:<port>' service reference should be used: %s", cfg.Value)
+	}
+	svc, err := c.cache.GetService(namespace, svcName)
+	if err != nil {
+		return nil, nil
+	}
+	port := convutils.FindServicePort(svc, svcPort)
+	if port == nil {
+		return nil, nil
+	}
+	return c.haproxy.Backends().FindBackend(svc.Namespace, svc.Name, port.TargetPort.String()), nil
+}
+
+func (c *updater) buildBackendCanary(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		canary := config.Get(ingtypes.BackCanaryBackend)
+		if canary == nil || canary.Value == "" {
+			continue
+		}
+		weight := config.Get(ingtypes.BackCanaryWeight)
+		weightValue, err := strconv.Atoi(weight.Value)
+		if err != nil || weightValue < 0 || weightValue > 100 {
+			c.logger.Warn("ignoring canary backend on %v: canary weight should be a percentage between 0 and 100: %s", weight.Source, weight.Value)
+			continue
+		}
+		if weightValue == 0 {
+			continue
+		}
+		backend, err := c.findServiceBackend(canary)
+		if err != nil {
+			c.logger.Warn("ignoring canary backend on %v: %v", canary.Source, err)
+			continue
+		}
+		if backend == nil {
+			continue
+		}
+		if backend.ID == d.backend.ID {
+			c.logger.Warn("ignoring canary backend on %v: canary and primary backends are the same: %s", canary.Source, canary.Value)
+			continue
+		}
+		path.Canary.Backend = backend.ID
+		path.Canary.Weight = weightValue
+	}
+}
+
+func (c *updater) buildBackendFallback(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		fallback := d.mapper.GetConfig(path.Link).Get(ingtypes.BackFallbackBackend)
+		if fallback == nil || fallback.Value == "" {
+			continue
+		}
+		backend, err := c.findServiceBackend(fallback)
+		if err != nil {
+			c.logger.Warn("ignoring fallback backend on %v: %v", fallback.Source, err)
+			continue
+		}
+		if backend == nil {
+			continue
+		}
+		if backend.ID == d.backend.ID {
+			c.logger.Warn("ignoring fallback backend on %v: fallback and primary backends are the same: %s", fallback.Source, fallback.Value)
+			continue
+		}
+		path.FallbackBackend = backend.ID
+	}
+}
+
 func (c *updater) buildBackendAgentCheck(d *backData) {
+	cfgPort := d.mapper.Get(ingtypes.BackAgentCheckPort)
+	if cfgPort.Value == "" {
+		return
+	}
+	port, err := strconv.Atoi(cfgPort.Value)
+	if err != nil || port <= 0 || port > 65535 {
+		c.logger.Warn("ignoring invalid agent check port on %v: %s", cfgPort.Source, cfgPort.Value)
+		return
+	}
+	send := d.mapper.Get(ingtypes.BackAgentCheckSend)
+	if strings.ContainsAny(send.Value, " \t\"'") {
+		c.logger.Warn("ignoring agent check send on %v: spaces and quotes are not supported: %s", send.Source, send.Value)
+	} else {
+		d.backend.AgentCheck.Send = send.Value
+	}
 	d.backend.AgentCheck.Addr = d.mapper.Get(ingtypes.BackAgentCheckAddr).Value
 	d.backend.AgentCheck.Interval = c.validateTime(d.mapper.Get(ingtypes.BackAgentCheckInterval))
-	d.backend.AgentCheck.Port = d.mapper.Get(ingtypes.BackAgentCheckPort).Int()
-	d.backend.AgentCheck.Send = d.mapper.Get(ingtypes.BackAgentCheckSend).Value
+	d.backend.AgentCheck.Port = port
 }
 
 func (c *updater) buildBackendHealthCheck(d *backData) {
+	if d.mapper.Get(ingtypes.BackHealthCheckDisabled).Bool() {
+		// active health checks are done elsewhere, eg by the
+		// load balancer in front of the backend servers
+		d.backend.HealthCheck.Disabled = true
+		return
+	}
 	d.backend.HealthCheck.Addr = d.mapper.Get(ingtypes.BackHealthCheckAddr).Value
 	d.backend.HealthCheck.FallCount = d.mapper.Get(ingtypes.BackHealthCheckFallCount).Int()
 	interval := d.mapper.Get(ingtypes.BackHealthCheckInterval)
@@ -609,9 +916,69 @@ func (c *updater) buildBackendHealthCheck(d *backData) {
 		interval = d.mapper.Get(ingtypes.BackBackendCheckInterval)
 	}
 	d.backend.HealthCheck.Interval = c.validateTime(interval)
+	d.backend.HealthCheck.Log = d.mapper.Get(ingtypes.BackHealthCheckLog).Bool()
 	d.backend.HealthCheck.Port = d.mapper.Get(ingtypes.BackHealthCheckPort).Int()
 	d.backend.HealthCheck.RiseCount = d.mapper.Get(ingtypes.BackHealthCheckRiseCount).Int()
+	if sslMode := d.mapper.Get(ingtypes.BackHealthCheckSSLMode); sslMode.Value != "" {
+		switch sslMode.Value {
+		case "ssl", "no-ssl":
+			d.backend.HealthCheck.SSLMode = sslMode.Value
+		case "default":
+		default:
+			c.logger.Warn("ignoring invalid health check ssl mode on %v: %s", sslMode.Source, sslMode.Value)
+		}
+	}
 	d.backend.HealthCheck.URI = d.mapper.Get(ingtypes.BackHealthCheckURI).Value
+	if sni := d.mapper.Get(ingtypes.BackHealthCheckSNI); sni.Value != "" {
+		if !validDomainRegex.MatchString(sni.Value) {
+			c.logger.Warn("ignoring invalid health check sni on %v: %s", sni.Source, sni.Value)
+		} else if d.backend.HealthCheck.URI == "" {
+			// sni is sent by http-check connect, used only by http checks
+			c.logger.Warn("ignoring health check sni on %v: health-check-uri was not configured", sni.Source)
+		} else if d.backend.HealthCheck.SSLMode == "no-ssl" {
+			c.logger.Warn("ignoring health check sni on %v: health check ssl mode is no-ssl", sni.Source)
+		} else {
+			// sni needs a ssl connection
+			d.backend.HealthCheck.SNI = sni.Value
+			d.backend.HealthCheck.SSLMode = "ssl"
+		}
+	}
+}
+
+func (c *updater) buildBackendHeaderMatch(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		match := config.Get(ingtypes.BackHeaderMatch)
+		if match == nil || match.Value == "" {
+			continue
+		}
+		var name, value string
+		if i := strings.Index(match.Value, ":"); i >= 0 {
+			name = strings.TrimSpace(match.Value[:i])
+			value = strings.TrimSpace(match.Value[i+1:])
+		} else {
+			name = strings.TrimSpace(match.Value)
+		}
+		if !headerNameRegex.MatchString(name) || strings.ContainsAny(value, " \t") {
+			c.logger.Warn("ignoring invalid header match on %v: %s", match.Source, match.Value)
+			continue
+		}
+		regex := config.Get(ingtypes.BackHeaderMatchRegex).Bool()
+		if regex && value != "" {
+			if _, err := regexp.Compile(value); err != nil {
+				c.logger.Warn("ignoring invalid header match regex on %v: %s", match.Source, value)
+				continue
+			}
+		}
+		path.HeaderMatch = hatypes.HeaderMatch{
+			Name:  name,
+			Value: value,
+			Regex: regex && value != "",
+		}
+	}
 }
 
 func (c *updater) buildBackendHeaders(d *backData) {
@@ -641,6 +1008,141 @@ func (c *updater) buildBackendHeaders(d *backData) {
 	}
 }
 
+var headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+func (c *updater) buildBackendHeadersRemove(d *backData) {
+	headers := d.mapper.Get(ingtypes.BackHeadersResponseRemove)
+	for _, header := range utils.Split(headers.Value, ",") {
+		if header == "" {
+			continue
+		}
+		if !headerNameRegex.MatchString(header) {
+			c.logger.Warn("ignoring invalid header name on %v: %s", headers.Source, header)
+			continue
+		}
+		d.backend.HeadersRemove = append(d.backend.HeadersRemove, header)
+	}
+}
+
+func (c *updater) buildBackendHeadersReplace(d *backData) {
+	headers := d.mapper.Get(ingtypes.BackHeadersReplace)
+	for _, header := range utils.LineToSlice(headers.Value) {
+		fields := strings.Fields(header)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			c.logger.Warn("ignoring header replace on %v: expected '<name> <match-regex> <replacement>', found: %s", headers.Source, header)
+			continue
+		}
+		if !headerNameRegex.MatchString(fields[0]) {
+			c.logger.Warn("ignoring invalid header name on %v: %s", headers.Source, fields[0])
+			continue
+		}
+		d.backend.HeadersReplace = append(d.backend.HeadersReplace, &hatypes.BackendHeaderReplace{
+			Name:    fields[0],
+			Match:   fields[1],
+			Replace: fields[2],
+		})
+	}
+}
+
+func (c *updater) buildBackendRequiredHeader(d *backData) {
+	header := d.mapper.Get(ingtypes.BackRequiredHeader)
+	if header.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring required header on %v: backend is in tcp mode", header.Source)
+		return
+	}
+	if !headerNameRegex.MatchString(header.Value) {
+		c.logger.Warn("ignoring invalid header name on %v: %s", header.Source, header.Value)
+		return
+	}
+	d.backend.RequiredHeader = header.Value
+}
+
+func (c *updater) buildBackendResolveDst(d *backData) {
+	header := d.mapper.Get(ingtypes.BackResolveDstHeader)
+	if header.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring resolve dst on %v: backend is in tcp mode", header.Source)
+		return
+	}
+	if !headerNameRegex.MatchString(header.Value) {
+		c.logger.Warn("ignoring invalid header name on %v: %s", header.Source, header.Value)
+		return
+	}
+	resolver := d.mapper.Get(ingtypes.BackResolveDstResolver)
+	if resolver.Value == "" {
+		c.logger.Warn("ignoring resolve dst on %v: missing resolver", header.Source)
+		return
+	}
+	found := false
+	for _, r := range c.haproxy.Global().DNS.Resolvers {
+		if r.Name == resolver.Value {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.logger.Warn("skipping undeclared DNS resolver on %v: %s", resolver.Source, resolver.Value)
+		return
+	}
+	var domainList []string
+	domains := d.mapper.Get(ingtypes.BackResolveDstDomains)
+	for _, domain := range utils.Split(domains.Value, ",") {
+		if domain == "" {
+			continue
+		}
+		// the lower converter is applied on the header before the match
+		domain = strings.ToLower(domain)
+		if !validDomainRegex.MatchString(domain) {
+			c.logger.Warn("ignoring invalid resolve dst domain on %v: %s", domains.Source, domain)
+			continue
+		}
+		domainList = append(domainList, domain)
+	}
+	d.backend.ResolveDst = hatypes.BackendResolveDst{
+		Domains:  domainList,
+		Header:   header.Value,
+		Resolver: resolver.Value,
+	}
+}
+
+func (c *updater) buildBackendResponseStatus(d *backData) {
+	config := d.mapper.Get(ingtypes.BackResponseSetStatus)
+	if config.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring response set status on %v: backend is in tcp mode", config.Source)
+		return
+	}
+	for _, line := range utils.LineToSlice(config.Value) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		code, _ := strconv.Atoi(fields[0])
+		if code < 100 || code > 599 {
+			c.logger.Warn("ignoring response set status on %v: expected '<code> [<acl-condition>]' with a status code between 100 and 599, found: %s", config.Source, line)
+			continue
+		}
+		if len(fields) > 1 && (fields[1] == "if" || fields[1] == "unless") {
+			c.logger.Warn("ignoring response set status on %v: expected an acl condition without '%s': %s", config.Source, fields[1], line)
+			continue
+		}
+		d.backend.ResponseStatus = append(d.backend.ResponseStatus, &hatypes.BackendResponseStatus{
+			Code:      code,
+			Condition: strings.Join(fields[1:], " "),
+		})
+	}
+}
+
 func (c *updater) buildBackendHSTS(d *backData) {
 	for _, path := range d.backend.Paths {
 		config := d.mapper.GetConfig(path.Link)
@@ -652,9 +1154,110 @@ func (c *updater) buildBackendHSTS(d *backData) {
 }
 
 func (c *updater) buildBackendLimit(d *backData) {
+	action := d.mapper.Get(ingtypes.BackLimitAction)
+	switch action.Value {
+	case "deny":
+		// default action, rendered when Action is empty
+	case "silent-drop", "tarpit":
+		d.backend.Limit.Action = action.Value
+	default:
+		c.logger.Warn("ignoring invalid limit action on %v: %s", action.Source, action.Value)
+	}
 	d.backend.Limit.RPS = d.mapper.Get(ingtypes.BackLimitRPS).Int()
 	d.backend.Limit.Connections = d.mapper.Get(ingtypes.BackLimitConnections).Int()
+	d.backend.Limit.DenyStatus = c.validateDenyStatus(d.mapper.Get(ingtypes.BackLimitDenyStatus))
+	d.backend.Limit.PathRPS = d.mapper.Get(ingtypes.BackLimitPathRPS).Int()
 	d.backend.Limit.Whitelist = c.splitCIDR(d.mapper.Get(ingtypes.BackLimitWhitelist))
+	c.buildBackendLimitGPC0(d)
+	c.buildBackendLimitSession(d)
+}
+
+func (c *updater) buildBackendLimitGPC0(d *backData) {
+	threshold := d.mapper.Get(ingtypes.BackLimitGPC0Threshold)
+	if threshold.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring limit gpc0 on %v: backend is in tcp mode", threshold.Source)
+		return
+	}
+	value, err := strconv.Atoi(threshold.Value)
+	if err != nil || value <= 0 {
+		c.logger.Warn("ignoring invalid limit gpc0 threshold on %v: %s", threshold.Source, threshold.Value)
+		return
+	}
+	d.backend.Limit.GPC0Threshold = value
+	increment := d.mapper.Get(ingtypes.BackLimitGPC0Increment)
+	condition := singleLine(increment.Value)
+	if fields := strings.Fields(condition); len(fields) > 0 && (fields[0] == "if" || fields[0] == "unless") {
+		c.logger.Warn("ignoring limit gpc0 increment on %v: expected an acl condition without '%s': %s", increment.Source, fields[0], condition)
+		return
+	}
+	d.backend.Limit.GPC0Increment = condition
+}
+
+var limitSessionNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func (c *updater) buildBackendLimitSession(d *backData) {
+	rps := d.mapper.Get(ingtypes.BackLimitSessionRPS)
+	if rps.Value == "" {
+		return
+	}
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring limit session on %v: backend is in tcp mode", rps.Source)
+		return
+	}
+	value, err := strconv.Atoi(rps.Value)
+	if err != nil || value <= 0 {
+		c.logger.Warn("ignoring invalid limit session rps on %v: %s", rps.Source, rps.Value)
+		return
+	}
+	key := d.mapper.Get(ingtypes.BackLimitSessionKey)
+	var fetch string
+	if kv := strings.SplitN(key.Value, ":", 2); len(kv) == 2 && limitSessionNameRegex.MatchString(kv[1]) {
+		switch kv[0] {
+		case "cookie":
+			fetch = fmt.Sprintf("req.cook(%s)", kv[1])
+		case "header":
+			fetch = fmt.Sprintf("req.hdr(%s)", kv[1])
+		}
+	}
+	if fetch == "" {
+		c.logger.Warn("ignoring limit session on %v: expected 'cookie:<name>' or 'header:<name>' as the session key: '%s'", rps.Source, key.Value)
+		return
+	}
+	d.backend.Limit.SessionKey = fetch
+	d.backend.Limit.SessionRPS = value
+}
+
+var logLevels = map[string]bool{
+	"emerg":   true,
+	"alert":   true,
+	"crit":    true,
+	"err":     true,
+	"warning": true,
+	"notice":  true,
+	"info":    true,
+	"debug":   true,
+	"silent":  true,
+}
+
+func (c *updater) buildBackendLogLevel(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		level := config.Get(ingtypes.BackLogLevel)
+		if level.Value == "" {
+			continue
+		}
+		if !logLevels[level.Value] {
+			c.logger.Warn("ignoring invalid log level on %v: %s", level.Source, level.Value)
+			continue
+		}
+		path.LogLevel = level.Value
+	}
 }
 
 func (c *updater) buildBackendOAuth(d *backData) {
@@ -735,6 +1338,144 @@ func (c *updater) findBackend(namespace, uriPrefix string) *hatypes.HostBackend
 
 var validDomainRegex = regexp.MustCompile(`^([A-Za-z0-9-]{1,63}\.)+[A-Za-z]{2,6}$`)
 
+func (c *updater) buildBackendPersist(d *backData) {
+	d.backend.Persist.Enabled = d.mapper.Get(ingtypes.BackPersist).Bool()
+	d.backend.Persist.Force = c.readPersistConditions(d, ingtypes.BackPersistForce)
+	d.backend.Persist.Ignore = c.readPersistConditions(d, ingtypes.BackPersistIgnore)
+}
+
+func (c *updater) readPersistConditions(d *backData, key string) []string {
+	config := d.mapper.Get(key)
+	var conditions []string
+	for _, line := range utils.LineToSlice(config.Value) {
+		cond := singleLine(line)
+		if cond == "" {
+			continue
+		}
+		if keyword := strings.Fields(cond)[0]; keyword == "if" || keyword == "unless" {
+			c.logger.Warn("ignoring %s condition on %v: expected an acl condition without '%s': %s", key, config.Source, keyword, cond)
+			continue
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
+
+func (c *updater) buildBackendPriority(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		if class := config.Get(ingtypes.BackPriorityClass); class.Value != "" {
+			if value, err := strconv.Atoi(class.Value); err == nil && value >= -2047 && value <= 2047 {
+				path.Priority.Class = value
+			} else {
+				c.logger.Warn("ignoring invalid priority class on %v: %s", class.Source, class.Value)
+			}
+		}
+		if offset := config.Get(ingtypes.BackPriorityOffset); offset.Value != "" {
+			if value, err := strconv.Atoi(offset.Value); err == nil && value >= -524287 && value <= 524287 {
+				path.Priority.Offset = value
+			} else {
+				c.logger.Warn("ignoring invalid priority offset on %v: %s", offset.Source, offset.Value)
+			}
+		}
+	}
+}
+
+func (c *updater) buildBackendRedispatch(d *backData) {
+	redispatch := &d.backend.Redispatch
+	if retries := d.mapper.Get(ingtypes.BackRetries); retries.Value != "" {
+		if value, err := strconv.Atoi(retries.Value); err == nil && value > 0 {
+			redispatch.Retries = value
+		} else {
+			c.logger.Warn("ignoring invalid retries on %v: %s", retries.Source, retries.Value)
+		}
+	}
+	interval := d.mapper.Get(ingtypes.BackRedispatchInterval)
+	if interval.Value == "" {
+		return
+	}
+	value, err := strconv.Atoi(interval.Value)
+	if err != nil || value == 0 {
+		c.logger.Warn("ignoring invalid redispatch interval on %v: %s", interval.Source, interval.Value)
+		return
+	}
+	if redispatch.Retries > 0 && value > redispatch.Retries {
+		c.logger.Warn("redispatch interval %d is greater than %d retries on %v, requests will not be redispatched", value, redispatch.Retries, interval.Source)
+	}
+	redispatch.Interval = value
+}
+
+func (c *updater) buildBackendQoS(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		if mark := config.Get(ingtypes.BackQoSMark); mark.Value != "" {
+			// base 0 allows both decimal and 0x prefixed hexadecimal values
+			if value, err := strconv.ParseInt(mark.Value, 0, 64); err == nil && value >= 0 && value <= 0xffffffff {
+				path.QoS.Mark = &value
+			} else {
+				c.logger.Warn("ignoring invalid qos mark on %v: %s", mark.Source, mark.Value)
+			}
+		}
+		if tos := config.Get(ingtypes.BackQoSTOS); tos.Value != "" {
+			if value, err := strconv.ParseInt(tos.Value, 0, 64); err == nil && value >= 0 && value <= 0xff {
+				path.QoS.TOS = &value
+			} else {
+				c.logger.Warn("ignoring invalid qos tos on %v: %s", tos.Source, tos.Value)
+			}
+		}
+	}
+}
+
+var (
+	fcgiDocRootRegex  = regexp.MustCompile(`^/[^\s]*$`)
+	fcgiIndexRegex    = regexp.MustCompile(`^[^\s/]+$`)
+	fcgiPathInfoRegex = regexp.MustCompile(`^[^\s]+$`)
+)
+
+// readFastCGI configures the fcgi-app used by a backend whose protocol is
+// fcgi, returning false if the mandatory docroot is missing or invalid.
+func (c *updater) readFastCGI(d *backData, proto *ConfigValue) bool {
+	if d.backend.ModeTCP {
+		c.logger.Warn("ignoring fcgi protocol on %v: backend is in tcp mode", proto.Source)
+		return false
+	}
+	docroot := d.mapper.Get(ingtypes.BackFastCGIDocRoot)
+	if docroot.Value == "" {
+		c.logger.Warn("ignoring fcgi protocol on %v: missing fcgi-docroot", proto.Source)
+		return false
+	}
+	if !fcgiDocRootRegex.MatchString(docroot.Value) {
+		c.logger.Warn("ignoring fcgi protocol on %v: invalid fcgi-docroot, expected an absolute path: %s", docroot.Source, docroot.Value)
+		return false
+	}
+	fcgi := hatypes.BackendFastCGI{
+		Name:    d.backend.ID,
+		DocRoot: docroot.Value,
+	}
+	if index := d.mapper.Get(ingtypes.BackFastCGIIndex); index.Value != "" {
+		if fcgiIndexRegex.MatchString(index.Value) {
+			fcgi.Index = index.Value
+		} else {
+			c.logger.Warn("ignoring invalid fcgi-index on %v: %s", index.Source, index.Value)
+		}
+	}
+	if pathInfo := d.mapper.Get(ingtypes.BackFastCGIPathInfo); pathInfo.Value != "" {
+		if fcgiPathInfoRegex.MatchString(pathInfo.Value) {
+			fcgi.PathInfo = pathInfo.Value
+		} else {
+			c.logger.Warn("ignoring invalid fcgi-path-info on %v: %s", pathInfo.Source, pathInfo.Value)
+		}
+	}
+	d.backend.FastCGI = fcgi
+	return true
+}
+
 func (c *updater) buildBackendProtocol(d *backData) {
 	proto := d.mapper.Get(ingtypes.BackBackendProtocol)
 	var protocol string
@@ -752,12 +1493,18 @@ func (c *updater) buildBackendProtocol(d *backData) {
 	case "h2-ssl", "grpcs":
 		protocol = "h2"
 		secure = true
+	case "fcgi":
+		protocol = "fcgi"
+		secure = false
 	default:
 		c.logger.Warn("ignoring invalid backend protocol on %v: %s", proto.Source, proto.Value)
 		return
 	}
-	if protocol == "h2" && !c.haproxy.Global().UseHTX {
-		c.logger.Warn("ignoring h2 protocol on %v due to HTX disabled, changing to h1", proto.Source)
+	if (protocol == "h2" || protocol == "fcgi") && !c.haproxy.Global().UseHTX {
+		c.logger.Warn("ignoring %s protocol on %v due to HTX disabled, changing to h1", protocol, proto.Source)
+		protocol = "h1"
+	}
+	if protocol == "fcgi" && !c.readFastCGI(d, proto) {
 		protocol = "h1"
 	}
 	if !secure {
@@ -934,7 +1681,65 @@ func (c *updater) buildBackendSSLRedirect(d *backData) {
 	}
 }
 
+var staticResponseEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+
+func (c *updater) buildBackendStaticResponse(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	for _, path := range d.backend.Paths {
+		config := d.mapper.GetConfig(path.Link)
+		body := config.Get(ingtypes.BackStaticResponse)
+		file := config.Get(ingtypes.BackStaticResponseFile)
+		if body.Value == "" && file.Value == "" {
+			continue
+		}
+		status := config.Get(ingtypes.BackStaticResponseStatus)
+		code, err := strconv.Atoi(status.Value)
+		if err != nil || code < 200 || code > 599 {
+			c.logger.Warn("ignoring static response on %v: invalid status code: %s", status.Source, status.Value)
+			continue
+		}
+		contentType := config.Get(ingtypes.BackStaticResponseCType)
+		if contentType.Value == "" || strings.ContainsAny(contentType.Value, "\"\\\r\n") {
+			c.logger.Warn("ignoring static response on %v: invalid content type: %s", contentType.Source, contentType.Value)
+			continue
+		}
+		response := hatypes.StaticResponse{
+			Status:      code,
+			ContentType: contentType.Value,
+		}
+		if file.Value != "" {
+			if body.Value != "" {
+				c.logger.Warn("ignoring static response body on %v due to conflict with static response file", body.Source)
+			}
+			if strings.ContainsAny(file.Value, " \t\"'\\") {
+				c.logger.Warn("ignoring static response on %v: invalid file name: %s", file.Source, file.Value)
+				continue
+			}
+			// an external haproxy has its own filesystem, the file is checked by haproxy itself
+			if !c.haproxy.Global().External.IsExternal() {
+				if _, err := os.Stat(file.Value); err != nil {
+					c.logger.Warn("ignoring static response on %v: file cannot be read: %v", file.Source, err)
+					continue
+				}
+			}
+			response.File = file.Value
+		} else {
+			if strings.ContainsAny(body.Value, "\r\n") {
+				c.logger.Warn("ignoring static response on %v: body should be a single line, use static-response-file instead", body.Source)
+				continue
+			}
+			response.Body = staticResponseEscape.Replace(body.Value)
+		}
+		path.StaticResponse = response
+	}
+}
+
 func (c *updater) buildBackendTimeout(d *backData) {
+	if cfg := d.mapper.Get(ingtypes.BackTimeoutCheck); cfg.Source != nil {
+		d.backend.Timeout.Check = c.validateTime(cfg)
+	}
 	if cfg := d.mapper.Get(ingtypes.BackTimeoutConnect); cfg.Source != nil {
 		d.backend.Timeout.Connect = c.validateTime(cfg)
 	}
@@ -953,11 +1758,28 @@ func (c *updater) buildBackendTimeout(d *backData) {
 	if cfg := d.mapper.Get(ingtypes.BackTimeoutServerFin); cfg.Source != nil {
 		d.backend.Timeout.ServerFin = c.validateTime(cfg)
 	}
+	if cfg := d.mapper.Get(ingtypes.BackTimeoutTarpit); cfg.Source != nil {
+		d.backend.Timeout.Tarpit = c.validateTime(cfg)
+	}
 	if cfg := d.mapper.Get(ingtypes.BackTimeoutTunnel); cfg.Source != nil {
 		d.backend.Timeout.Tunnel = c.validateTime(cfg)
 	}
 }
 
+var upstreamHostRegex = regexp.MustCompile(`^[a-zA-Z0-9.-]+(:[0-9]+)?$`)
+
+func (c *updater) buildBackendUpstreamHost(d *backData) {
+	vhost := d.mapper.Get(ingtypes.BackUpstreamVhost)
+	if vhost.Value == "" {
+		return
+	}
+	if !upstreamHostRegex.MatchString(vhost.Value) {
+		c.logger.Warn("ignoring invalid upstream vhost on %v: %s", vhost.Source, vhost.Value)
+		return
+	}
+	d.backend.UpstreamHost = vhost.Value
+}
+
 func (c *updater) buildBackendWAF(d *backData) {
 	for _, path := range d.backend.Paths {
 		config := d.mapper.GetConfig(path.Link)
@@ -981,11 +1803,40 @@ func (c *updater) buildBackendWAF(d *backData) {
 	}
 }
 
+func (c *updater) buildBackendWaitForBody(d *backData) {
+	if d.backend.ModeTCP {
+		return
+	}
+	waitTime := d.mapper.Get(ingtypes.BackWaitForBodyTime)
+	if waitTime.Value == "" {
+		return
+	}
+	timeout := c.validateTime(waitTime)
+	if timeout == "" {
+		return
+	}
+	var atLeastBytes int64
+	if atLeast := d.mapper.Get(ingtypes.BackWaitForBodyAtLeast); atLeast.Value != "" {
+		value, err := utils.SizeSuffixToInt64(atLeast.Value)
+		if err != nil || value <= 0 {
+			c.logger.Warn("ignoring invalid wait for body at least on %v: %s", atLeast.Source, atLeast.Value)
+		} else {
+			atLeastBytes = value
+		}
+	}
+	d.backend.WaitForBody = hatypes.BackendWaitForBody{
+		AtLeast: atLeastBytes,
+		Time:    timeout,
+	}
+}
+
 func (c *updater) buildBackendWhitelistHTTP(d *backData) {
 	if !d.backend.ModeTCP {
 		for _, path := range d.backend.Paths {
 			config := d.mapper.GetConfig(path.Link)
 			path.AllowedIPHTTP, path.DeniedIPHTTP = c.readAccessConfig(config)
+			path.AllowedIPHTTP.DenyStatus = c.validateDenyStatus(config.Get(ingtypes.BackAllowlistDenyStatus))
+			path.DeniedIPHTTP.DenyStatus = c.validateDenyStatus(config.Get(ingtypes.BackDenylistDenyStatus))
 		}
 	}
 }
@@ -18,7 +18,10 @@ package annotations
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -36,6 +39,7 @@ func TestAffinity(t *testing.T) {
 		annDefault map[string]string
 		ann        map[string]string
 		expCookie  hatypes.Cookie
+		expSource  hatypes.SourceAffinity
 		expLogging string
 	}{
 		// 0
@@ -158,6 +162,44 @@ func TestAffinity(t *testing.T) {
 			expCookie:  hatypes.Cookie{Name: "INGRESSCOOKIE", Strategy: "insert", Dynamic: false, Keywords: "indirect nocache httponly"},
 			expLogging: "WARN invalid session-cookie-value-strategy 'err' on ingress 'default/ing1', using 'server-name' instead",
 		},
+		// 13
+		{
+			ann: map[string]string{
+				ingtypes.BackAffinity: "source-ip",
+			},
+			annDefault: map[string]string{
+				ingtypes.BackSessionTableExpire: "30m",
+				ingtypes.BackSessionTableSize:   "100k",
+			},
+			expSource: hatypes.SourceAffinity{Size: "100k", Expire: "30m"},
+		},
+		// 14
+		{
+			ann: map[string]string{
+				ingtypes.BackAffinity:           "source-ip",
+				ingtypes.BackSessionTableExpire: "1h",
+				ingtypes.BackSessionTableSize:   "1m",
+			},
+			expSource: hatypes.SourceAffinity{Size: "1m", Expire: "1h"},
+		},
+		// 15
+		{
+			ann: map[string]string{
+				ingtypes.BackAffinity:           "source-ip",
+				ingtypes.BackSessionTableExpire: "1h",
+				ingtypes.BackSessionTableSize:   "1M",
+			},
+			expLogging: "WARN ignoring source-ip affinity on ingress 'default/ing1': invalid table size: 1M",
+		},
+		// 16
+		{
+			ann: map[string]string{
+				ingtypes.BackAffinity:           "source-ip",
+				ingtypes.BackSessionTableExpire: "1y",
+				ingtypes.BackSessionTableSize:   "1m",
+			},
+			expLogging: "WARN ignoring invalid time format on ingress 'default/ing1': 1y",
+		},
 	}
 
 	source := &Source{
@@ -171,11 +213,136 @@ func TestAffinity(t *testing.T) {
 		d := c.createBackendData("default/app", source, test.ann, test.annDefault)
 		u.buildBackendAffinity(d)
 		c.compareObjects("affinity", i, d.backend.Cookie, test.expCookie)
+		c.compareObjects("source affinity", i, d.backend.SourceAffinity, test.expSource)
 		c.logger.CompareLogging(test.expLogging)
 		c.teardown()
 	}
 }
 
+func TestAgentCheck(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected hatypes.AgentCheck
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackAgentCheckAddr:     "10.0.0.10",
+				ingtypes.BackAgentCheckInterval: "5s",
+			},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackAgentCheckAddr:     "10.0.0.10",
+				ingtypes.BackAgentCheckInterval: "5s",
+				ingtypes.BackAgentCheckPort:     "8000",
+				ingtypes.BackAgentCheckSend:     "hello",
+			},
+			expected: hatypes.AgentCheck{Addr: "10.0.0.10", Interval: "5s", Port: 8000, Send: "hello"},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackAgentCheckPort: "80000",
+			},
+			logging: `WARN ignoring invalid agent check port on ingress 'default/ing1': 80000`,
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackAgentCheckPort: "agent",
+			},
+			logging: `WARN ignoring invalid agent check port on ingress 'default/ing1': agent`,
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackAgentCheckInterval: "5",
+				ingtypes.BackAgentCheckPort:     "8000",
+				ingtypes.BackAgentCheckSend:     "get load",
+			},
+			expected: hatypes.AgentCheck{Port: 8000},
+			logging: `
+WARN ignoring agent check send on ingress 'default/ing1': spaces and quotes are not supported: get load
+WARN ignoring invalid time format on ingress 'default/ing1': 5`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		c.createUpdater().buildBackendAgentCheck(d)
+		c.compareObjects("agent check", i, d.backend.AgentCheck, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]map[string]string
+		paths    []string
+		expected map[string][]string
+		logging  string
+	}{
+		// 0
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackAllowedMethods: "GET,HEAD",
+				},
+			},
+			expected: map[string][]string{
+				"/": {"GET", "HEAD"},
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/app": {
+					ingtypes.BackAllowedMethods: "GET, POST",
+				},
+			},
+			paths: []string{"/"},
+			expected: map[string][]string{
+				"/":    nil,
+				"/app": {"GET", "POST"},
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackAllowedMethods: "GET,get,P0ST",
+				},
+			},
+			expected: map[string][]string{
+				"/": {"GET"},
+			},
+			logging: `
+WARN ignoring invalid http method on ingress 'default/ing1': get
+WARN ignoring invalid http method on ingress 'default/ing1': P0ST`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, test.ann, test.paths)
+		c.createUpdater().buildBackendAllowedMethods(d)
+		actual := map[string][]string{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.AllowedMethods
+		}
+		c.compareObjects("allowed methods", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestAuthExternal(t *testing.T) {
 	testCase := []struct {
 		url        string
@@ -600,7 +767,7 @@ func TestAuthHTTP(t *testing.T) {
 			secrets:      conv_helper.SecretContent{"default/basicpwd": {"auth": []byte("fail")}},
 			expUserlists: []*hatypes.Userlist{{Name: "default_basicpwd"}},
 			expLogging: `
-WARN ignoring malformed usr/passwd on secret 'default/basicpwd', declared on ingress 'default/ing1': missing password of user 'fail' line 1
+WARN ignoring malformed usr/passwd on secret 'default/basicpwd', declared on ingress 'default/ing1': missing user/password separator line 1
 WARN userlist on ingress 'default/ing1' for basic authentication is empty`,
 		},
 		// 6
@@ -616,7 +783,7 @@ nopwd`)}},
 			expUserlists: []*hatypes.Userlist{{Name: "default_basicpwd", Users: []hatypes.User{
 				{Name: "usr1", Passwd: "clearpwd1", Encrypted: false},
 			}}},
-			expLogging: "WARN ignoring malformed usr/passwd on secret 'default/basicpwd', declared on ingress 'default/ing1': missing password of user 'nopwd' line 3",
+			expLogging: "WARN ignoring malformed usr/passwd on secret 'default/basicpwd', declared on ingress 'default/ing1': missing user/password separator line 3",
 		},
 		// 7
 		{
@@ -691,6 +858,143 @@ usr2::clearpwd2`)}},
 	}
 }
 
+func TestBalance(t *testing.T) {
+	testCases := []struct {
+		ann         map[string]string
+		expBalance  string
+		expHashType string
+		logging     string
+	}{
+		// 0
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "roundrobin",
+			},
+			expBalance: "roundrobin",
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "roundrobin",
+				ingtypes.BackBalanceURIDepth:  "3",
+			},
+			expBalance: "roundrobin",
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "uri",
+			},
+			expBalance:  "uri",
+			expHashType: "consistent",
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "uri",
+				ingtypes.BackBalanceURIDepth:  "3",
+				ingtypes.BackBalanceURILen:    "20",
+			},
+			expBalance:  "uri depth 3 len 20",
+			expHashType: "consistent",
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "uri",
+				ingtypes.BackBalanceURIDepth:  "0",
+				ingtypes.BackBalanceURILen:    "a",
+			},
+			expBalance:  "uri",
+			expHashType: "consistent",
+			logging: `
+WARN ignoring invalid balance uri depth on ingress 'default/ing1': 0
+WARN ignoring invalid balance uri len on ingress 'default/ing1': a`,
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "source",
+				ingtypes.BackHashType:         "consistent",
+			},
+			expBalance:  "source",
+			expHashType: "consistent",
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "uri",
+				ingtypes.BackHashType:         "consistent  sdbm avalanche",
+			},
+			expBalance:  "uri",
+			expHashType: "consistent sdbm avalanche",
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "source",
+				ingtypes.BackHashType:         "map-based crc32",
+			},
+			expBalance:  "source",
+			expHashType: "map-based crc32",
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "source",
+				ingtypes.BackHashType:         "consistent wt6 avalanche",
+			},
+			expBalance:  "source",
+			expHashType: "consistent wt6 avalanche",
+		},
+		// 9
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm: "uri",
+				ingtypes.BackHashType:         "consistent md5",
+			},
+			expBalance:  "uri",
+			expHashType: "consistent",
+			logging:     `WARN ignoring invalid hash type on ingress 'default/ing1': consistent md5`,
+		},
+		// 10
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm:   "random",
+				ingtypes.BackBalanceRandomDraws: "2",
+			},
+			expBalance: "random(2)",
+		},
+		// 11
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm:   "roundrobin",
+				ingtypes.BackBalanceRandomDraws: "2",
+			},
+			expBalance: "roundrobin",
+		},
+		// 12
+		{
+			ann: map[string]string{
+				ingtypes.BackBalanceAlgorithm:   "random",
+				ingtypes.BackBalanceRandomDraws: "0",
+			},
+			expBalance: "random",
+			logging:    `WARN ignoring invalid balance random draws on ingress 'default/ing1': 0`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		c.createUpdater().buildBackendBalance(d)
+		c.compareObjects("balance", i, d.backend.BalanceAlgorithm, test.expBalance)
+		c.compareObjects("hash-type", i, d.backend.HashType, test.expHashType)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestBlueGreen(t *testing.T) {
 	buildPod := func(labels string) *api.Pod {
 		l := make(map[string]string)
@@ -1184,25 +1488,20 @@ func TestBodySize(t *testing.T) {
 	}
 }
 
-const (
-	corsDefaultHeaders = "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Authorization"
-	corsDefaultMethods = "GET, PUT, POST, DELETE, PATCH, OPTIONS"
-	corsDefaultMaxAge  = 86400
-)
-
-var corsDefaultOrigin = []string{"*"}
-
-func TestCors(t *testing.T) {
+func TestPriority(t *testing.T) {
 	testCases := []struct {
-		paths    []string
+		source   Source
 		ann      map[string]map[string]string
-		expected map[string]hatypes.Cors
+		paths    []string
+		expected map[string]hatypes.Priority
 		logging  string
 	}{
 		// 0
 		{
-			paths: []string{"/"},
-			expected: map[string]hatypes.Cors{
+			ann: map[string]map[string]string{
+				"/": {},
+			},
+			expected: map[string]hatypes.Priority{
 				"/": {},
 			},
 		},
@@ -1210,300 +1509,1467 @@ func TestCors(t *testing.T) {
 		{
 			ann: map[string]map[string]string{
 				"/": {
-					ingtypes.BackCorsEnable: "true",
+					ingtypes.BackPriorityClass: "10",
 				},
-			},
-			expected: map[string]hatypes.Cors{
-				"/": {
-					Enabled:          true,
-					AllowCredentials: false,
-					AllowHeaders:     corsDefaultHeaders,
-					AllowMethods:     corsDefaultMethods,
-					AllowOrigin:      corsDefaultOrigin,
-					ExposeHeaders:    "",
-					MaxAge:           corsDefaultMaxAge,
+				"/api": {
+					ingtypes.BackPriorityClass:  "-5",
+					ingtypes.BackPriorityOffset: "-1000",
 				},
 			},
+			expected: map[string]hatypes.Priority{
+				"/":    {Class: 10},
+				"/api": {Class: -5, Offset: -1000},
+			},
 		},
 		// 2
 		{
 			ann: map[string]map[string]string{
 				"/": {
-					ingtypes.BackCorsEnable: "false",
-				},
-				"/sub": {
-					ingtypes.BackCorsEnable: "true",
+					ingtypes.BackPriorityClass:  "2048",
+					ingtypes.BackPriorityOffset: "1s",
 				},
 			},
-			expected: map[string]hatypes.Cors{
+			expected: map[string]hatypes.Priority{
 				"/": {},
-				"/sub": {
-					Enabled:          true,
-					AllowCredentials: false,
-					AllowHeaders:     corsDefaultHeaders,
-					AllowMethods:     corsDefaultMethods,
-					AllowOrigin:      corsDefaultOrigin,
-					ExposeHeaders:    "",
-					MaxAge:           corsDefaultMaxAge,
-				},
 			},
+			source: Source{Namespace: "default", Name: "ing1", Type: "ingress"},
+			logging: `
+WARN ignoring invalid priority class on ingress 'default/ing1': 2048
+WARN ignoring invalid priority offset on ingress 'default/ing1': 1s`,
 		},
-		// 3
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", &test.source, map[string]string{}, test.ann, test.paths)
+		c.createUpdater().buildBackendPriority(d)
+		actual := map[string]hatypes.Priority{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.Priority
+		}
+		c.compareObjects("priority", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestQoS(t *testing.T) {
+	value := func(v int64) *int64 { return &v }
+	testCases := []struct {
+		source   Source
+		ann      map[string]map[string]string
+		paths    []string
+		expected map[string]hatypes.QoS
+		logging  string
+	}{
+		// 0
 		{
 			ann: map[string]map[string]string{
-				"/": {
-					ingtypes.BackCorsEnable:      "true",
-					ingtypes.BackCorsAllowOrigin: "http://d1.local,https://d2.local,https://d3.local",
-				},
+				"/": {},
 			},
-			expected: map[string]hatypes.Cors{
+			expected: map[string]hatypes.QoS{
+				"/": {},
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
 				"/": {
-					Enabled:          true,
-					AllowCredentials: false,
-					AllowHeaders:     corsDefaultHeaders,
-					AllowMethods:     corsDefaultMethods,
-					AllowOrigin:      []string{"http://d1.local", "https://d2.local", "https://d3.local"},
-					ExposeHeaders:    "",
-					MaxAge:           corsDefaultMaxAge,
+					ingtypes.BackQoSTOS: "46",
+				},
+				"/api": {
+					ingtypes.BackQoSMark: "0x1f",
+					ingtypes.BackQoSTOS:  "0xb8",
 				},
 			},
+			expected: map[string]hatypes.QoS{
+				"/":    {TOS: value(46)},
+				"/api": {Mark: value(31), TOS: value(184)},
+			},
 		},
-		// 4
+		// 2
 		{
 			ann: map[string]map[string]string{
 				"/": {
-					ingtypes.BackCorsEnable:      "true",
-					ingtypes.BackCorsAllowOrigin: "https://d1.local,https://d2.local,invalid",
+					ingtypes.BackQoSMark: "4294967296",
+					ingtypes.BackQoSTOS:  "256",
+				},
+				"/api": {
+					ingtypes.BackQoSMark: "-1",
+					ingtypes.BackQoSTOS:  "ef",
 				},
 			},
-			expected: map[string]hatypes.Cors{
+			expected: map[string]hatypes.QoS{
+				"/":    {},
+				"/api": {},
+			},
+			source: Source{Namespace: "default", Name: "ing1", Type: "ingress"},
+			logging: `
+WARN ignoring invalid qos mark on ingress 'default/ing1': 4294967296
+WARN ignoring invalid qos tos on ingress 'default/ing1': 256
+WARN ignoring invalid qos mark on ingress 'default/ing1': -1
+WARN ignoring invalid qos tos on ingress 'default/ing1': ef`,
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
 				"/": {
-					Enabled:          true,
-					AllowCredentials: false,
-					AllowHeaders:     corsDefaultHeaders,
-					AllowMethods:     corsDefaultMethods,
-					AllowOrigin:      corsDefaultOrigin,
-					ExposeHeaders:    "",
-					MaxAge:           corsDefaultMaxAge,
+					ingtypes.BackQoSMark: "0",
+					ingtypes.BackQoSTOS:  "0x00",
 				},
 			},
-			logging: `WARN ignoring invalid cors origin on ingress 'default/ing': invalid`,
+			expected: map[string]hatypes.QoS{
+				"/": {Mark: value(0), TOS: value(0)},
+			},
 		},
 	}
-	annDefault := map[string]string{
-		ingtypes.BackCorsAllowHeaders: corsDefaultHeaders,
-		ingtypes.BackCorsAllowMethods: corsDefaultMethods,
-		ingtypes.BackCorsAllowOrigin:  strings.Join(corsDefaultOrigin, ","),
-		ingtypes.BackCorsMaxAge:       strconv.Itoa(corsDefaultMaxAge),
-	}
-	source := &Source{
-		Namespace: "default",
-		Name:      "ing",
-		Type:      "ingress",
-	}
 	for i, test := range testCases {
 		c := setup(t)
-		d := c.createBackendMappingData("default/app", source, annDefault, test.ann, test.paths)
-		c.createUpdater().buildBackendCors(d)
-		actual := map[string]hatypes.Cors{}
+		d := c.createBackendMappingData("default/app", &test.source, map[string]string{}, test.ann, test.paths)
+		c.createUpdater().buildBackendQoS(d)
+		actual := map[string]hatypes.QoS{}
 		for _, path := range d.backend.Paths {
-			actual[path.Path()] = path.Cors
+			actual[path.Path()] = path.QoS
 		}
-		c.compareObjects("cors", i, actual, test.expected)
+		c.compareObjects("qos", i, actual, test.expected)
 		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
 }
 
-func TestCustomConfig(t *testing.T) {
-	defaultSource := &Source{
-		Type:      "Ingress",
-		Namespace: "default",
-		Name:      "app",
-	}
+const (
+	corsDefaultHeaders = "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Authorization"
+	corsDefaultMethods = "GET, PUT, POST, DELETE, PATCH, OPTIONS"
+	corsDefaultMaxAge  = 86400
+)
+
+var corsDefaultOrigin = []string{"*"}
+
+func TestCache(t *testing.T) {
 	testCases := []struct {
-		disabled []string
-		config   string
-		source   *Source
-		expected []string
+		ann      map[string]string
+		modeTCP  bool
+		other    hatypes.BackendCache
+		expected hatypes.BackendCache
 		logging  string
 	}{
 		// 0
-		{
-			config:   "  server srv001 127.0.0.1:8080",
-			expected: []string{"  server srv001 127.0.0.1:8080"},
-		},
+		{},
 		// 1
 		{
-			disabled: []string{"server"},
-			config:   "  server srv001 127.0.0.1:8080",
-			source:   defaultSource,
-			logging:  `WARN skipping configuration snippet on Ingress 'default/app': keyword 'server' not allowed`,
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static",
+			},
+			expected: hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4},
 		},
 		// 2
 		{
-			disabled: []string{"*"},
-			config:   "  server srv001 127.0.0.1:8080",
-			logging:  `WARN skipping configuration snippet on global config: custom configuration is disabled`,
+			ann: map[string]string{
+				ingtypes.BackCacheName:          "static",
+				ingtypes.BackCacheMaxAge:        "300",
+				ingtypes.BackCacheMaxObjectSize: "512k",
+				ingtypes.BackCacheTotalMaxSize:  "16",
+			},
+			expected: hatypes.BackendCache{Name: "static", MaxAge: 300, MaxObjectSize: 524288, TotalMaxSize: 16},
 		},
 		// 3
 		{
-			disabled: []string{"http-response"},
-			config: `
-  http-request set-header x-id 1 if { path / }
-`,
-			expected: []string{"", "  http-request set-header x-id 1 if { path / }"},
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static",
+			},
+			modeTCP: true,
+			logging: `WARN ignoring cache on ingress 'default/ing1': backend is in tcp mode`,
 		},
 		// 4
 		{
-			disabled: []string{"http-response"},
-			config: `
-  acl rootpath path /
-  http-request set-header x-id 1 if rootpath
-`,
-			expected: []string{"", "  acl rootpath path /", "  http-request set-header x-id 1 if rootpath"},
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static cache",
+			},
+			logging: `WARN ignoring invalid cache name on ingress 'default/ing1': static cache`,
 		},
 		// 5
 		{
-			disabled: []string{"http-response", "acl"},
-			config: `
-  acl rootpath path /
-  http-request set-header x-id 1 if rootpath
-`,
-			source:  defaultSource,
-			logging: `WARN skipping configuration snippet on Ingress 'default/app': keyword 'acl' not allowed`,
+			ann: map[string]string{
+				ingtypes.BackCacheName:         "static",
+				ingtypes.BackCacheTotalMaxSize: "4096",
+			},
+			logging: `WARN ignoring cache on ingress 'default/ing1': invalid total max size, should be a number of megabytes between 1 and 4095: 4096`,
 		},
 		// 6
 		{
-			disabled: []string{"http"},
-			config:   "  http-request set-header x-id 1 if { path / }",
-			expected: []string{"  http-request set-header x-id 1 if { path / }"},
+			ann: map[string]string{
+				ingtypes.BackCacheName:   "static",
+				ingtypes.BackCacheMaxAge: "1m",
+			},
+			logging: `WARN ignoring cache on ingress 'default/ing1': invalid max age, should be a positive number of seconds: 1m`,
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackCacheName:          "static",
+				ingtypes.BackCacheMaxObjectSize: "3m",
+			},
+			logging: `WARN ignoring cache on ingress 'default/ing1': invalid max object size, should be a positive size up to half of the total max size: 3m`,
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static",
+			},
+			other:    hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4},
+			expected: hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4},
+		},
+		// 9
+		{
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static",
+			},
+			other:    hatypes.BackendCache{Name: "default", MaxAge: 120, TotalMaxSize: 16},
+			expected: hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4},
+		},
+		// 10
+		{
+			ann: map[string]string{
+				ingtypes.BackCacheName: "static",
+			},
+			other:    hatypes.BackendCache{Name: "static", MaxAge: 120, TotalMaxSize: 16},
+			expected: hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4},
+			logging:  `WARN cache 'static' on ingress 'default/ing1' conflicts with the cache options of backend 'default_other_8080', using the options of the backend sorted first`,
 		},
 	}
+	annDefault := map[string]string{
+		ingtypes.BackCacheMaxAge:       "60",
+		ingtypes.BackCacheTotalMaxSize: "4",
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
 	for i, test := range testCases {
 		c := setup(t)
-		ann := map[string]map[string]string{
-			"/": {ingtypes.BackConfigBackend: test.config},
-		}
-		d := c.createBackendMappingData("default/app", test.source, map[string]string{}, ann, []string{"/"})
-		updater := c.createUpdater()
-		updater.options.DisableKeywords = test.disabled
-		updater.buildBackendCustomConfig(d)
-		c.compareObjects("custom config", i, d.backend.CustomConfig, test.expected)
+		c.haproxy.Backends().AcquireBackend("default", "other", "8080").Cache = test.other
+		d := c.createBackendData("default/app", source, test.ann, annDefault)
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendCache(d)
+		c.compareObjects("cache", i, d.backend.Cache, test.expected)
 		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
 }
 
-func TestFirstToken(t *testing.T) {
+func TestCanaryBackend(t *testing.T) {
 	testCases := []struct {
-		line     string
-		expected string
+		paths      []string
+		annDefault map[string]string
+		ann        map[string]map[string]string
+		modeTCP    bool
+		expected   map[string]hatypes.BackendCanary
+		logging    string
 	}{
 		// 0
 		{
-			line:     "",
-			expected: "",
+			paths: []string{"/"},
+			expected: map[string]hatypes.BackendCanary{
+				"/": {},
+			},
 		},
 		// 1
 		{
-			line:     "server",
-			expected: "server",
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCanaryBackend: "app-v2:8080",
+					ingtypes.BackCanaryWeight:  "10",
+				},
+				"/api": {
+					ingtypes.BackCanaryBackend: "other/app:http",
+					ingtypes.BackCanaryWeight:  "100",
+				},
+				"/app": {
+					ingtypes.BackCanaryBackend: "app-v2:8080",
+				},
+			},
+			expected: map[string]hatypes.BackendCanary{
+				"/":    {Backend: "default_app-v2_8080", Weight: 10},
+				"/api": {Backend: "other_app_8000", Weight: 100},
+				"/app": {},
+			},
 		},
 		// 2
 		{
-			line:     "server svc",
-			expected: "server",
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCanaryBackend: "app-v2:8080",
+					ingtypes.BackCanaryWeight:  "10",
+				},
+			},
+			modeTCP: true,
+			expected: map[string]hatypes.BackendCanary{
+				"/": {},
+			},
 		},
 		// 3
 		{
-			line:     "\tserver\tsvc",
-			expected: "server",
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCanaryBackend: "app-v2",
+					ingtypes.BackCanaryWeight:  "10",
+				},
+				"/api": {
+					ingtypes.BackCanaryBackend: "app:8080",
+					ingtypes.BackCanaryWeight:  "10",
+				},
+				"/app": {
+					ingtypes.BackCanaryBackend: "notfound:8080",
+					ingtypes.BackCanaryWeight:  "10",
+				},
+				"/web": {
+					ingtypes.BackCanaryBackend: "app-v2:8080",
+					ingtypes.BackCanaryWeight:  "101",
+				},
+			},
+			expected: map[string]hatypes.BackendCanary{
+				"/":    {},
+				"/api": {},
+				"/app": {},
+				"/web": {},
+			},
+			logging: `
+WARN ignoring canary backend on ingress 'default/ing1': invalid service syntax: app-v2
+WARN ignoring canary backend on ingress 'default/ing1': canary and primary backends are the same: app:8080
+WARN ignoring canary backend on ingress 'default/ing1': canary weight should be a percentage between 0 and 100: 101`,
 		},
 		// 4
 		{
-			line:     " \tserver \tsvc",
-			expected: "server",
+			annDefault: map[string]string{
+				ingtypes.BackCanaryBackend: "app-v2:8080",
+				ingtypes.BackCanaryWeight:  "10",
+			},
+			paths: []string{"/"},
+			expected: map[string]hatypes.BackendCanary{
+				"/": {},
+			},
+			logging: `WARN ignoring canary backend on global config: a '<namespace>/<name>:<port>' service reference should be used: app-v2:8080`,
 		},
 		// 5
 		{
-			line:     "  server  svc",
-			expected: "server",
+			annDefault: map[string]string{
+				ingtypes.BackCanaryBackend: "default/app-v2:8080",
+				ingtypes.BackCanaryWeight:  "10",
+			},
+			paths: []string{"/"},
+			expected: map[string]hatypes.BackendCanary{
+				"/": {Backend: "default_app-v2_8080", Weight: 10},
+			},
 		},
 	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
 	for i, test := range testCases {
 		c := setup(t)
-		c.compareObjects("token", i, firstToken(test.line), test.expected)
+		annDefault := map[string]string{
+			ingtypes.BackCanaryWeight: "0",
+		}
+		for key, value := range test.annDefault {
+			annDefault[key] = value
+		}
+		for _, svc := range []struct{ name, port string }{
+			{"default/app", "8080"},
+			{"default/app-v2", "8080"},
+			{"other/app", "http:80:8000"},
+		} {
+			s, _ := conv_helper.CreateService(svc.name, svc.port, "")
+			c.cache.SvcList = append(c.cache.SvcList, s)
+			ssvc := strings.Split(svc.name, "/")
+			c.haproxy.Backends().AcquireBackend(ssvc[0], ssvc[1], s.Spec.Ports[0].TargetPort.String())
+		}
+		d := c.createBackendMappingData("default/app", source, annDefault, test.ann, test.paths)
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendCanary(d)
+		actual := map[string]hatypes.BackendCanary{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.Canary
+		}
+		c.compareObjects("canary backend", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
 }
 
-func TestHeaders(t *testing.T) {
+func TestConnectionMode(t *testing.T) {
 	testCases := []struct {
-		headers  string
-		expected []*hatypes.BackendHeader
-		logging  string
+		annDefault map[string]string
+		ann        map[string]string
+		expected   string
+		logging    string
 	}{
 		// 0
-		{
-			headers: `invalid`,
-			logging: `WARN ignored missing header name or value on ingress 'ing1/app': invalid`,
-		},
+		{},
 		// 1
 		{
-			headers: `key value`,
-			expected: []*hatypes.BackendHeader{
-				{Name: "key", Value: "value"},
+			annDefault: map[string]string{
+				ingtypes.BackHTTPConnectionMode: "httpclose",
 			},
 		},
 		// 2
 		{
-			headers: `name: content`,
-			expected: []*hatypes.BackendHeader{
-				{Name: "name", Value: "content"},
+			ann: map[string]string{
+				ingtypes.BackHTTPConnectionMode: "http-keep-alive",
 			},
+			expected: "http-keep-alive",
 		},
 		// 3
 		{
-			headers: `k:v`,
-			expected: []*hatypes.BackendHeader{
-				{Name: "k", Value: "v"},
+			ann: map[string]string{
+				ingtypes.BackHTTPConnectionMode: "http-server-close",
 			},
+			expected: "http-server-close",
 		},
 		// 4
 		{
-			headers: `host: %[service].%[namespace].svc.cluster.local`,
-			expected: []*hatypes.BackendHeader{
-				{Name: "host", Value: "app.default.svc.cluster.local"},
+			ann: map[string]string{
+				ingtypes.BackHTTPConnectionMode: "httpclose",
 			},
+			expected: "httpclose",
 		},
 		// 5
 		{
-			headers: `
-k8snamespace: %[namespace]
-k8sservice: %[service]
-host: %[service].%[namespace].svc.cluster.local
-`,
-			expected: []*hatypes.BackendHeader{
-				{Name: "k8snamespace", Value: "default"},
-				{Name: "k8sservice", Value: "app"},
+			ann: map[string]string{
+				ingtypes.BackHTTPConnectionMode: "close",
+			},
+			logging: `WARN ignoring invalid http connection mode on ingress 'default/ing1': close`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, test.annDefault)
+		c.createUpdater().buildBackendConnectionMode(d)
+		c.compareObjects("connection mode", i, d.backend.ConnectionMode, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestConnectionPool(t *testing.T) {
+	testCases := []struct {
+		ann        map[string]string
+		expMaxConn string
+		expPurge   string
+		logging    string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackPoolMaxConn: "20",
+			},
+			expMaxConn: "20",
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackPoolMaxConn:    "-1",
+				ingtypes.BackPoolPurgeDelay: "10s",
+			},
+			expMaxConn: "-1",
+			expPurge:   "10s",
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackPoolMaxConn: "0",
+			},
+			expMaxConn: "0",
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackPoolMaxConn: "-2",
+			},
+			logging: `WARN ignoring invalid pool max connections on ingress 'default/ing1': -2`,
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackPoolMaxConn:    "none",
+				ingtypes.BackPoolPurgeDelay: "10",
+			},
+			logging: `
+WARN ignoring invalid pool max connections on ingress 'default/ing1': none
+WARN ignoring invalid time format on ingress 'default/ing1': 10`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		c.createUpdater().buildBackendConnectionPool(d)
+		var maxConn string
+		if d.backend.Server.PoolMaxConn != nil {
+			maxConn = strconv.Itoa(*d.backend.Server.PoolMaxConn)
+		}
+		c.compareObjects("pool max conn", i, maxConn, test.expMaxConn)
+		c.compareObjects("pool purge delay", i, d.backend.Server.PoolPurge, test.expPurge)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestCors(t *testing.T) {
+	testCases := []struct {
+		paths    []string
+		ann      map[string]map[string]string
+		expected map[string]hatypes.Cors
+		logging  string
+	}{
+		// 0
+		{
+			paths: []string{"/"},
+			expected: map[string]hatypes.Cors{
+				"/": {},
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCorsEnable: "true",
+				},
+			},
+			expected: map[string]hatypes.Cors{
+				"/": {
+					Enabled:          true,
+					AllowCredentials: false,
+					AllowHeaders:     corsDefaultHeaders,
+					AllowMethods:     corsDefaultMethods,
+					AllowOrigin:      corsDefaultOrigin,
+					ExposeHeaders:    "",
+					MaxAge:           corsDefaultMaxAge,
+				},
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCorsEnable: "false",
+				},
+				"/sub": {
+					ingtypes.BackCorsEnable: "true",
+				},
+			},
+			expected: map[string]hatypes.Cors{
+				"/": {},
+				"/sub": {
+					Enabled:          true,
+					AllowCredentials: false,
+					AllowHeaders:     corsDefaultHeaders,
+					AllowMethods:     corsDefaultMethods,
+					AllowOrigin:      corsDefaultOrigin,
+					ExposeHeaders:    "",
+					MaxAge:           corsDefaultMaxAge,
+				},
+			},
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCorsEnable:      "true",
+					ingtypes.BackCorsAllowOrigin: "http://d1.local,https://d2.local,https://d3.local",
+				},
+			},
+			expected: map[string]hatypes.Cors{
+				"/": {
+					Enabled:          true,
+					AllowCredentials: false,
+					AllowHeaders:     corsDefaultHeaders,
+					AllowMethods:     corsDefaultMethods,
+					AllowOrigin:      []string{"http://d1.local", "https://d2.local", "https://d3.local"},
+					ExposeHeaders:    "",
+					MaxAge:           corsDefaultMaxAge,
+				},
+			},
+		},
+		// 4
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackCorsEnable:      "true",
+					ingtypes.BackCorsAllowOrigin: "https://d1.local,https://d2.local,invalid",
+				},
+			},
+			expected: map[string]hatypes.Cors{
+				"/": {
+					Enabled:          true,
+					AllowCredentials: false,
+					AllowHeaders:     corsDefaultHeaders,
+					AllowMethods:     corsDefaultMethods,
+					AllowOrigin:      corsDefaultOrigin,
+					ExposeHeaders:    "",
+					MaxAge:           corsDefaultMaxAge,
+				},
+			},
+			logging: `WARN ignoring invalid cors origin on ingress 'default/ing': invalid`,
+		},
+	}
+	annDefault := map[string]string{
+		ingtypes.BackCorsAllowHeaders: corsDefaultHeaders,
+		ingtypes.BackCorsAllowMethods: corsDefaultMethods,
+		ingtypes.BackCorsAllowOrigin:  strings.Join(corsDefaultOrigin, ","),
+		ingtypes.BackCorsMaxAge:       strconv.Itoa(corsDefaultMaxAge),
+	}
+	source := &Source{
+		Namespace: "default",
+		Name:      "ing",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", source, annDefault, test.ann, test.paths)
+		c.createUpdater().buildBackendCors(d)
+		actual := map[string]hatypes.Cors{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.Cors
+		}
+		c.compareObjects("cors", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestCustomConfig(t *testing.T) {
+	defaultSource := &Source{
+		Type:      "Ingress",
+		Namespace: "default",
+		Name:      "app",
+	}
+	testCases := []struct {
+		disabled []string
+		config   string
+		source   *Source
+		expected []string
+		logging  string
+	}{
+		// 0
+		{
+			config:   "  server srv001 127.0.0.1:8080",
+			expected: []string{"  server srv001 127.0.0.1:8080"},
+		},
+		// 1
+		{
+			disabled: []string{"server"},
+			config:   "  server srv001 127.0.0.1:8080",
+			source:   defaultSource,
+			logging:  `WARN skipping configuration snippet on Ingress 'default/app': keyword 'server' not allowed`,
+		},
+		// 2
+		{
+			disabled: []string{"*"},
+			config:   "  server srv001 127.0.0.1:8080",
+			logging:  `WARN skipping configuration snippet on global config: custom configuration is disabled`,
+		},
+		// 3
+		{
+			disabled: []string{"http-response"},
+			config: `
+  http-request set-header x-id 1 if { path / }
+`,
+			expected: []string{"", "  http-request set-header x-id 1 if { path / }"},
+		},
+		// 4
+		{
+			disabled: []string{"http-response"},
+			config: `
+  acl rootpath path /
+  http-request set-header x-id 1 if rootpath
+`,
+			expected: []string{"", "  acl rootpath path /", "  http-request set-header x-id 1 if rootpath"},
+		},
+		// 5
+		{
+			disabled: []string{"http-response", "acl"},
+			config: `
+  acl rootpath path /
+  http-request set-header x-id 1 if rootpath
+`,
+			source:  defaultSource,
+			logging: `WARN skipping configuration snippet on Ingress 'default/app': keyword 'acl' not allowed`,
+		},
+		// 6
+		{
+			disabled: []string{"http"},
+			config:   "  http-request set-header x-id 1 if { path / }",
+			expected: []string{"  http-request set-header x-id 1 if { path / }"},
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		ann := map[string]map[string]string{
+			"/": {ingtypes.BackConfigBackend: test.config},
+		}
+		d := c.createBackendMappingData("default/app", test.source, map[string]string{}, ann, []string{"/"})
+		updater := c.createUpdater()
+		updater.options.DisableKeywords = test.disabled
+		updater.buildBackendCustomConfig(d)
+		c.compareObjects("custom config", i, d.backend.CustomConfig, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestFallbackBackend(t *testing.T) {
+	testCases := []struct {
+		paths      []string
+		annDefault map[string]string
+		ann        map[string]map[string]string
+		modeTCP    bool
+		expected   map[string]string
+		logging    string
+	}{
+		// 0
+		{
+			paths: []string{"/"},
+			expected: map[string]string{
+				"/": "",
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackFallbackBackend: "app-v1:8080",
+				},
+				"/api": {
+					ingtypes.BackFallbackBackend: "other/app:http",
+				},
+			},
+			paths: []string{"/app"},
+			expected: map[string]string{
+				"/":    "default_app-v1_8080",
+				"/api": "other_app_8000",
+				"/app": "",
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackFallbackBackend: "app-v1:8080",
+				},
+			},
+			modeTCP: true,
+			expected: map[string]string{
+				"/": "",
+			},
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackFallbackBackend: "app-v1",
+				},
+				"/api": {
+					ingtypes.BackFallbackBackend: "app:8080",
+				},
+				"/app": {
+					ingtypes.BackFallbackBackend: "notfound:8080",
+				},
+			},
+			expected: map[string]string{
+				"/":    "",
+				"/api": "",
+				"/app": "",
+			},
+			logging: `
+WARN ignoring fallback backend on ingress 'default/ing1': invalid service syntax: app-v1
+WARN ignoring fallback backend on ingress 'default/ing1': fallback and primary backends are the same: app:8080`,
+		},
+		// 4
+		{
+			annDefault: map[string]string{
+				ingtypes.BackFallbackBackend: "app-v1:8080",
+			},
+			paths: []string{"/"},
+			expected: map[string]string{
+				"/": "",
+			},
+			logging: `WARN ignoring fallback backend on global config: a '<namespace>/<name>:<port>' service reference should be used: app-v1:8080`,
+		},
+		// 5
+		{
+			annDefault: map[string]string{
+				ingtypes.BackFallbackBackend: "default/app-v1:8080",
+			},
+			paths: []string{"/"},
+			expected: map[string]string{
+				"/": "default_app-v1_8080",
+			},
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		for _, svc := range []struct{ name, port string }{
+			{"default/app", "8080"},
+			{"default/app-v1", "8080"},
+			{"other/app", "http:80:8000"},
+		} {
+			s, _ := conv_helper.CreateService(svc.name, svc.port, "")
+			c.cache.SvcList = append(c.cache.SvcList, s)
+			ssvc := strings.Split(svc.name, "/")
+			c.haproxy.Backends().AcquireBackend(ssvc[0], ssvc[1], s.Spec.Ports[0].TargetPort.String())
+		}
+		d := c.createBackendMappingData("default/app", source, test.annDefault, test.ann, test.paths)
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendFallback(d)
+		actual := map[string]string{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.FallbackBackend
+		}
+		c.compareObjects("fallback backend", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestFirstToken(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected string
+	}{
+		// 0
+		{
+			line:     "",
+			expected: "",
+		},
+		// 1
+		{
+			line:     "server",
+			expected: "server",
+		},
+		// 2
+		{
+			line:     "server svc",
+			expected: "server",
+		},
+		// 3
+		{
+			line:     "\tserver\tsvc",
+			expected: "server",
+		},
+		// 4
+		{
+			line:     " \tserver \tsvc",
+			expected: "server",
+		},
+		// 5
+		{
+			line:     "  server  svc",
+			expected: "server",
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.compareObjects("token", i, firstToken(test.line), test.expected)
+		c.teardown()
+	}
+}
+
+func TestHeaderMatch(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]map[string]string
+		paths    []string
+		expected map[string]hatypes.HeaderMatch
+		logging  string
+	}{
+		// 0
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackHeaderMatch: "X-Canary",
+				},
+			},
+			expected: map[string]hatypes.HeaderMatch{
+				"/": {Name: "X-Canary"},
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/app": {
+					ingtypes.BackHeaderMatch: "X-Version: v2",
+				},
+			},
+			paths: []string{"/"},
+			expected: map[string]hatypes.HeaderMatch{
+				"/":    {},
+				"/app": {Name: "X-Version", Value: "v2"},
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackHeaderMatch:      "X-Version:^v[34]$",
+					ingtypes.BackHeaderMatchRegex: "true",
+				},
+			},
+			expected: map[string]hatypes.HeaderMatch{
+				"/": {Name: "X-Version", Value: "^v[34]$", Regex: true},
+			},
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackHeaderMatch:      "X-Canary",
+					ingtypes.BackHeaderMatchRegex: "true",
+				},
+			},
+			expected: map[string]hatypes.HeaderMatch{
+				"/": {Name: "X-Canary"},
+			},
+		},
+		// 4
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackHeaderMatch: "X Canary",
+				},
+				"/app": {
+					ingtypes.BackHeaderMatch: "X-Version: v2 v3",
+				},
+			},
+			expected: map[string]hatypes.HeaderMatch{
+				"/":    {},
+				"/app": {},
+			},
+			logging: `
+WARN ignoring invalid header match on ingress 'default/ing1': X Canary
+WARN ignoring invalid header match on ingress 'default/ing1': X-Version: v2 v3`,
+		},
+		// 5
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackHeaderMatch:      "X-Version: v[2",
+					ingtypes.BackHeaderMatchRegex: "true",
+				},
+			},
+			expected: map[string]hatypes.HeaderMatch{
+				"/": {},
+			},
+			logging: `WARN ignoring invalid header match regex on ingress 'default/ing1': v[2`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, test.ann, test.paths)
+		c.createUpdater().buildBackendHeaderMatch(d)
+		actual := map[string]hatypes.HeaderMatch{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.HeaderMatch
+		}
+		c.compareObjects("header match", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	testCases := []struct {
+		headers  string
+		expected []*hatypes.BackendHeader
+		logging  string
+	}{
+		// 0
+		{
+			headers: `invalid`,
+			logging: `WARN ignored missing header name or value on ingress 'ing1/app': invalid`,
+		},
+		// 1
+		{
+			headers: `key value`,
+			expected: []*hatypes.BackendHeader{
+				{Name: "key", Value: "value"},
+			},
+		},
+		// 2
+		{
+			headers: `name: content`,
+			expected: []*hatypes.BackendHeader{
+				{Name: "name", Value: "content"},
+			},
+		},
+		// 3
+		{
+			headers: `k:v`,
+			expected: []*hatypes.BackendHeader{
+				{Name: "k", Value: "v"},
+			},
+		},
+		// 4
+		{
+			headers: `host: %[service].%[namespace].svc.cluster.local`,
+			expected: []*hatypes.BackendHeader{
 				{Name: "host", Value: "app.default.svc.cluster.local"},
 			},
 		},
+		// 5
+		{
+			headers: `
+k8snamespace: %[namespace]
+k8sservice: %[service]
+host: %[service].%[namespace].svc.cluster.local
+`,
+			expected: []*hatypes.BackendHeader{
+				{Name: "k8snamespace", Value: "default"},
+				{Name: "k8sservice", Value: "app"},
+				{Name: "host", Value: "app.default.svc.cluster.local"},
+			},
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		ann := map[string]map[string]string{
+			"/": {ingtypes.BackHeaders: test.headers},
+		}
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, ann, []string{"/"})
+		c.createUpdater().buildBackendHeaders(d)
+		c.compareObjects("headers", i, d.backend.Headers, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestHeadersRemove(t *testing.T) {
+	testCases := []struct {
+		headers  string
+		expected []string
+		logging  string
+	}{
+		// 0
+		{
+			headers: ``,
+		},
+		// 1
+		{
+			headers:  `Server`,
+			expected: []string{"Server"},
+		},
+		// 2
+		{
+			headers:  `Server, X-Powered-By,,`,
+			expected: []string{"Server", "X-Powered-By"},
+		},
+		// 3
+		{
+			headers:  `Server,X Powered By`,
+			expected: []string{"Server"},
+			logging:  `WARN ignoring invalid header name on ingress 'ing1/app': X Powered By`,
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		ann := map[string]map[string]string{
+			"/": {ingtypes.BackHeadersResponseRemove: test.headers},
+		}
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, ann, []string{"/"})
+		c.createUpdater().buildBackendHeadersRemove(d)
+		c.compareObjects("headers remove", i, d.backend.HeadersRemove, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestHeadersReplace(t *testing.T) {
+	testCases := []struct {
+		headers  string
+		expected []*hatypes.BackendHeaderReplace
+		logging  string
+	}{
+		// 0
+		{
+			headers: ``,
+		},
+		// 1
+		{
+			headers: `Cookie (.*);?\s*tracking=[^;]*(.*) \1\2`,
+			expected: []*hatypes.BackendHeaderReplace{
+				{Name: "Cookie", Match: `(.*);?\s*tracking=[^;]*(.*)`, Replace: `\1\2`},
+			},
+		},
+		// 2
+		{
+			headers: `
+Cookie ^(.*)session=[^;]*(.*)$ \1\2
+
+X-Forwarded-Host ^(.*):[0-9]+$ \1
+`,
+			expected: []*hatypes.BackendHeaderReplace{
+				{Name: "Cookie", Match: `^(.*)session=[^;]*(.*)$`, Replace: `\1\2`},
+				{Name: "X-Forwarded-Host", Match: `^(.*):[0-9]+$`, Replace: `\1`},
+			},
+		},
+		// 3
+		{
+			headers: `Cookie ^(.*)$`,
+			logging: `WARN ignoring header replace on ingress 'ing1/app': expected '<name> <match-regex> <replacement>', found: Cookie ^(.*)$`,
+		},
+		// 4
+		{
+			headers: `X:Host ^(.*)$ \1`,
+			logging: `WARN ignoring invalid header name on ingress 'ing1/app': X:Host`,
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		ann := map[string]map[string]string{
+			"/": {ingtypes.BackHeadersReplace: test.headers},
+		}
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, ann, []string{"/"})
+		c.createUpdater().buildBackendHeadersReplace(d)
+		c.compareObjects("headers replace", i, d.backend.HeadersReplace, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestRequiredHeader(t *testing.T) {
+	testCases := []struct {
+		header   string
+		modeTCP  bool
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			header:   "Authorization",
+			expected: "Authorization",
+		},
+		// 2
+		{
+			header:  "X Api Key",
+			logging: `WARN ignoring invalid header name on ingress 'ing1/app': X Api Key`,
+		},
+		// 3
+		{
+			header:  "Authorization",
+			modeTCP: true,
+			logging: `WARN ignoring required header on ingress 'ing1/app': backend is in tcp mode`,
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, map[string]string{ingtypes.BackRequiredHeader: test.header}, map[string]string{})
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendRequiredHeader(d)
+		c.compareObjects("required header", i, d.backend.RequiredHeader, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestResolveDst(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		modeTCP  bool
+		expected hatypes.BackendResolveDst
+		logging  string
+	}{
+		// 0
+		{
+			ann: map[string]string{},
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstHeader:   "X-Backend-Host",
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+			expected: hatypes.BackendResolveDst{Header: "X-Backend-Host", Resolver: "k8s"},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstHeader: "X-Backend-Host",
+			},
+			logging: `WARN ignoring resolve dst on ingress 'default/ing1': missing resolver`,
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstHeader:   "X-Backend-Host",
+				ingtypes.BackResolveDstResolver: "external",
+			},
+			logging: `WARN skipping undeclared DNS resolver on ingress 'default/ing1': external`,
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstHeader:   "X Backend Host",
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+			logging: `WARN ignoring invalid header name on ingress 'default/ing1': X Backend Host`,
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstHeader:   "X-Backend-Host",
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+			modeTCP: true,
+			logging: `WARN ignoring resolve dst on ingress 'default/ing1': backend is in tcp mode`,
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstDomains:  "svc.cluster.local, Example.com",
+				ingtypes.BackResolveDstHeader:   "X-Backend-Host",
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+			expected: hatypes.BackendResolveDst{Domains: []string{"svc.cluster.local", "example.com"}, Header: "X-Backend-Host", Resolver: "k8s"},
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackResolveDstDomains:  "svc.cluster.local,*.example.com",
+				ingtypes.BackResolveDstHeader:   "X-Backend-Host",
+				ingtypes.BackResolveDstResolver: "k8s",
+			},
+			expected: hatypes.BackendResolveDst{Domains: []string{"svc.cluster.local"}, Header: "X-Backend-Host", Resolver: "k8s"},
+			logging:  `WARN ignoring invalid resolve dst domain on ingress 'default/ing1': *.example.com`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		c.haproxy.Global().DNS.Resolvers = []*hatypes.DNSResolver{{Name: "k8s"}}
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendResolveDst(d)
+		c.compareObjects("resolve dst", i, d.backend.ResolveDst, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	testCases := []struct {
+		status   string
+		modeTCP  bool
+		expected []*hatypes.BackendResponseStatus
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			status: "502 { status 200 } { res.hdr(X-Error) -m found }",
+			expected: []*hatypes.BackendResponseStatus{
+				{Code: 502, Condition: "{ status 200 } { res.hdr(X-Error) -m found }"},
+			},
+		},
+		// 2
+		{
+			status: `
+503 { status 500 }
+
+404`,
+			expected: []*hatypes.BackendResponseStatus{
+				{Code: 503, Condition: "{ status 500 }"},
+				{Code: 404},
+			},
+		},
+		// 3
+		{
+			status:  "50x { status 200 }",
+			logging: `WARN ignoring response set status on ingress 'ing1/app': expected '<code> [<acl-condition>]' with a status code between 100 and 599, found: 50x { status 200 }`,
+		},
+		// 4
+		{
+			status:  "502 if { status 200 }",
+			logging: `WARN ignoring response set status on ingress 'ing1/app': expected an acl condition without 'if': 502 if { status 200 }`,
+		},
+		// 5
+		{
+			status:  "502 { status 200 }",
+			modeTCP: true,
+			logging: `WARN ignoring response set status on ingress 'ing1/app': backend is in tcp mode`,
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, map[string]string{ingtypes.BackResponseSetStatus: test.status}, map[string]string{})
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendResponseStatus(d)
+		c.compareObjects("response status", i, d.backend.ResponseStatus, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected hatypes.HealthCheck
+		logging  string
+	}{
+		// 0
+		{
+			expected: hatypes.HealthCheck{Interval: "2s"},
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckPort: "8080",
+				ingtypes.BackHealthCheckURI:  "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", Port: 8080, URI: "/health"},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckDisabled: "true",
+			},
+			expected: hatypes.HealthCheck{Disabled: true},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckDisabled: "true",
+				ingtypes.BackHealthCheckPort:     "8080",
+				ingtypes.BackHealthCheckURI:      "/health",
+			},
+			expected: hatypes.HealthCheck{Disabled: true},
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSSLMode: "ssl",
+				ingtypes.BackHealthCheckURI:     "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SSLMode: "ssl", URI: "/health"},
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSNI: "app.local",
+				ingtypes.BackHealthCheckURI: "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SNI: "app.local", SSLMode: "ssl", URI: "/health"},
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSNI: "app local",
+				ingtypes.BackHealthCheckURI: "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", URI: "/health"},
+			logging:  `WARN ignoring invalid health check sni on ingress 'system/ing1': app local`,
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSSLMode: "ssl",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SSLMode: "ssl"},
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSSLMode: "no-ssl",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SSLMode: "no-ssl"},
+		},
+		// 9
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSSLMode: "default",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s"},
+		},
+		// 10
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSSLMode: "tls",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s"},
+			logging:  `WARN ignoring invalid health check ssl mode on ingress 'system/ing1': tls`,
+		},
+		// 11
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSNI: "app.local",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s"},
+			logging:  `WARN ignoring health check sni on ingress 'system/ing1': health-check-uri was not configured`,
+		},
+		// 12
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSNI:     "app.local",
+				ingtypes.BackHealthCheckSSLMode: "no-ssl",
+				ingtypes.BackHealthCheckURI:     "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SSLMode: "no-ssl", URI: "/health"},
+			logging:  `WARN ignoring health check sni on ingress 'system/ing1': health check ssl mode is no-ssl`,
+		},
+		// 13
+		{
+			ann: map[string]string{
+				ingtypes.BackHealthCheckSNI:     "app.local",
+				ingtypes.BackHealthCheckSSLMode: "ssl",
+				ingtypes.BackHealthCheckURI:     "/health",
+			},
+			expected: hatypes.HealthCheck{Interval: "2s", SNI: "app.local", SSLMode: "ssl", URI: "/health"},
+		},
 	}
 	source := &Source{
-		Namespace: "ing1",
-		Name:      "app",
+		Namespace: "system",
+		Name:      "ing1",
 		Type:      "ingress",
 	}
+	annDefault := map[string]string{
+		ingtypes.BackHealthCheckDisabled: "false",
+		ingtypes.BackHealthCheckInterval: "2s",
+	}
 	for i, test := range testCases {
 		c := setup(t)
-		ann := map[string]map[string]string{
-			"/": {ingtypes.BackHeaders: test.headers},
-		}
-		d := c.createBackendMappingData("default/app", source, map[string]string{}, ann, []string{"/"})
-		c.createUpdater().buildBackendHeaders(d)
-		c.compareObjects("headers", i, d.backend.Headers, test.expected)
+		d := c.createBackendData("default/app", source, test.ann, annDefault)
+		c.createUpdater().buildBackendHealthCheck(d)
+		c.compareObjects("health check", i, d.backend.HealthCheck, test.expected)
 		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
@@ -1595,6 +3061,269 @@ func TestHSTS(t *testing.T) {
 	}
 }
 
+func TestLimit(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		modeTCP  bool
+		expected hatypes.BackendLimit
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitRPS: "20",
+			},
+			expected: hatypes.BackendLimit{RPS: 20},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitAction:      "silent-drop",
+				ingtypes.BackLimitConnections: "200",
+			},
+			expected: hatypes.BackendLimit{Action: "silent-drop", Connections: 200},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitAction: "drop",
+				ingtypes.BackLimitRPS:    "20",
+			},
+			expected: hatypes.BackendLimit{RPS: 20},
+			logging:  `WARN ignoring invalid limit action on ingress 'ing1/app': drop`,
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitAction: "tarpit",
+				ingtypes.BackLimitRPS:    "20",
+			},
+			expected: hatypes.BackendLimit{Action: "tarpit", RPS: 20},
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitDenyStatus: "503",
+				ingtypes.BackLimitRPS:        "20",
+			},
+			expected: hatypes.BackendLimit{DenyStatus: 503, RPS: 20},
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitDenyStatus: "302",
+				ingtypes.BackLimitRPS:        "20",
+			},
+			expected: hatypes.BackendLimit{RPS: 20},
+			logging:  `WARN ignoring invalid deny status on ingress 'ing1/app', expected an error code between 400 and 599: 302`,
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitGPC0Increment: "{ path_beg /login } { method POST }",
+				ingtypes.BackLimitGPC0Threshold: "10",
+			},
+			expected: hatypes.BackendLimit{GPC0Increment: "{ path_beg /login } { method POST }", GPC0Threshold: 10},
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitGPC0Increment: "{ path_beg /login }",
+			},
+			expected: hatypes.BackendLimit{},
+		},
+		// 9
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitGPC0Increment: "{ path_beg /login }",
+				ingtypes.BackLimitGPC0Threshold: "0",
+			},
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring invalid limit gpc0 threshold on ingress 'ing1/app': 0`,
+		},
+		// 10
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitGPC0Increment: "if { path_beg /login }",
+				ingtypes.BackLimitGPC0Threshold: "10",
+			},
+			expected: hatypes.BackendLimit{GPC0Threshold: 10},
+			logging:  `WARN ignoring limit gpc0 increment on ingress 'ing1/app': expected an acl condition without 'if': if { path_beg /login }`,
+		},
+		// 11
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitGPC0Threshold: "10",
+			},
+			modeTCP:  true,
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring limit gpc0 on ingress 'ing1/app': backend is in tcp mode`,
+		},
+		// 12
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "cookie:sessionid",
+				ingtypes.BackLimitSessionRPS: "10",
+			},
+			expected: hatypes.BackendLimit{SessionKey: "req.cook(sessionid)", SessionRPS: 10},
+		},
+		// 13
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "header:X-Api-Key",
+				ingtypes.BackLimitSessionRPS: "100",
+			},
+			expected: hatypes.BackendLimit{SessionKey: "req.hdr(X-Api-Key)", SessionRPS: 100},
+		},
+		// 14
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "cookie:sessionid",
+			},
+			expected: hatypes.BackendLimit{},
+		},
+		// 15
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionRPS: "10",
+			},
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring limit session on ingress 'ing1/app': expected 'cookie:<name>' or 'header:<name>' as the session key: ''`,
+		},
+		// 16
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "query:sessionid",
+				ingtypes.BackLimitSessionRPS: "10",
+			},
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring limit session on ingress 'ing1/app': expected 'cookie:<name>' or 'header:<name>' as the session key: 'query:sessionid'`,
+		},
+		// 17
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "cookie:session id",
+				ingtypes.BackLimitSessionRPS: "10",
+			},
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring limit session on ingress 'ing1/app': expected 'cookie:<name>' or 'header:<name>' as the session key: 'cookie:session id'`,
+		},
+		// 18
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "cookie:sessionid",
+				ingtypes.BackLimitSessionRPS: "-1",
+			},
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring invalid limit session rps on ingress 'ing1/app': -1`,
+		},
+		// 19
+		{
+			ann: map[string]string{
+				ingtypes.BackLimitSessionKey: "cookie:sessionid",
+				ingtypes.BackLimitSessionRPS: "10",
+			},
+			modeTCP:  true,
+			expected: hatypes.BackendLimit{},
+			logging:  `WARN ignoring limit session on ingress 'ing1/app': backend is in tcp mode`,
+		},
+	}
+	source := &Source{
+		Namespace: "ing1",
+		Name:      "app",
+		Type:      "ingress",
+	}
+	annDefault := map[string]string{
+		ingtypes.BackLimitAction: "deny",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, annDefault)
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendLimit(d)
+		c.compareObjects("limit", i, d.backend.Limit, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]map[string]string
+		paths    []string
+		modeTCP  bool
+		expected map[string]string
+		logging  string
+	}{
+		// 0
+		{
+			ann: map[string]map[string]string{
+				"/": {},
+			},
+			expected: map[string]string{
+				"/": "",
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/": {},
+				"/healthz": {
+					ingtypes.BackLogLevel: "silent",
+				},
+			},
+			expected: map[string]string{
+				"/":        "",
+				"/healthz": "silent",
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackLogLevel: "debug",
+				},
+				"/healthz": {
+					ingtypes.BackLogLevel: "quiet",
+				},
+			},
+			expected: map[string]string{
+				"/":        "debug",
+				"/healthz": "",
+			},
+			logging: `WARN ignoring invalid log level on ingress 'default/ing1': quiet`,
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackLogLevel: "silent",
+				},
+			},
+			modeTCP: true,
+			expected: map[string]string{
+				"/": "",
+			},
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", source, map[string]string{}, test.ann, test.paths)
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendLogLevel(d)
+		actual := map[string]string{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.LogLevel
+		}
+		c.compareObjects("log level", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestOAuth(t *testing.T) {
 	testCases := []struct {
 		ann      map[string]map[string]string
@@ -1898,6 +3627,132 @@ WARN oauth2_proxy on ingress 'default/ing1' needs Lua json module, install lua-j
 	}
 }
 
+func TestPersist(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected hatypes.BackendPersist
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackPersist: "true",
+			},
+			expected: hatypes.BackendPersist{Enabled: true},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackPersist:      "true",
+				ingtypes.BackPersistForce: "{ src 10.0.0.0/8 }\n\n{ hdr(x-maint)   -m found }",
+			},
+			expected: hatypes.BackendPersist{
+				Enabled: true,
+				Force:   []string{"{ src 10.0.0.0/8 }", "{ hdr(x-maint) -m found }"},
+			},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackPersistIgnore: "{ path_beg /static }",
+			},
+			expected: hatypes.BackendPersist{
+				Ignore: []string{"{ path_beg /static }"},
+			},
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackPersistForce:  "if { src 10.0.0.0/8 }",
+				ingtypes.BackPersistIgnore: "unless { src 10.0.0.0/8 }\n{ path_beg /static }",
+			},
+			expected: hatypes.BackendPersist{
+				Ignore: []string{"{ path_beg /static }"},
+			},
+			logging: `
+WARN ignoring persist-force condition on ingress 'default/ing1': expected an acl condition without 'if': if { src 10.0.0.0/8 }
+WARN ignoring persist-ignore condition on ingress 'default/ing1': expected an acl condition without 'unless': unless { src 10.0.0.0/8 }`,
+		},
+	}
+	source := &Source{
+		Namespace: "default",
+		Name:      "ing1",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		c.createUpdater().buildBackendPersist(d)
+		c.compareObjects("persist", i, d.backend.Persist, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestRedispatch(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected hatypes.BackendRedispatch
+		logging  string
+	}{
+		// 0
+		{
+			ann:      map[string]string{},
+			expected: hatypes.BackendRedispatch{},
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackRetries:            "5",
+				ingtypes.BackRedispatchInterval: "2",
+			},
+			expected: hatypes.BackendRedispatch{Interval: 2, Retries: 5},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackRedispatchInterval: "-1",
+			},
+			expected: hatypes.BackendRedispatch{Interval: -1},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackRetries:            "0",
+				ingtypes.BackRedispatchInterval: "0",
+			},
+			expected: hatypes.BackendRedispatch{},
+			logging: `
+WARN ignoring invalid retries on ingress 'default/ing1': 0
+WARN ignoring invalid redispatch interval on ingress 'default/ing1': 0`,
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackRetries:            "2",
+				ingtypes.BackRedispatchInterval: "3",
+			},
+			expected: hatypes.BackendRedispatch{Interval: 3, Retries: 2},
+			logging:  `WARN redispatch interval 3 is greater than 2 retries on ingress 'default/ing1', requests will not be redispatched`,
+		},
+	}
+	source := &Source{
+		Namespace: "default",
+		Name:      "ing1",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		c.createUpdater().buildBackendRedispatch(d)
+		c.compareObjects("redispatch", i, d.backend.Redispatch, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestRewriteURL(t *testing.T) {
 	testCases := []struct {
 		source   Source
@@ -1945,6 +3800,113 @@ func TestRewriteURL(t *testing.T) {
 	}
 }
 
+func TestBackendDNS(t *testing.T) {
+	type dns struct {
+		resolver string
+		initAddr string
+		dnsPort  string
+	}
+	testCases := []struct {
+		ann      map[string]string
+		expected dns
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "none"},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackUseResolver: "dns",
+			},
+			logging: `WARN skipping undeclared DNS resolver: dns`,
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackInitAddr:    "last,libc,none",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "last,libc,none"},
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackInitAddr:    "last,10.0.0.1",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "last,10.0.0.1"},
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackInitAddr:    "last,dns",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "none"},
+			logging:  `WARN ignoring invalid init-addr on ingress 'default/ing1': last,dns`,
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackInitAddr: "last,libc,none",
+			},
+		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.BackDNSSRVPort:  "http-alt",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "none", dnsPort: "http-alt"},
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.BackDNSSRVPort: "http",
+			},
+		},
+		// 9
+		{
+			ann: map[string]string{
+				ingtypes.BackDNSSRVPort:  "8080",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "none"},
+			logging:  `WARN ignoring invalid SRV port name on ingress 'default/ing1': 8080`,
+		},
+		// 10
+		{
+			ann: map[string]string{
+				ingtypes.BackDNSSRVPort:  "_http._tcp",
+				ingtypes.BackUseResolver: "k8s",
+			},
+			expected: dns{resolver: "k8s", initAddr: "none"},
+			logging:  `WARN ignoring invalid SRV port name on ingress 'default/ing1': _http._tcp`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	annDefault := map[string]string{
+		ingtypes.BackInitAddr: "none",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.haproxy.Global().DNS.Resolvers = []*hatypes.DNSResolver{{Name: "k8s"}}
+		d := c.createBackendData("default/app", source, test.ann, annDefault)
+		c.createUpdater().buildBackendDNS(d)
+		actual := dns{resolver: d.backend.Resolver, initAddr: d.backend.InitAddr, dnsPort: d.backend.DNSPort}
+		c.compareObjects("dns", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestBackendServerNaming(t *testing.T) {
 	testCases := []struct {
 		source  Source
@@ -2316,6 +4278,117 @@ WARN skipping CA on service 'default/app1': secret not found: 'default/ca'`,
 	}
 }
 
+func TestBackendFastCGI(t *testing.T) {
+	testCase := []struct {
+		useHTX   bool
+		ann      map[string]string
+		tcp      bool
+		expProto string
+		expected hatypes.BackendFastCGI
+		logging  string
+	}{
+		// 0
+		{
+			useHTX: true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "/var/www/html",
+			},
+			expProto: "fcgi",
+			expected: hatypes.BackendFastCGI{
+				Name:    "default_app_8080",
+				DocRoot: "/var/www/html",
+			},
+		},
+		// 1
+		{
+			useHTX: true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "/var/www/html",
+				ingtypes.BackFastCGIIndex:    "index.php",
+				ingtypes.BackFastCGIPathInfo: `^(/.+\.php)(/.*)?$`,
+			},
+			expProto: "fcgi",
+			expected: hatypes.BackendFastCGI{
+				Name:     "default_app_8080",
+				DocRoot:  "/var/www/html",
+				Index:    "index.php",
+				PathInfo: `^(/.+\.php)(/.*)?$`,
+			},
+		},
+		// 2
+		{
+			useHTX: true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "/var/www/html",
+				ingtypes.BackFastCGIIndex:    "php/index.php",
+				ingtypes.BackFastCGIPathInfo: "invalid path",
+			},
+			expProto: "fcgi",
+			expected: hatypes.BackendFastCGI{
+				Name:    "default_app_8080",
+				DocRoot: "/var/www/html",
+			},
+			logging: `
+WARN ignoring invalid fcgi-index on ingress 'default/app': php/index.php
+WARN ignoring invalid fcgi-path-info on ingress 'default/app': invalid path`,
+		},
+		// 3
+		{
+			useHTX: true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+			},
+			expProto: "h1",
+			logging:  `WARN ignoring fcgi protocol on ingress 'default/app': missing fcgi-docroot`,
+		},
+		// 4
+		{
+			useHTX: true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "var/www/html",
+			},
+			expProto: "h1",
+			logging:  `WARN ignoring fcgi protocol on ingress 'default/app': invalid fcgi-docroot, expected an absolute path: var/www/html`,
+		},
+		// 5
+		{
+			useHTX: true,
+			tcp:    true,
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "/var/www/html",
+			},
+			expProto: "h1",
+			logging:  `WARN ignoring fcgi protocol on ingress 'default/app': backend is in tcp mode`,
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackBackendProtocol: "fcgi",
+				ingtypes.BackFastCGIDocRoot:  "/var/www/html",
+			},
+			expProto: "h1",
+			logging:  `WARN ignoring fcgi protocol on ingress 'default/app' due to HTX disabled, changing to h1`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "app", Type: "ingress"}
+	for i, test := range testCase {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		d.backend.ModeTCP = test.tcp
+		c.haproxy.Global().UseHTX = test.useHTX
+		c.createUpdater().buildBackendProtocol(d)
+		c.compareObjects("protocol", i, d.backend.Server.Protocol, test.expProto)
+		c.compareObjects("fastcgi", i, d.backend.FastCGI, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 type addr struct {
 	ip string
 }
@@ -2428,63 +4501,242 @@ func TestSSLRedirect(t *testing.T) {
 			},
 			ann: map[string]map[string]string{
 				"/": {
-					ingtypes.BackSSLRedirect: "true",
+					ingtypes.BackSSLRedirect: "true",
+				},
+				"/url": {
+					ingtypes.BackSSLRedirect: "false",
+				},
+				"/path": {
+					ingtypes.BackSSLRedirect: "no-bool",
+				},
+			},
+			expected: map[bool][]string{
+				false: {"/other", "/path", "/url"},
+				true:  {"/"},
+			},
+			source:  Source{Namespace: "system1", Name: "app", Type: "service"},
+			logging: `WARN ignoring invalid bool expression on service 'system1/app' key 'ssl-redirect': no-bool`,
+		},
+		// 4
+		{
+			annDefault: map[string]string{
+				ingtypes.GlobalNoTLSRedirectLocations: "/.hidden,/app",
+			},
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackSSLRedirect: "false",
+				},
+				"/.hidden/api": {
+					ingtypes.BackSSLRedirect: "true",
+				},
+				"/api": {
+					ingtypes.BackSSLRedirect: "true",
+				},
+				"/app": {
+					ingtypes.BackSSLRedirect: "true",
+				},
+			},
+			expected: map[bool][]string{
+				false: {"/", "/.hidden/api", "/app"},
+				true:  {"/api"},
+			},
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendMappingData("default/app", &test.source, test.annDefault, test.ann, test.addPaths)
+		c.createUpdater().buildBackendSSLRedirect(d)
+		actual := map[bool][]string{}
+		for _, path := range d.backend.Paths {
+			actual[path.SSLRedirect] = append(actual[path.SSLRedirect], path.Path())
+		}
+		if len(actual[false]) == 0 {
+			delete(actual, false)
+		}
+		if len(actual[true]) == 0 {
+			delete(actual, true)
+		}
+		c.compareObjects("sslredirect", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestStaticResponse(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	for _, file := range []string{"index.html", "ok.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(tempdir, file), []byte{}, 0644); err != nil {
+			t.Fatalf("error creating static file: %v", err)
+		}
+	}
+	testCases := []struct {
+		ann        map[string]map[string]string
+		paths      []string
+		isExternal bool
+		expected   map[string]hatypes.StaticResponse
+		logging    string
+	}{
+		// 0
+		{
+			ann: map[string]map[string]string{
+				"/": {},
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/": {},
+			},
+		},
+		// 1
+		{
+			ann: map[string]map[string]string{
+				"/robots.txt": {
+					ingtypes.BackStaticResponse: "User-agent: * Disallow: /",
+				},
+			},
+			paths: []string{"/"},
+			expected: map[string]hatypes.StaticResponse{
+				"/":           {},
+				"/robots.txt": {Status: 200, ContentType: "text/plain", Body: "User-agent: * Disallow: /"},
+			},
+		},
+		// 2
+		{
+			ann: map[string]map[string]string{
+				"/health": {
+					ingtypes.BackStaticResponse:      `{"status": "up", "cost": "$0"}`,
+					ingtypes.BackStaticResponseCType: "application/json",
+				},
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/health": {Status: 200, ContentType: "application/json", Body: `{\"status\": \"up\", \"cost\": \"\$0\"}`},
+			},
+		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackStaticResponseFile:   "<dir>/index.html",
+					ingtypes.BackStaticResponseCType:  "text/html",
+					ingtypes.BackStaticResponseStatus: "503",
+				},
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/": {Status: 503, ContentType: "text/html", File: "<dir>/index.html"},
+			},
+		},
+		// 4
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackStaticResponse:     "ok",
+					ingtypes.BackStaticResponseFile: "<dir>/ok.txt",
+				},
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/": {Status: 200, ContentType: "text/plain", File: "<dir>/ok.txt"},
+			},
+			logging: `WARN ignoring static response body on ingress 'default/ing1' due to conflict with static response file`,
+		},
+		// 5
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackStaticResponse:       "ok",
+					ingtypes.BackStaticResponseStatus: "301",
+				},
+				"/app": {
+					ingtypes.BackStaticResponse:       "ok",
+					ingtypes.BackStaticResponseStatus: "ok",
+				},
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/":    {Status: 301, ContentType: "text/plain", Body: "ok"},
+				"/app": {},
+			},
+			logging: `WARN ignoring static response on ingress 'default/ing1': invalid status code: ok`,
+		},
+		// 6
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackStaticResponse:      "ok",
+					ingtypes.BackStaticResponseCType: `text/plain"`,
 				},
-				"/url": {
-					ingtypes.BackSSLRedirect: "false",
+				"/app": {
+					ingtypes.BackStaticResponseFile: "/etc/haproxy/static/my file.txt",
 				},
-				"/path": {
-					ingtypes.BackSSLRedirect: "no-bool",
+				"/api": {
+					ingtypes.BackStaticResponse: "line1\nline2",
 				},
 			},
-			expected: map[bool][]string{
-				false: {"/other", "/path", "/url"},
-				true:  {"/"},
+			expected: map[string]hatypes.StaticResponse{
+				"/":    {},
+				"/app": {},
+				"/api": {},
 			},
-			source:  Source{Namespace: "system1", Name: "app", Type: "service"},
-			logging: `WARN ignoring invalid bool expression on service 'system1/app' key 'ssl-redirect': no-bool`,
+			logging: `
+WARN ignoring static response on ingress 'default/ing1': invalid content type: text/plain"
+WARN ignoring static response on ingress 'default/ing1': body should be a single line, use static-response-file instead
+WARN ignoring static response on ingress 'default/ing1': invalid file name: /etc/haproxy/static/my file.txt`,
 		},
-		// 4
+		// 7
 		{
-			annDefault: map[string]string{
-				ingtypes.GlobalNoTLSRedirectLocations: "/.hidden,/app",
-			},
 			ann: map[string]map[string]string{
 				"/": {
-					ingtypes.BackSSLRedirect: "false",
-				},
-				"/.hidden/api": {
-					ingtypes.BackSSLRedirect: "true",
-				},
-				"/api": {
-					ingtypes.BackSSLRedirect: "true",
+					ingtypes.BackStaticResponseFile: "<dir>/missing.html",
 				},
-				"/app": {
-					ingtypes.BackSSLRedirect: "true",
+			},
+			expected: map[string]hatypes.StaticResponse{
+				"/": {},
+			},
+			logging: `WARN ignoring static response on ingress 'default/ing1': file cannot be read: stat <dir>/missing.html: no such file or directory`,
+		},
+		// 8
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					ingtypes.BackStaticResponseFile: "<dir>/missing.html",
 				},
 			},
-			expected: map[bool][]string{
-				false: {"/", "/.hidden/api", "/app"},
-				true:  {"/api"},
+			isExternal: true,
+			expected: map[string]hatypes.StaticResponse{
+				"/": {Status: 200, ContentType: "text/plain", File: "<dir>/missing.html"},
 			},
 		},
 	}
+	replace := func(s string) string {
+		return strings.Replace(s, "<dir>", tempdir, -1)
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	annDefault := map[string]string{
+		ingtypes.BackStaticResponseCType:  "text/plain",
+		ingtypes.BackStaticResponseStatus: "200",
+	}
 	for i, test := range testCases {
 		c := setup(t)
-		d := c.createBackendMappingData("default/app", &test.source, test.annDefault, test.ann, test.addPaths)
-		c.createUpdater().buildBackendSSLRedirect(d)
-		actual := map[bool][]string{}
-		for _, path := range d.backend.Paths {
-			actual[path.SSLRedirect] = append(actual[path.SSLRedirect], path.Path())
+		if test.isExternal {
+			c.haproxy.Global().External.MasterSocket = "/socket"
 		}
-		if len(actual[false]) == 0 {
-			delete(actual, false)
+		for _, ann := range test.ann {
+			if file, found := ann[ingtypes.BackStaticResponseFile]; found {
+				ann[ingtypes.BackStaticResponseFile] = replace(file)
+			}
 		}
-		if len(actual[true]) == 0 {
-			delete(actual, true)
+		d := c.createBackendMappingData("default/app", source, annDefault, test.ann, test.paths)
+		c.createUpdater().buildBackendStaticResponse(d)
+		actual := map[string]hatypes.StaticResponse{}
+		for _, path := range d.backend.Paths {
+			actual[path.Path()] = path.StaticResponse
 		}
-		c.compareObjects("sslredirect", i, actual, test.expected)
-		c.logger.CompareLogging(test.logging)
+		for path, response := range test.expected {
+			response.File = replace(response.File)
+			test.expected[path] = response
+		}
+		c.compareObjects("static response", i, actual, test.expected)
+		c.logger.CompareLogging(replace(test.logging))
 		c.teardown()
 	}
 }
@@ -2528,6 +4780,94 @@ func TestTimeout(t *testing.T) {
 			// use only if declared as svc/ing annotation, otherwise defaults to HAProxy's defaults section
 			expected: hatypes.BackendTimeoutConfig{},
 		},
+		// 3
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-tunnel": "8h",
+				},
+			},
+			expected: hatypes.BackendTimeoutConfig{
+				Tunnel: "8h",
+			},
+		},
+		// 4
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-tunnel": "1day",
+				},
+			},
+			source:   Source{Namespace: "default", Name: "ing1", Type: "ingress"},
+			expected: hatypes.BackendTimeoutConfig{},
+			logging:  `WARN ignoring invalid time format on ingress 'default/ing1': 1day`,
+		},
+		// 5
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-check": "10s",
+				},
+			},
+			expected: hatypes.BackendTimeoutConfig{
+				Check: "10s",
+			},
+		},
+		// 6
+		{
+			annDefault: map[string]string{
+				"timeout-check": "10s",
+			},
+			expected: hatypes.BackendTimeoutConfig{},
+		},
+		// 7
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-keep-alive": "30s",
+				},
+			},
+			expected: hatypes.BackendTimeoutConfig{
+				KeepAlive: "30s",
+			},
+		},
+		// 8
+		{
+			annDefault: map[string]string{
+				"timeout-keep-alive": "30s",
+			},
+			expected: hatypes.BackendTimeoutConfig{},
+		},
+		// 9
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-keep-alive": "1min",
+				},
+			},
+			source:   Source{Namespace: "default", Name: "ing1", Type: "ingress"},
+			expected: hatypes.BackendTimeoutConfig{},
+			logging:  `WARN ignoring invalid time format on ingress 'default/ing1': 1min`,
+		},
+		// 10
+		{
+			ann: map[string]map[string]string{
+				"/": {
+					"timeout-queue": "2m",
+				},
+			},
+			expected: hatypes.BackendTimeoutConfig{
+				Queue: "2m",
+			},
+		},
+		// 11
+		{
+			annDefault: map[string]string{
+				"timeout-queue": "30s",
+			},
+			// global timeout queue is rendered in the defaults section
+			expected: hatypes.BackendTimeoutConfig{},
+		},
 	}
 	for i, test := range testCase {
 		c := setup(t)
@@ -2539,6 +4879,42 @@ func TestTimeout(t *testing.T) {
 	}
 }
 
+func TestUpstreamHost(t *testing.T) {
+	testCases := []struct {
+		vhost    string
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			vhost:    "app.internal",
+			expected: "app.internal",
+		},
+		// 2
+		{
+			vhost:    "app.default.svc:8080",
+			expected: "app.default.svc:8080",
+		},
+		// 3
+		{
+			vhost:   "app.internal/path",
+			logging: `WARN ignoring invalid upstream vhost on ingress 'default/ing1': app.internal/path`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		ann := map[string]string{ingtypes.BackUpstreamVhost: test.vhost}
+		d := c.createBackendData("default/app", source, ann, map[string]string{})
+		c.createUpdater().buildBackendUpstreamHost(d)
+		c.compareObjects("upstream host", i, d.backend.UpstreamHost, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestWAF(t *testing.T) {
 	testCase := []struct {
 		waf      string
@@ -2622,6 +4998,82 @@ func TestWAF(t *testing.T) {
 	}
 }
 
+func TestWaitForBody(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		modeTCP  bool
+		expected hatypes.BackendWaitForBody
+		logging  string
+	}{
+		// 0
+		{
+			ann:      map[string]string{},
+			expected: hatypes.BackendWaitForBody{},
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyAtLeast: "1k",
+			},
+			expected: hatypes.BackendWaitForBody{},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyTime: "10s",
+			},
+			expected: hatypes.BackendWaitForBody{Time: "10s"},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyTime:    "1s",
+				ingtypes.BackWaitForBodyAtLeast: "16k",
+			},
+			expected: hatypes.BackendWaitForBody{Time: "1s", AtLeast: 16384},
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyTime: "1x",
+			},
+			expected: hatypes.BackendWaitForBody{},
+			logging:  `WARN ignoring invalid time format on ingress 'default/ing1': 1x`,
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyTime:    "1s",
+				ingtypes.BackWaitForBodyAtLeast: "-1",
+			},
+			expected: hatypes.BackendWaitForBody{Time: "1s"},
+			logging:  `WARN ignoring invalid wait for body at least on ingress 'default/ing1': -1`,
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.BackWaitForBodyTime: "1s",
+			},
+			modeTCP:  true,
+			expected: hatypes.BackendWaitForBody{},
+		},
+	}
+	source := &Source{
+		Namespace: "default",
+		Name:      "ing1",
+		Type:      "ingress",
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createBackendData("default/app", source, test.ann, map[string]string{})
+		d.backend.ModeTCP = test.modeTCP
+		c.createUpdater().buildBackendWaitForBody(d)
+		c.compareObjects("wait for body", i, d.backend.WaitForBody, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestWhitelistHTTP(t *testing.T) {
 	testCases := []struct {
 		paths       []string
@@ -2631,6 +5083,8 @@ func TestWhitelistHTTP(t *testing.T) {
 		expAllowHdr map[string]string
 		expDenyRule map[string][]string
 		expDenyExc  map[string][]string
+		expStatus   map[string]int
+		expDenySt   map[string]int
 		logging     string
 	}{
 		// 0
@@ -2779,6 +5233,71 @@ WARN both allowlist and whitelist were used on ingress 'default/ing1', ignoring
 				"/": "X-Forwarded-For",
 			},
 		},
+		// 11
+		{
+			paths: []string{"/", "/api"},
+			cidrlist: map[string]map[string]string{
+				"/": {
+					ingtypes.BackAllowlistSourceRange: "10.0.0.0/8",
+					ingtypes.BackAllowlistDenyStatus:  "403",
+				},
+				"/api": {
+					ingtypes.BackDenylistSourceRange: "192.168.0.0/24",
+					ingtypes.BackDenylistDenyStatus:  "401",
+				},
+			},
+			expected: map[string][]string{
+				"/": {"10.0.0.0/8"},
+			},
+			expDenyRule: map[string][]string{
+				"/api": {"192.168.0.0/24"},
+			},
+			expStatus: map[string]int{
+				"/": 403,
+			},
+			expDenySt: map[string]int{
+				"/api": 401,
+			},
+		},
+		// 12
+		{
+			paths: []string{"/"},
+			cidrlist: map[string]map[string]string{
+				"/": {
+					ingtypes.BackAllowlistSourceRange: "10.0.0.0/8",
+					ingtypes.BackAllowlistDenyStatus:  "forbidden",
+				},
+			},
+			expected: map[string][]string{
+				"/": {"10.0.0.0/8"},
+			},
+			logging: `
+WARN ignoring invalid deny status on ingress 'default/ing1', expected an error code between 400 and 599: forbidden`,
+		},
+		// 13
+		{
+			paths: []string{"/"},
+			cidrlist: map[string]map[string]string{
+				"/": {
+					ingtypes.BackAllowlistSourceRange: "10.0.0.0/8",
+					ingtypes.BackAllowlistDenyStatus:  "401",
+					ingtypes.BackDenylistSourceRange:  "10.0.0.0/24",
+					ingtypes.BackDenylistDenyStatus:   "404",
+				},
+			},
+			expected: map[string][]string{
+				"/": {"10.0.0.0/8"},
+			},
+			expDenyRule: map[string][]string{
+				"/": {"10.0.0.0/24"},
+			},
+			expStatus: map[string]int{
+				"/": 401,
+			},
+			expDenySt: map[string]int{
+				"/": 404,
+			},
+		},
 	}
 	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
 	for i, test := range testCases {
@@ -2790,6 +5309,8 @@ WARN both allowlist and whitelist were used on ingress 'default/ing1', ignoring
 		actualAllowHdr := map[string]string{}
 		actualDenyRule := map[string][]string{}
 		actualDenyExc := map[string][]string{}
+		actualStatus := map[string]int{}
+		actualDenySt := map[string]int{}
 		for _, path := range d.backend.Paths {
 			if len(path.AllowedIPHTTP.Rule) > 0 {
 				actual[path.Path()] = path.AllowedIPHTTP.Rule
@@ -2806,6 +5327,12 @@ WARN both allowlist and whitelist were used on ingress 'default/ing1', ignoring
 			if len(path.DeniedIPHTTP.Exception) > 0 {
 				actualDenyExc[path.Path()] = path.DeniedIPHTTP.Exception
 			}
+			if path.AllowedIPHTTP.DenyStatus > 0 {
+				actualStatus[path.Path()] = path.AllowedIPHTTP.DenyStatus
+			}
+			if path.DeniedIPHTTP.DenyStatus > 0 {
+				actualDenySt[path.Path()] = path.DeniedIPHTTP.DenyStatus
+			}
 		}
 		if test.expected == nil {
 			test.expected = map[string][]string{}
@@ -2822,11 +5349,19 @@ WARN both allowlist and whitelist were used on ingress 'default/ing1', ignoring
 		if test.expAllowHdr == nil {
 			test.expAllowHdr = map[string]string{}
 		}
+		if test.expStatus == nil {
+			test.expStatus = map[string]int{}
+		}
+		if test.expDenySt == nil {
+			test.expDenySt = map[string]int{}
+		}
 		c.compareObjects("whitelist http", i, actual, test.expected)
 		c.compareObjects("whitelist http", i, actualAllowExc, test.expAllowExc)
 		c.compareObjects("whitelist http", i, actualDenyRule, test.expDenyRule)
 		c.compareObjects("whitelist http", i, actualDenyExc, test.expDenyExc)
 		c.compareObjects("whitelist http", i, actualAllowHdr, test.expAllowHdr)
+		c.compareObjects("whitelist http", i, actualStatus, test.expStatus)
+		c.compareObjects("whitelist http", i, actualDenySt, test.expDenySt)
 		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
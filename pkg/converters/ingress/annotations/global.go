@@ -18,6 +18,7 @@ package annotations
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -55,6 +56,36 @@ func (c *updater) buildGlobalAcme(d *globalData) {
 
 var authProxyRegex = regexp.MustCompile(`^([A-Za-z_-]+):([0-9]{1,5})-([0-9]{1,5})$`)
 
+var (
+	adminSocketModeRegex  = regexp.MustCompile(`^0?[0-7]{3}$`)
+	adminSocketOwnerRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+func (c *updater) buildGlobalAdminSocket(d *globalData) {
+	perms := &d.global.AdminSocketPerms
+	if mode := d.mapper.Get(ingtypes.GlobalAdminSocketMode).Value; mode != "" {
+		if adminSocketModeRegex.MatchString(mode) {
+			perms.Mode = mode
+		} else {
+			c.logger.Warn("ignoring invalid admin socket mode config: %s", mode)
+		}
+	}
+	if user := d.mapper.Get(ingtypes.GlobalAdminSocketUser).Value; user != "" {
+		if adminSocketOwnerRegex.MatchString(user) {
+			perms.User = user
+		} else {
+			c.logger.Warn("ignoring invalid admin socket user config: %s", user)
+		}
+	}
+	if group := d.mapper.Get(ingtypes.GlobalAdminSocketGroup).Value; group != "" {
+		if adminSocketOwnerRegex.MatchString(group) {
+			perms.Group = group
+		} else {
+			c.logger.Warn("ignoring invalid admin socket group config: %s", group)
+		}
+	}
+}
+
 func (c *updater) buildGlobalAuthProxy(d *globalData) {
 	proxystr := d.mapper.Get(ingtypes.GlobalAuthProxy).Value
 	proxy := authProxyRegex.FindStringSubmatch(proxystr)
@@ -90,6 +121,36 @@ func (c *updater) buildGlobalBind(d *globalData) {
 	}
 }
 
+func (c *updater) buildGlobalBlockedPaths(d *globalData) {
+	var blockedPaths []string
+	for _, path := range utils.LineToSlice(d.mapper.Get(ingtypes.GlobalBlockedPaths).Value) {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t") {
+			c.logger.Warn("ignoring invalid blocked path, expected a path starting with a slash: %s", path)
+			continue
+		}
+		blockedPaths = append(blockedPaths, path)
+	}
+	c.haproxy.Frontend().BlockedPaths = blockedPaths
+}
+
+func (c *updater) buildGlobalBucket(d *globalData) {
+	cookie := d.mapper.Get(ingtypes.GlobalBucketCookie).Value
+	if cookie == "" {
+		return
+	}
+	count := d.mapper.Get(ingtypes.GlobalBucketCount)
+	if count.Int() <= 0 {
+		c.logger.Warn("ignoring bucket config, invalid bucket count: %s", count.Value)
+		return
+	}
+	d.global.Bucket.Cookie = cookie
+	d.global.Bucket.Count = count.Int()
+}
+
 func (c *updater) buildGlobalCloseSessions(d *globalData) {
 	durationCfg := d.mapper.Get(ingtypes.GlobalCloseSessionsDuration).Value
 	if durationCfg == "" {
@@ -133,6 +194,85 @@ func (c *updater) buildGlobalCloseSessions(d *globalData) {
 	d.global.Timeout.Stats = timeoutCfg
 }
 
+// status codes haproxy can generate and which can be overwritten by errorfile
+var errorFileCodes = map[int]struct{}{
+	200: {}, 400: {}, 401: {}, 403: {}, 404: {}, 405: {}, 407: {}, 408: {}, 410: {}, 413: {}, 425: {}, 429: {},
+	500: {}, 501: {}, 502: {}, 503: {}, 504: {},
+}
+
+func (c *updater) buildGlobalErrorFiles(d *globalData) {
+	for _, line := range utils.LineToSlice(d.mapper.Get(ingtypes.GlobalErrorFiles).Value) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			c.logger.Warn("ignoring error file config, expected '<code> <filename>' but got: %s", line)
+			continue
+		}
+		code, err := strconv.Atoi(fields[0])
+		if _, found := errorFileCodes[code]; err != nil || !found {
+			c.logger.Warn("ignoring error file config, unsupported status code: %s", fields[0])
+			continue
+		}
+		if _, err := os.Stat(fields[1]); err != nil {
+			c.logger.Warn("ignoring error file config, file cannot be read: %v", err)
+			continue
+		}
+		d.global.ErrorFiles = append(d.global.ErrorFiles, &hatypes.ErrorFile{
+			Code:     code,
+			Filename: fields[1],
+		})
+	}
+}
+
+func (c *updater) buildGlobalFrontendMaxConn(d *globalData) {
+	// zero means the process limit
+	var value int
+	if maxConn := d.mapper.Get(ingtypes.GlobalFrontendMaxConn).Value; maxConn != "" {
+		var err error
+		value, err = strconv.Atoi(maxConn)
+		if err != nil || value < 0 {
+			c.logger.Warn("ignoring invalid frontend-maxconn config: %s", maxConn)
+			value = 0
+		}
+	}
+	c.haproxy.Frontend().MaxConn = value
+}
+
+func (c *updater) buildGlobalGeoIP(d *globalData) {
+	actionMap := d.mapper.Get(ingtypes.GlobalGeoIPActionMap).Value
+	if actionMap == "" {
+		return
+	}
+	countryMap := d.mapper.Get(ingtypes.GlobalGeoIPCountryMap).Value
+	if countryMap == "" {
+		c.logger.Warn("ignoring geoip config, missing '%s' configuration", ingtypes.GlobalGeoIPCountryMap)
+		return
+	}
+	for _, file := range []string{countryMap, actionMap} {
+		if _, err := os.Stat(file); err != nil {
+			c.logger.Warn("ignoring geoip config, map file cannot be read: %v", err)
+			return
+		}
+	}
+	d.global.GeoIP.ActionMapFile = actionMap
+	d.global.GeoIP.CountryMapFile = countryMap
+}
+
+var normalizeURIRegex = regexp.MustCompile(`^(fragment-encode|fragment-strip|path-merge-slashes|path-strip-dot|path-strip-dotdot( full)?|percent-decode-unreserved( strict)?|percent-to-uppercase( strict)?|query-sort-by-name)$`)
+
+func (c *updater) buildGlobalNormalizeURI(d *globalData) {
+	for _, mode := range utils.Split(d.mapper.Get(ingtypes.GlobalNormalizeURI).Value, ",") {
+		mode = strings.Join(strings.Fields(mode), " ")
+		if !normalizeURIRegex.MatchString(mode) {
+			c.logger.Warn("ignoring invalid normalize-uri mode: %s", mode)
+			continue
+		}
+		d.global.NormalizeURI = append(d.global.NormalizeURI, mode)
+	}
+}
+
 func (c *updater) buildGlobalPathTypeOrder(d *globalData) {
 	matchTypes := make(map[hatypes.MatchType]struct{}, len(hatypes.DefaultMatchOrder))
 	for _, match := range hatypes.DefaultMatchOrder {
@@ -160,6 +300,41 @@ func (c *updater) buildGlobalPathTypeOrder(d *globalData) {
 	d.global.MatchOrder = order
 }
 
+var (
+	peerNameRegex     = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	peerEndpointRegex = regexp.MustCompile(`^[^:\s]+:[0-9]+$`)
+)
+
+func (c *updater) buildGlobalPeers(d *globalData) {
+	peersCfg := d.mapper.Get(ingtypes.GlobalPeers).Value
+	if peersCfg == "" {
+		return
+	}
+	var peers []*hatypes.Peer
+	names := map[string]bool{}
+	// peers are separated by commas, spaces or line breaks
+	for _, peer := range strings.Fields(strings.ReplaceAll(peersCfg, ",", " ")) {
+		peerData := strings.Split(peer, "=")
+		if len(peerData) != 2 || !peerNameRegex.MatchString(peerData[0]) || !peerEndpointRegex.MatchString(peerData[1]) {
+			c.logger.Warn("ignoring misconfigured peer: %s", peer)
+			continue
+		}
+		if names[peerData[0]] {
+			c.logger.Warn("ignoring duplicated peer name: %s", peer)
+			continue
+		}
+		names[peerData[0]] = true
+		peers = append(peers, &hatypes.Peer{
+			Name:     peerData[0],
+			Endpoint: peerData[1],
+		})
+	}
+	if len(peers) > 0 {
+		d.global.Peers.SectionName = "_peers"
+		d.global.Peers.Peers = peers
+	}
+}
+
 func (c *updater) buildGlobalProc(d *globalData) {
 	balance := d.mapper.Get(ingtypes.GlobalNbprocBalance).Int()
 	if balance < 1 {
@@ -203,6 +378,12 @@ func (c *updater) buildGlobalProc(d *globalData) {
 	cpumap := ""
 	if useCPUMap {
 		cpumap = d.mapper.Get(ingtypes.GlobalCPUMap).Value
+		if cpumap != "" && threads > 0 {
+			if maxThread := cpuMapMaxThread(cpumap); maxThread > threads {
+				c.logger.Warn("ignoring cpu-map configmap option, thread %d is out of range, nbthread is %d: %s", maxThread, threads, cpumap)
+				cpumap = ""
+			}
+		}
 		if cpumap == "" {
 			if threads > 1 {
 				if procs == 1 {
@@ -222,6 +403,27 @@ func (c *updater) buildGlobalProc(d *globalData) {
 	d.global.Procs.CPUMap = cpumap
 }
 
+// cpuMapMaxThread returns the highest thread ID referenced in the thread set
+// of a cpu-map declared as `[auto:]<process-set>[/<thread-set>] <cpu-set>`,
+// or zero if the thread set is missing or only uses keywords, e.g. `all`.
+func cpuMapMaxThread(cpumap string) int {
+	procThread := strings.Fields(cpumap)
+	if len(procThread) == 0 {
+		return 0
+	}
+	slash := strings.Index(procThread[0], "/")
+	if slash < 0 {
+		return 0
+	}
+	maxThread := 0
+	for _, id := range strings.Split(procThread[0][slash+1:], "-") {
+		if thread, err := strconv.Atoi(id); err == nil && thread > maxThread {
+			maxThread = thread
+		}
+	}
+	return maxThread
+}
+
 func (c *updater) buildGlobalStats(d *globalData) {
 	// healthz
 	d.global.Healthz.BindIP = d.mapper.Get(ingtypes.GlobalBindIPAddrHealthz).Value
@@ -245,10 +447,12 @@ func (c *updater) buildGlobalStats(d *globalData) {
 }
 
 func (c *updater) buildGlobalSyslog(d *globalData) {
+	d.global.Syslog.DontLogNull = d.mapper.Get(ingtypes.GlobalDontLogNull).Bool()
 	d.global.Syslog.Endpoint = d.mapper.Get(ingtypes.GlobalSyslogEndpoint).Value
 	d.global.Syslog.Format = d.mapper.Get(ingtypes.GlobalSyslogFormat).Value
 	d.global.Syslog.Length = d.mapper.Get(ingtypes.GlobalSyslogLength).Int()
 	d.global.Syslog.Tag = d.mapper.Get(ingtypes.GlobalSyslogTag).Value
+	c.buildGlobalSyslogRing(d)
 	//
 	d.global.Syslog.AuthLogFormat = d.mapper.Get(ingtypes.GlobalAuthLogFormat).Value
 	d.global.Syslog.HTTPLogFormat = d.mapper.Get(ingtypes.GlobalHTTPLogFormat).Value
@@ -256,14 +460,45 @@ func (c *updater) buildGlobalSyslog(d *globalData) {
 	d.global.Syslog.TCPLogFormat = d.mapper.Get(ingtypes.GlobalTCPLogFormat).Value
 }
 
+func (c *updater) buildGlobalSyslogRing(d *globalData) {
+	if !d.mapper.Get(ingtypes.GlobalSyslogRing).Bool() {
+		return
+	}
+	if d.global.Syslog.Endpoint == "" {
+		c.logger.Warn("ignoring syslog ring config, missing '%s' configuration", ingtypes.GlobalSyslogEndpoint)
+		return
+	}
+	var size int64
+	if sizeCfg := d.mapper.Get(ingtypes.GlobalSyslogRingSize).Value; sizeCfg != "" {
+		var err error
+		size, err = utils.SizeSuffixToInt64(sizeCfg)
+		if err != nil || size <= 0 {
+			c.logger.Warn("ignoring invalid syslog ring size: %s", sizeCfg)
+			size = 0
+		}
+	}
+	d.global.Syslog.Ring = hatypes.SyslogRing{
+		Name: "syslog",
+		Size: size,
+	}
+}
+
+// client, connect and server timeouts are mandatory in the defaults section,
+// these values are used if the configured ones cannot be used
+const (
+	fallbackTimeoutClient  = "50s"
+	fallbackTimeoutConnect = "5s"
+	fallbackTimeoutServer  = "50s"
+)
+
 func (c *updater) buildGlobalTimeout(d *globalData) {
-	d.global.Timeout.Client = c.validateTime(d.mapper.Get(ingtypes.GlobalTimeoutClient))
+	d.global.Timeout.Client = c.requiredGlobalTime(d, ingtypes.GlobalTimeoutClient, fallbackTimeoutClient)
 	d.global.Timeout.ClientFin = c.validateTime(d.mapper.Get(ingtypes.GlobalTimeoutClientFin))
-	d.global.Timeout.Connect = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutConnect))
+	d.global.Timeout.Connect = c.requiredGlobalTime(d, ingtypes.BackTimeoutConnect, fallbackTimeoutConnect)
 	d.global.Timeout.HTTPRequest = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutHTTPRequest))
 	d.global.Timeout.KeepAlive = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutKeepAlive))
 	d.global.Timeout.Queue = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutQueue))
-	d.global.Timeout.Server = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutServer))
+	d.global.Timeout.Server = c.requiredGlobalTime(d, ingtypes.BackTimeoutServer, fallbackTimeoutServer)
 	d.global.Timeout.ServerFin = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutServerFin))
 	d.global.Timeout.Stop = c.validateTime(d.mapper.Get(ingtypes.GlobalTimeoutStop))
 	d.global.Timeout.Tunnel = c.validateTime(d.mapper.Get(ingtypes.BackTimeoutTunnel))
@@ -272,6 +507,43 @@ func (c *updater) buildGlobalTimeout(d *globalData) {
 	}
 }
 
+func (c *updater) buildGlobalTune(d *globalData) {
+	d.global.Tune.H2MaxConcurrentStreams = d.mapper.Get(ingtypes.GlobalHTTP2MaxConcurrentStreams).Int()
+	maxAccept := d.mapper.Get(ingtypes.GlobalMaxAccept)
+	if maxAccept.Value == "" {
+		return
+	}
+	// -1 means unlimited
+	if value, err := strconv.Atoi(maxAccept.Value); err == nil && value >= -1 && value != 0 {
+		d.global.Tune.MaxAccept = value
+	} else {
+		c.logger.Warn("ignoring invalid max accept config: %s", maxAccept.Value)
+	}
+}
+
+func (c *updater) requiredGlobalTime(d *globalData, key, fallback string) string {
+	value := d.mapper.Get(key).Value
+	timeout, err := parseTime(value)
+	if err != nil {
+		c.logger.Warn("ignoring invalid '%s' configmap option '%s', using '%s' instead: %v", key, value, fallback, err)
+		return fallback
+	}
+	return timeout
+}
+
+func (c *updater) buildGlobalUniqueID(d *globalData) {
+	format := d.mapper.Get(ingtypes.GlobalUniqueIDFormat).Value
+	header := d.mapper.Get(ingtypes.GlobalUniqueIDHeader).Value
+	if format == "" {
+		if header != "" {
+			c.logger.Warn("ignoring '%s' config, '%s' need to be configured", ingtypes.GlobalUniqueIDHeader, ingtypes.GlobalUniqueIDFormat)
+		}
+		return
+	}
+	d.global.UniqueIDFormat = format
+	d.global.UniqueIDHeader = header
+}
+
 func (c *updater) buildSecurity(d *globalData) {
 	username := d.mapper.Get(ingtypes.GlobalUsername).Value
 	groupname := d.mapper.Get(ingtypes.GlobalGroupname).Value
@@ -294,6 +566,8 @@ func (c *updater) buildSecurity(d *globalData) {
 	d.global.Security.UseChroot = d.mapper.Get(ingtypes.GlobalUseChroot).Bool()
 }
 
+var sslVersionRegex = regexp.MustCompile(`^(SSLv3|TLSv1\.[0-3])$`)
+
 func (c *updater) buildGlobalSSL(d *globalData) {
 	ssl := &d.global.SSL
 	ssl.ALPN = d.mapper.Get(ingtypes.HostTLSALPN).Value
@@ -305,15 +579,39 @@ func (c *updater) buildGlobalSSL(d *globalData) {
 		if dhFile, err := c.cache.GetDHSecretPath("", sslDHParam); err == nil {
 			ssl.DHParam.Filename = dhFile.Filename
 		} else {
-			c.logger.Error("error reading DH params: %v", err)
+			c.logger.Warn("ignoring ssl-dh-param config, DH params cannot be read: %v", err)
 		}
 	}
-	ssl.DHParam.DefaultMaxSize = d.mapper.Get(ingtypes.GlobalSSLDHDefaultMaxSize).Int()
+	dhMaxSize := d.mapper.Get(ingtypes.GlobalSSLDHDefaultMaxSize)
+	if size := dhMaxSize.Int(); size >= 1024 {
+		ssl.DHParam.DefaultMaxSize = size
+	} else {
+		// haproxy refuses to start with a size lower than 1024 bits
+		if dhMaxSize.Value != "" {
+			c.logger.Warn("ignoring invalid ssl-dh-default-max-size config, using '2048' instead: %s", dhMaxSize.Value)
+		}
+		ssl.DHParam.DefaultMaxSize = 2048
+	}
 	ssl.Engine = d.mapper.Get(ingtypes.GlobalSSLEngine).Value
 	ssl.HeadersPrefix = d.mapper.Get(ingtypes.GlobalSSLHeadersPrefix).Value
+	if minVer := d.mapper.Get(ingtypes.GlobalSSLMinVer).Value; minVer != "" {
+		if sslVersionRegex.MatchString(minVer) {
+			ssl.MinVersion = minVer
+		} else {
+			c.logger.Warn("ignoring invalid ssl-min-ver value: %s", minVer)
+		}
+	}
 	ssl.ModeAsync = d.mapper.Get(ingtypes.GlobalSSLModeAsync).Bool()
-	ssl.Options = d.mapper.Get(ingtypes.GlobalSSLOptions).Value
-	ssl.RedirectCode = d.mapper.Get(ingtypes.GlobalSSLRedirectCode).Int()
+	ssl.Options = singleLine(d.mapper.Get(ingtypes.GlobalSSLOptions).Value)
+	ssl.RedirectCode = c.validateRedirectCode(d, ingtypes.GlobalSSLRedirectCode, 0)
+	if cacheSize := d.mapper.Get(ingtypes.GlobalSSLSessionCacheSize).Value; cacheSize != "" {
+		if size, err := strconv.Atoi(cacheSize); err == nil && size >= 0 {
+			ssl.SessionCacheSize = &size
+		} else {
+			c.logger.Warn("ignoring invalid ssl-session-cache-size config: %s", cacheSize)
+		}
+	}
+	ssl.SessionLifetime = c.validateTime(d.mapper.Get(ingtypes.GlobalSSLSessionLifetime))
 }
 
 func (c *updater) buildGlobalHTTPStoHTTP(d *globalData) {
@@ -352,8 +650,14 @@ func (c *updater) buildGlobalDNS(d *globalData) {
 		return
 	}
 	payloadSize := d.mapper.Get(ingtypes.GlobalDNSAcceptedPayloadSize).Int()
+	holdNX := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldNX))
 	holdObsolete := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldObsolete))
+	holdOther := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldOther))
+	holdRefused := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldRefused))
+	holdTimeout := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldTimeout))
 	holdValid := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSHoldValid))
+	resolveRetries := d.mapper.Get(ingtypes.GlobalDNSResolveRetries).Int()
+	timeoutResolve := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSTimeoutResolve))
 	timeoutRetry := c.validateTime(d.mapper.Get(ingtypes.GlobalDNSTimeoutRetry))
 	for _, resolver := range utils.LineToSlice(resolvers) {
 		if resolver == "" {
@@ -367,8 +671,14 @@ func (c *updater) buildGlobalDNS(d *globalData) {
 		dnsResolver := &hatypes.DNSResolver{
 			Name:                resolverData[0],
 			AcceptedPayloadSize: payloadSize,
+			HoldNX:              holdNX,
 			HoldObsolete:        holdObsolete,
+			HoldOther:           holdOther,
+			HoldRefused:         holdRefused,
+			HoldTimeout:         holdTimeout,
 			HoldValid:           holdValid,
+			ResolveRetries:      resolveRetries,
+			TimeoutResolve:      timeoutResolve,
 			TimeoutRetry:        timeoutRetry,
 		}
 		var i int
@@ -419,6 +729,19 @@ func (c *updater) buildGlobalForwardFor(d *globalData) {
 	}
 }
 
+var cookieKeyRegex = regexp.MustCompile(`^[^"\\\x00-\x1f]+$`)
+
+func (c *updater) buildGlobalCookie(d *globalData) {
+	key := d.mapper.Get(ingtypes.GlobalCookieKey).Value
+	// the key is rendered as a quoted dynamic-cookie-key, and is
+	// omitted from the logs since it's a secret
+	if key != "" && !cookieKeyRegex.MatchString(key) {
+		c.logger.Warn("ignoring invalid cookie key config, using 'Ingress' instead")
+		key = "Ingress"
+	}
+	d.global.Cookie.Key = key
+}
+
 func (c *updater) buildGlobalCustomConfig(d *globalData) {
 	d.global.CustomConfig = utils.LineToSlice(d.mapper.Get(ingtypes.GlobalConfigGlobal).Value)
 	d.global.CustomDefaults = utils.LineToSlice(d.mapper.Get(ingtypes.GlobalConfigDefaults).Value)
@@ -17,7 +17,12 @@ limitations under the License.
 package annotations
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +31,58 @@ import (
 	hatypes "github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy/types"
 )
 
+func TestAdminSocket(t *testing.T) {
+	testCases := []struct {
+		config   map[string]string
+		expected hatypes.AdminSocketPermsConfig
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			config: map[string]string{
+				ingtypes.GlobalAdminSocketMode: "660",
+			},
+			expected: hatypes.AdminSocketPermsConfig{Mode: "660"},
+		},
+		// 2
+		{
+			config: map[string]string{
+				ingtypes.GlobalAdminSocketMode:  "0640",
+				ingtypes.GlobalAdminSocketUser:  "haproxy",
+				ingtypes.GlobalAdminSocketGroup: "monitor",
+			},
+			expected: hatypes.AdminSocketPermsConfig{Mode: "0640", User: "haproxy", Group: "monitor"},
+		},
+		// 3
+		{
+			config: map[string]string{
+				ingtypes.GlobalAdminSocketMode: "rw-rw----",
+			},
+			logging: `WARN ignoring invalid admin socket mode config: rw-rw----`,
+		},
+		// 4
+		{
+			config: map[string]string{
+				ingtypes.GlobalAdminSocketUser:  "ha proxy",
+				ingtypes.GlobalAdminSocketGroup: "1000",
+			},
+			logging: `
+WARN ignoring invalid admin socket user config: ha proxy
+WARN ignoring invalid admin socket group config: 1000`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.config)
+		c.createUpdater().buildGlobalAdminSocket(d)
+		c.compareObjects("admin socket", i, d.global.AdminSocketPerms, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestAuthProxy(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -150,6 +207,93 @@ func TestBind(t *testing.T) {
 	}
 }
 
+func TestBlockedPaths(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+		logging  string
+	}{
+		// 0
+		{
+			input: "",
+		},
+		// 1
+		{
+			input:    "/.git",
+			expected: []string{"/.git"},
+		},
+		// 2
+		{
+			input: `
+/.git
+  /.env
+
+/wp-admin`,
+			expected: []string{"/.git", "/.env", "/wp-admin"},
+		},
+		// 3
+		{
+			input: `
+.git
+/.env
+/some path`,
+			expected: []string{"/.env"},
+			logging: `
+WARN ignoring invalid blocked path, expected a path starting with a slash: .git
+WARN ignoring invalid blocked path, expected a path starting with a slash: /some path`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalBlockedPaths: test.input,
+		})
+		c.createUpdater().buildGlobalBlockedPaths(d)
+		c.compareObjects("blocked paths", i, c.haproxy.Frontend().BlockedPaths, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestBucket(t *testing.T) {
+	testCases := []struct {
+		config   map[string]string
+		expected hatypes.BucketConfig
+		logging  string
+	}{
+		// 0
+		{
+			config: map[string]string{
+				ingtypes.GlobalBucketCount: "100",
+			},
+		},
+		// 1
+		{
+			config: map[string]string{
+				ingtypes.GlobalBucketCookie: "sessid",
+				ingtypes.GlobalBucketCount:  "100",
+			},
+			expected: hatypes.BucketConfig{Cookie: "sessid", Count: 100},
+		},
+		// 2
+		{
+			config: map[string]string{
+				ingtypes.GlobalBucketCookie: "sessid",
+				ingtypes.GlobalBucketCount:  "0",
+			},
+			logging: `WARN ignoring bucket config, invalid bucket count: 0`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.config)
+		c.createUpdater().buildGlobalBucket(d)
+		c.compareObjects("bucket", i, d.global.Bucket, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestCloseSessions(t *testing.T) {
 	testCases := []struct {
 		annDuration string
@@ -232,6 +376,53 @@ func TestCloseSessions(t *testing.T) {
 	}
 }
 
+func TestCookie(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			key:      "Ingress",
+			expected: "Ingress",
+		},
+		// 2
+		{
+			key:      "a secret key",
+			expected: "a secret key",
+		},
+		// 3
+		{
+			key:      `my"key`,
+			expected: "Ingress",
+			logging:  `WARN ignoring invalid cookie key config, using 'Ingress' instead`,
+		},
+		// 4
+		{
+			key:      `my\key`,
+			expected: "Ingress",
+			logging:  `WARN ignoring invalid cookie key config, using 'Ingress' instead`,
+		},
+		// 5
+		{
+			key:      "my\nkey",
+			expected: "Ingress",
+			logging:  `WARN ignoring invalid cookie key config, using 'Ingress' instead`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{ingtypes.GlobalCookieKey: test.key})
+		c.createUpdater().buildGlobalCookie(d)
+		c.compareObjects("cookie key", i, d.global.Cookie.Key, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestCustomConfigProxy(t *testing.T) {
 	testCases := []struct {
 		config   string
@@ -410,6 +601,106 @@ k8s3=10.0.1.31:10053,10.0.1.32:10053,10.0.1.33:10053,
 				},
 			},
 		},
+		// 3
+		{
+			config: map[string]string{
+				ingtypes.GlobalDNSResolveRetries: "3",
+				ingtypes.GlobalDNSResolvers:      "k8s=10.0.1.11",
+				ingtypes.GlobalDNSTimeoutResolve: "1s",
+				ingtypes.GlobalDNSTimeoutRetry:   "2s",
+				ingtypes.GlobalDNSHoldValid:      "10s",
+			},
+			expected: hatypes.DNSConfig{
+				Resolvers: []*hatypes.DNSResolver{
+					{
+						Name: "k8s",
+						Nameservers: []*hatypes.DNSNameserver{
+							{
+								Name:     "ns01",
+								Endpoint: "10.0.1.11:53",
+							},
+						},
+						HoldValid:      "10s",
+						ResolveRetries: 3,
+						TimeoutResolve: "1s",
+						TimeoutRetry:   "2s",
+					},
+				},
+			},
+		},
+		// 4
+		{
+			config: map[string]string{
+				ingtypes.GlobalDNSResolvers:      "k8s=10.0.1.11",
+				ingtypes.GlobalDNSTimeoutResolve: "1",
+			},
+			expected: hatypes.DNSConfig{
+				Resolvers: []*hatypes.DNSResolver{
+					{
+						Name: "k8s",
+						Nameservers: []*hatypes.DNSNameserver{
+							{
+								Name:     "ns01",
+								Endpoint: "10.0.1.11:53",
+							},
+						},
+					},
+				},
+			},
+			logging: `WARN ignoring invalid time format on global/default config: 1`,
+		},
+		// 5
+		{
+			config: map[string]string{
+				ingtypes.GlobalDNSResolvers:    "k8s=10.0.1.11",
+				ingtypes.GlobalDNSHoldNX:       "5s",
+				ingtypes.GlobalDNSHoldObsolete: "10s",
+				ingtypes.GlobalDNSHoldOther:    "15s",
+				ingtypes.GlobalDNSHoldRefused:  "20s",
+				ingtypes.GlobalDNSHoldTimeout:  "25s",
+				ingtypes.GlobalDNSHoldValid:    "30s",
+			},
+			expected: hatypes.DNSConfig{
+				Resolvers: []*hatypes.DNSResolver{
+					{
+						Name: "k8s",
+						Nameservers: []*hatypes.DNSNameserver{
+							{
+								Name:     "ns01",
+								Endpoint: "10.0.1.11:53",
+							},
+						},
+						HoldNX:       "5s",
+						HoldObsolete: "10s",
+						HoldOther:    "15s",
+						HoldRefused:  "20s",
+						HoldTimeout:  "25s",
+						HoldValid:    "30s",
+					},
+				},
+			},
+		},
+		// 6
+		{
+			config: map[string]string{
+				ingtypes.GlobalDNSResolvers: "k8s=10.0.1.11",
+				ingtypes.GlobalDNSHoldNX:    "5",
+			},
+			expected: hatypes.DNSConfig{
+				Resolvers: []*hatypes.DNSResolver{
+					{
+						Name: "k8s",
+						Nameservers: []*hatypes.DNSNameserver{
+							{
+								Name:     "ns01",
+								Endpoint: "10.0.1.11:53",
+							},
+						},
+					},
+				},
+			},
+			logging: `WARN ignoring invalid time format on global/default config: 5`,
+		},
 	}
 	for i, test := range testCases {
 		c := setup(t)
@@ -556,82 +847,281 @@ func TestForwardFor(t *testing.T) {
 	}
 }
 
-func TestFrontingProxy(t *testing.T) {
+func TestFrontendMaxConn(t *testing.T) {
 	testCases := []struct {
-		ann      map[string]string
-		expected hatypes.GlobalBindConfig
+		maxConn  string
+		expected int
+		logging  string
 	}{
 		// 0
-		{
-			ann: map[string]string{
-				ingtypes.GlobalHTTPStoHTTPPort: "8000",
-			},
-			expected: hatypes.GlobalBindConfig{
-				FrontingBind: ":8000",
-			},
-		},
+		{},
 		// 1
 		{
-			ann: map[string]string{
-				ingtypes.GlobalFrontingProxyPort: "9000",
-			},
-			expected: hatypes.GlobalBindConfig{
-				FrontingBind: ":9000",
-			},
+			maxConn:  "1000",
+			expected: 1000,
 		},
 		// 2
 		{
-			ann: map[string]string{
-				ingtypes.GlobalHTTPStoHTTPPort:   "9000",
-				ingtypes.GlobalBindFrontingProxy: ":7000",
-			},
-			expected: hatypes.GlobalBindConfig{
-				FrontingBind: ":7000",
-			},
+			maxConn:  "0",
+			expected: 0,
 		},
 		// 3
 		{
-			ann: map[string]string{
-				ingtypes.GlobalFrontingProxyPort: "8000",
-				ingtypes.GlobalBindFrontingProxy: "127.0.0.1:7000",
-			},
-			expected: hatypes.GlobalBindConfig{
-				FrontingBind: "127.0.0.1:7000",
-			},
+			maxConn: "-1",
+			logging: `WARN ignoring invalid frontend-maxconn config: -1`,
+		},
+		// 4
+		{
+			maxConn: "1k",
+			logging: `WARN ignoring invalid frontend-maxconn config: 1k`,
 		},
 	}
-	frontingSockID := 10011
 	for i, test := range testCases {
 		c := setup(t)
-		d := c.createGlobalData(test.ann)
-		c.createUpdater().buildGlobalHTTPStoHTTP(d)
-		test.expected.FrontingSockID = frontingSockID
-		c.compareObjects("fronting proxy", i, d.global.Bind, test.expected)
+		d := c.createGlobalData(map[string]string{ingtypes.GlobalFrontendMaxConn: test.maxConn})
+		c.createUpdater().buildGlobalFrontendMaxConn(d)
+		c.compareObjects("frontend-maxconn", i, c.haproxy.Frontend().MaxConn, test.expected)
+		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
 }
 
-func TestDisableCpuMap(t *testing.T) {
+func TestGeoIP(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	for _, file := range []string{"action.map", "country.map"} {
+		if err := ioutil.WriteFile(filepath.Join(tempdir, file), []byte{}, 0644); err != nil {
+			t.Fatalf("error creating map file: %v", err)
+		}
+	}
 	testCases := []struct {
-		ann      map[string]string
-		expected string
+		actionMap  string
+		countryMap string
+		expected   hatypes.GeoIPConfig
+		logging    string
 	}{
 		// 0
+		{},
+		// 1
 		{
-			ann: map[string]string{
-				ingtypes.GlobalUseCPUMap:     "false",
-				ingtypes.GlobalNbthread:      "1",
-				ingtypes.GlobalNbprocBalance: "1",
-			},
-			expected: "",
+			countryMap: "<dir>/country.map",
 		},
-		// 1
+		// 2
 		{
-			ann: map[string]string{
-				ingtypes.GlobalUseCPUMap:     "false",
-				ingtypes.GlobalCPUMap:        "auto 1/1 1-",
-				ingtypes.GlobalNbthread:      "1",
-				ingtypes.GlobalNbprocBalance: "1",
+			actionMap: "<dir>/action.map",
+			logging:   `WARN ignoring geoip config, missing 'geoip-country-map' configuration`,
+		},
+		// 3
+		{
+			actionMap:  "<dir>/action.map",
+			countryMap: "<dir>/missing.map",
+			logging:    `WARN ignoring geoip config, map file cannot be read: stat <dir>/missing.map: no such file or directory`,
+		},
+		// 4
+		{
+			actionMap:  "<dir>/missing.map",
+			countryMap: "<dir>/country.map",
+			logging:    `WARN ignoring geoip config, map file cannot be read: stat <dir>/missing.map: no such file or directory`,
+		},
+		// 5
+		{
+			actionMap:  "<dir>/action.map",
+			countryMap: "<dir>/country.map",
+			expected: hatypes.GeoIPConfig{
+				ActionMapFile:  "<dir>/action.map",
+				CountryMapFile: "<dir>/country.map",
+			},
+		},
+	}
+	replace := func(s string) string {
+		return strings.Replace(s, "<dir>", tempdir, -1)
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalGeoIPActionMap:  replace(test.actionMap),
+			ingtypes.GlobalGeoIPCountryMap: replace(test.countryMap),
+		})
+		c.createUpdater().buildGlobalGeoIP(d)
+		test.expected.ActionMapFile = replace(test.expected.ActionMapFile)
+		test.expected.CountryMapFile = replace(test.expected.CountryMapFile)
+		c.compareObjects("geoip", i, d.global.GeoIP, test.expected)
+		c.logger.CompareLogging(replace(test.logging))
+		c.teardown()
+	}
+}
+
+func TestErrorFiles(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+	for _, file := range []string{"401.http", "404.http", "413.http", "501.http", "503.http", "504.http"} {
+		if err := ioutil.WriteFile(filepath.Join(tempdir, file), []byte{}, 0644); err != nil {
+			t.Fatalf("error creating error file: %v", err)
+		}
+	}
+	testCases := []struct {
+		config   string
+		expected []*hatypes.ErrorFile
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			config: "503 <dir>/503.http",
+			expected: []*hatypes.ErrorFile{
+				{Code: 503, Filename: "<dir>/503.http"},
+			},
+		},
+		// 2
+		{
+			config: `
+503 <dir>/503.http
+
+504   <dir>/504.http
+`,
+			expected: []*hatypes.ErrorFile{
+				{Code: 503, Filename: "<dir>/503.http"},
+				{Code: 504, Filename: "<dir>/504.http"},
+			},
+		},
+		// 3
+		{
+			config: `
+503 <dir>/missing.http
+504 <dir>/504.http`,
+			expected: []*hatypes.ErrorFile{
+				{Code: 504, Filename: "<dir>/504.http"},
+			},
+			logging: `WARN ignoring error file config, file cannot be read: stat <dir>/missing.http: no such file or directory`,
+		},
+		// 4
+		{
+			config: `
+418 <dir>/503.http
+50x <dir>/503.http
+503`,
+			logging: `
+WARN ignoring error file config, unsupported status code: 418
+WARN ignoring error file config, unsupported status code: 50x
+WARN ignoring error file config, expected '<code> <filename>' but got: 503`,
+		},
+		// 5
+		{
+			config: `
+401 <dir>/401.http
+404 <dir>/404.http
+413 <dir>/413.http
+501 <dir>/501.http`,
+			expected: []*hatypes.ErrorFile{
+				{Code: 401, Filename: "<dir>/401.http"},
+				{Code: 404, Filename: "<dir>/404.http"},
+				{Code: 413, Filename: "<dir>/413.http"},
+				{Code: 501, Filename: "<dir>/501.http"},
+			},
+		},
+	}
+	replace := func(s string) string {
+		return strings.Replace(s, "<dir>", tempdir, -1)
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalErrorFiles: replace(test.config),
+		})
+		c.createUpdater().buildGlobalErrorFiles(d)
+		for _, errorfile := range test.expected {
+			errorfile.Filename = replace(errorfile.Filename)
+		}
+		c.compareObjects("error files", i, d.global.ErrorFiles, test.expected)
+		c.logger.CompareLogging(replace(test.logging))
+		c.teardown()
+	}
+}
+
+func TestFrontingProxy(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected hatypes.GlobalBindConfig
+	}{
+		// 0
+		{
+			ann: map[string]string{
+				ingtypes.GlobalHTTPStoHTTPPort: "8000",
+			},
+			expected: hatypes.GlobalBindConfig{
+				FrontingBind: ":8000",
+			},
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.GlobalFrontingProxyPort: "9000",
+			},
+			expected: hatypes.GlobalBindConfig{
+				FrontingBind: ":9000",
+			},
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.GlobalHTTPStoHTTPPort:   "9000",
+				ingtypes.GlobalBindFrontingProxy: ":7000",
+			},
+			expected: hatypes.GlobalBindConfig{
+				FrontingBind: ":7000",
+			},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.GlobalFrontingProxyPort: "8000",
+				ingtypes.GlobalBindFrontingProxy: "127.0.0.1:7000",
+			},
+			expected: hatypes.GlobalBindConfig{
+				FrontingBind: "127.0.0.1:7000",
+			},
+		},
+	}
+	frontingSockID := 10011
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.ann)
+		c.createUpdater().buildGlobalHTTPStoHTTP(d)
+		test.expected.FrontingSockID = frontingSockID
+		c.compareObjects("fronting proxy", i, d.global.Bind, test.expected)
+		c.teardown()
+	}
+}
+
+func TestDisableCpuMap(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		expected string
+		logging  string
+	}{
+		// 0
+		{
+			ann: map[string]string{
+				ingtypes.GlobalUseCPUMap:     "false",
+				ingtypes.GlobalNbthread:      "1",
+				ingtypes.GlobalNbprocBalance: "1",
+			},
+			expected: "",
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.GlobalUseCPUMap:     "false",
+				ingtypes.GlobalCPUMap:        "auto 1/1 1-",
+				ingtypes.GlobalNbthread:      "1",
+				ingtypes.GlobalNbprocBalance: "1",
 			},
 			expected: "",
 		},
@@ -654,12 +1144,44 @@ func TestDisableCpuMap(t *testing.T) {
 			},
 			expected: "auto:1/1-2 0-1",
 		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.GlobalUseCPUMap:     "true",
+				ingtypes.GlobalCPUMap:        "auto:1/1-4 0-3",
+				ingtypes.GlobalNbthread:      "4",
+				ingtypes.GlobalNbprocBalance: "1",
+			},
+			expected: "auto:1/1-4 0-3",
+		},
+		// 5
+		{
+			ann: map[string]string{
+				ingtypes.GlobalUseCPUMap:     "true",
+				ingtypes.GlobalCPUMap:        "auto:1/1-8 0-7",
+				ingtypes.GlobalNbthread:      "4",
+				ingtypes.GlobalNbprocBalance: "1",
+			},
+			expected: "auto:1/1-4 0-3",
+			logging:  "WARN ignoring cpu-map configmap option, thread 8 is out of range, nbthread is 4: auto:1/1-8 0-7",
+		},
+		// 6
+		{
+			ann: map[string]string{
+				ingtypes.GlobalUseCPUMap:     "true",
+				ingtypes.GlobalCPUMap:        "1/all 0-7",
+				ingtypes.GlobalNbthread:      "4",
+				ingtypes.GlobalNbprocBalance: "1",
+			},
+			expected: "1/all 0-7",
+		},
 	}
 	for i, test := range testCases {
 		c := setup(t)
 		d := c.createGlobalData(test.ann)
 		c.createUpdater().buildGlobalProc(d)
 		c.compareObjects("cpu map", i, d.global.Procs.CPUMap, test.expected)
+		c.logger.CompareLogging(test.logging)
 		c.teardown()
 	}
 }
@@ -710,6 +1232,133 @@ func TestPathTypeOrder(t *testing.T) {
 	}
 }
 
+func TestPeers(t *testing.T) {
+	testCases := []struct {
+		peers    string
+		expected hatypes.PeersConfig
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			peers: "ingress-0=10.0.1.11:10000",
+			expected: hatypes.PeersConfig{
+				SectionName: "_peers",
+				Peers: []*hatypes.Peer{
+					{Name: "ingress-0", Endpoint: "10.0.1.11:10000"},
+				},
+			},
+		},
+		// 2
+		{
+			peers: `
+ingress-0=10.0.1.11:10000, ingress-1=10.0.1.12:10000
+ingress-2=10.0.1.13:10000
+`,
+			expected: hatypes.PeersConfig{
+				SectionName: "_peers",
+				Peers: []*hatypes.Peer{
+					{Name: "ingress-0", Endpoint: "10.0.1.11:10000"},
+					{Name: "ingress-1", Endpoint: "10.0.1.12:10000"},
+					{Name: "ingress-2", Endpoint: "10.0.1.13:10000"},
+				},
+			},
+		},
+		// 3
+		{
+			peers: "ingress-0=10.0.1.11,ingress-1=10.0.1.12:10000,ingress#2=10.0.1.13:10000",
+			expected: hatypes.PeersConfig{
+				SectionName: "_peers",
+				Peers: []*hatypes.Peer{
+					{Name: "ingress-1", Endpoint: "10.0.1.12:10000"},
+				},
+			},
+			logging: `
+WARN ignoring misconfigured peer: ingress-0=10.0.1.11
+WARN ignoring misconfigured peer: ingress#2=10.0.1.13:10000`,
+		},
+		// 4
+		{
+			peers: "ingress-0=10.0.1.11:10000,ingress-0=10.0.1.12:10000",
+			expected: hatypes.PeersConfig{
+				SectionName: "_peers",
+				Peers: []*hatypes.Peer{
+					{Name: "ingress-0", Endpoint: "10.0.1.11:10000"},
+				},
+			},
+			logging: `WARN ignoring duplicated peer name: ingress-0=10.0.1.12:10000`,
+		},
+		// 5
+		{
+			peers:   "ingress-0",
+			logging: `WARN ignoring misconfigured peer: ingress-0`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{ingtypes.GlobalPeers: test.peers})
+		c.createUpdater().buildGlobalPeers(d)
+		c.compareObjects("peers", i, d.global.Peers, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestRedirectCode(t *testing.T) {
+	testCases := []struct {
+		config  map[string]string
+		expFrom int
+		expTo   int
+		expSSL  int
+		logging string
+	}{
+		// 0
+		{
+			expFrom: 302,
+			expTo:   302,
+		},
+		// 1
+		{
+			config: map[string]string{
+				ingtypes.GlobalRedirectFromCode: "301",
+				ingtypes.GlobalRedirectToCode:   "307",
+				ingtypes.GlobalSSLRedirectCode:  "308",
+			},
+			expFrom: 301,
+			expTo:   307,
+			expSSL:  308,
+		},
+		// 2
+		{
+			config: map[string]string{
+				ingtypes.GlobalRedirectFromCode: "200",
+				ingtypes.GlobalRedirectToCode:   "308x",
+				ingtypes.GlobalSSLRedirectCode:  "304",
+			},
+			expFrom: 302,
+			expTo:   302,
+			logging: `
+WARN ignoring invalid redirect code on 'redirect-from-code' configmap option, supported codes are 301, 302, 303, 307 and 308: 200
+WARN ignoring invalid redirect code on 'redirect-to-code' configmap option, supported codes are 301, 302, 303, 307 and 308: 308x
+WARN ignoring invalid redirect code on 'ssl-redirect-code' configmap option, supported codes are 301, 302, 303, 307 and 308: 304`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.config)
+		u := c.createUpdater()
+		from := u.validateRedirectCode(d, ingtypes.GlobalRedirectFromCode, 302)
+		to := u.validateRedirectCode(d, ingtypes.GlobalRedirectToCode, 302)
+		ssl := u.validateRedirectCode(d, ingtypes.GlobalSSLRedirectCode, 0)
+		c.compareObjects("redirect from code", i, from, test.expFrom)
+		c.compareObjects("redirect to code", i, to, test.expTo)
+		c.compareObjects("ssl redirect code", i, ssl, test.expSSL)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestSecurity(t *testing.T) {
 	testCases := []struct {
 		ann      map[string]string
@@ -791,3 +1440,480 @@ func TestSecurity(t *testing.T) {
 		c.teardown()
 	}
 }
+
+func TestUniqueID(t *testing.T) {
+	testCases := []struct {
+		format    string
+		header    string
+		expFormat string
+		expHeader string
+		logging   string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			header:  "X-Request-ID",
+			logging: `WARN ignoring 'unique-id-header' config, 'unique-id-format' need to be configured`,
+		},
+		// 2
+		{
+			format:    "%[uuid()]",
+			expFormat: "%[uuid()]",
+		},
+		// 3
+		{
+			format:    "%[uuid()]",
+			header:    "X-Request-ID",
+			expFormat: "%[uuid()]",
+			expHeader: "X-Request-ID",
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalUniqueIDFormat: test.format,
+			ingtypes.GlobalUniqueIDHeader: test.header,
+		})
+		c.createUpdater().buildGlobalUniqueID(d)
+		c.compareObjects("unique-id-format", i, d.global.UniqueIDFormat, test.expFormat)
+		c.compareObjects("unique-id-header", i, d.global.UniqueIDHeader, test.expHeader)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestSSLDHParam(t *testing.T) {
+	testCases := []struct {
+		dhParam  string
+		maxSize  string
+		expected hatypes.DHParamConfig
+		logging  string
+	}{
+		// 0
+		{
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 2048},
+		},
+		// 1
+		{
+			dhParam:  "ingress/dh",
+			expected: hatypes.DHParamConfig{Filename: "/var/haproxy/ssl/dh.pem", DefaultMaxSize: 2048},
+		},
+		// 2
+		{
+			dhParam:  "ingress/missing",
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 2048},
+			logging:  `WARN ignoring ssl-dh-param config, DH params cannot be read: secret not found: 'ingress/missing'`,
+		},
+		// 3
+		{
+			maxSize:  "4096",
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 4096},
+		},
+		// 4
+		{
+			maxSize:  "1024",
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 1024},
+		},
+		// 5
+		{
+			maxSize:  "512",
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 2048},
+			logging:  `WARN ignoring invalid ssl-dh-default-max-size config, using '2048' instead: 512`,
+		},
+		// 6
+		{
+			maxSize:  "2k",
+			expected: hatypes.DHParamConfig{DefaultMaxSize: 2048},
+			logging:  `WARN ignoring invalid ssl-dh-default-max-size config, using '2048' instead: 2k`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.cache.SecretDHPath = map[string]string{"ingress/dh": "/var/haproxy/ssl/dh.pem"}
+		maxSize := test.maxSize
+		if maxSize == "" {
+			maxSize = "2048"
+		}
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalSSLDHDefaultMaxSize: maxSize,
+			ingtypes.GlobalSSLDHParam:          test.dhParam,
+		})
+		c.createUpdater().buildGlobalSSL(d)
+		c.compareObjects("ssl-dh-param", i, d.global.SSL.DHParam, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestSSLMinVer(t *testing.T) {
+	testCases := []struct {
+		minVer   string
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			minVer:   "TLSv1.2",
+			expected: "TLSv1.2",
+		},
+		// 2
+		{
+			minVer:   "SSLv3",
+			expected: "SSLv3",
+		},
+		// 3
+		{
+			minVer:  "TLSv1.4",
+			logging: `WARN ignoring invalid ssl-min-ver value: TLSv1.4`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{ingtypes.GlobalSSLMinVer: test.minVer})
+		c.createUpdater().buildGlobalSSL(d)
+		c.compareObjects("ssl-min-ver", i, d.global.SSL.MinVersion, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestSSLOptions(t *testing.T) {
+	testCases := []struct {
+		options  string
+		expected string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			options:  "no-sslv3",
+			expected: "no-sslv3",
+		},
+		// 2
+		{
+			options:  "no-sslv3 no-tlsv10 no-tlsv11",
+			expected: "no-sslv3 no-tlsv10 no-tlsv11",
+		},
+		// 3
+		{
+			options:  "  no-sslv3\n  no-tlsv10  no-tlsv11\n",
+			expected: "no-sslv3 no-tlsv10 no-tlsv11",
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{ingtypes.GlobalSSLOptions: test.options})
+		c.createUpdater().buildGlobalSSL(d)
+		c.compareObjects("ssl-options", i, d.global.SSL.Options, test.expected)
+		c.logger.CompareLogging("")
+		c.teardown()
+	}
+}
+
+func TestSSLSession(t *testing.T) {
+	testCases := []struct {
+		cacheSize string
+		lifetime  string
+		expSize   string
+		expTime   string
+		logging   string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			cacheSize: "50000",
+			lifetime:  "10m",
+			expSize:   "50000",
+			expTime:   "10m",
+		},
+		// 2
+		{
+			cacheSize: "0",
+			lifetime:  "600s",
+			expSize:   "0",
+			expTime:   "600s",
+		},
+		// 3
+		{
+			cacheSize: "-1",
+			logging:   `WARN ignoring invalid ssl-session-cache-size config: -1`,
+		},
+		// 4
+		{
+			cacheSize: "20k",
+			logging:   `WARN ignoring invalid ssl-session-cache-size config: 20k`,
+		},
+		// 5
+		{
+			lifetime: "10 minutes",
+			logging:  `WARN ignoring invalid time format on global/default config: 10 minutes`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalSSLSessionCacheSize: test.cacheSize,
+			ingtypes.GlobalSSLSessionLifetime:  test.lifetime,
+		})
+		c.createUpdater().buildGlobalSSL(d)
+		var size string
+		if d.global.SSL.SessionCacheSize != nil {
+			size = strconv.Itoa(*d.global.SSL.SessionCacheSize)
+		}
+		c.compareObjects("ssl-session-cache-size", i, size, test.expSize)
+		c.compareObjects("ssl-session-lifetime", i, d.global.SSL.SessionLifetime, test.expTime)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestSyslogRing(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		ring     string
+		size     string
+		expected hatypes.SyslogRing
+		logging  string
+	}{
+		// 0
+		{
+			endpoint: "127.0.0.1:1514",
+		},
+		// 1
+		{
+			endpoint: "127.0.0.1:1514",
+			ring:     "true",
+			expected: hatypes.SyslogRing{Name: "syslog"},
+		},
+		// 2
+		{
+			endpoint: "127.0.0.1:1514",
+			ring:     "true",
+			size:     "32k",
+			expected: hatypes.SyslogRing{Name: "syslog", Size: 32768},
+		},
+		// 3
+		{
+			endpoint: "127.0.0.1:1514",
+			ring:     "true",
+			size:     "32x",
+			expected: hatypes.SyslogRing{Name: "syslog"},
+			logging:  `WARN ignoring invalid syslog ring size: 32x`,
+		},
+		// 4
+		{
+			ring:    "true",
+			logging: `WARN ignoring syslog ring config, missing 'syslog-endpoint' configuration`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalSyslogEndpoint: test.endpoint,
+			ingtypes.GlobalSyslogRing:     test.ring,
+			ingtypes.GlobalSyslogRingSize: test.size,
+		})
+		c.createUpdater().buildGlobalSyslog(d)
+		c.compareObjects("syslog ring", i, d.global.Syslog.Ring, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestGlobalTimeout(t *testing.T) {
+	type timeout struct {
+		Client, Connect, Queue, Server, Stop string
+		StopDuration                         time.Duration
+	}
+	testCases := []struct {
+		config   map[string]string
+		expected timeout
+		logging  string
+	}{
+		// 0
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10s",
+				ingtypes.BackTimeoutServer:   "500ms",
+			},
+			expected: timeout{Client: "1m", Connect: "10s", Server: "500ms"},
+		},
+		// 1
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10",
+				ingtypes.BackTimeoutServer:   "500ms",
+			},
+			expected: timeout{Client: "1m", Connect: "5s", Server: "500ms"},
+			logging:  `WARN ignoring invalid 'timeout-connect' configmap option '10', using '5s' instead: time unit is missing, eg 10s or 10ms`,
+		},
+		// 2
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1min",
+				ingtypes.BackTimeoutServer:   "-1s",
+			},
+			expected: timeout{Client: "50s", Connect: "5s", Server: "50s"},
+			logging: `
+WARN ignoring invalid 'timeout-client' configmap option '1min', using '50s' instead: time should be a number followed by one of the units us, ms, s, m, h or d
+WARN ignoring invalid 'timeout-connect' configmap option '', using '5s' instead: time is empty
+WARN ignoring invalid 'timeout-server' configmap option '-1s', using '50s' instead: time should be a number followed by one of the units us, ms, s, m, h or d`,
+		},
+		// 3
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10s",
+				ingtypes.BackTimeoutQueue:    "30s",
+				ingtypes.BackTimeoutServer:   "500ms",
+			},
+			expected: timeout{Client: "1m", Connect: "10s", Queue: "30s", Server: "500ms"},
+		},
+		// 4
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10s",
+				ingtypes.BackTimeoutQueue:    "30",
+				ingtypes.BackTimeoutServer:   "500ms",
+			},
+			expected: timeout{Client: "1m", Connect: "10s", Server: "500ms"},
+			logging:  `WARN ignoring invalid time format on global/default config: 30`,
+		},
+		// 5
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10s",
+				ingtypes.BackTimeoutServer:   "500ms",
+				ingtypes.GlobalTimeoutStop:   "30m",
+			},
+			expected: timeout{Client: "1m", Connect: "10s", Server: "500ms", Stop: "30m", StopDuration: 30 * time.Minute},
+		},
+		// 6
+		{
+			config: map[string]string{
+				ingtypes.GlobalTimeoutClient: "1m",
+				ingtypes.BackTimeoutConnect:  "10s",
+				ingtypes.BackTimeoutServer:   "500ms",
+				ingtypes.GlobalTimeoutStop:   "30",
+			},
+			expected: timeout{Client: "1m", Connect: "10s", Server: "500ms"},
+			logging:  `WARN ignoring invalid time format on global/default config: 30`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.config)
+		c.createUpdater().buildGlobalTimeout(d)
+		actual := timeout{
+			Client:       d.global.Timeout.Client,
+			Connect:      d.global.Timeout.Connect,
+			Queue:        d.global.Timeout.Queue,
+			Server:       d.global.Timeout.Server,
+			Stop:         d.global.Timeout.Stop,
+			StopDuration: d.global.TimeoutStopDuration,
+		}
+		c.compareObjects("timeout", i, actual, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestGlobalTune(t *testing.T) {
+	testCases := []struct {
+		config   map[string]string
+		expected hatypes.TuneConfig
+		logging  string
+	}{
+		// 0
+		{
+			config:   map[string]string{},
+			expected: hatypes.TuneConfig{},
+		},
+		// 1
+		{
+			config: map[string]string{
+				ingtypes.GlobalHTTP2MaxConcurrentStreams: "200",
+				ingtypes.GlobalMaxAccept:                 "64",
+			},
+			expected: hatypes.TuneConfig{H2MaxConcurrentStreams: 200, MaxAccept: 64},
+		},
+		// 2
+		{
+			config: map[string]string{
+				ingtypes.GlobalMaxAccept: "-1",
+			},
+			expected: hatypes.TuneConfig{MaxAccept: -1},
+		},
+		// 3
+		{
+			config: map[string]string{
+				ingtypes.GlobalMaxAccept: "0",
+			},
+			expected: hatypes.TuneConfig{},
+			logging:  `WARN ignoring invalid max accept config: 0`,
+		},
+		// 4
+		{
+			config: map[string]string{
+				ingtypes.GlobalMaxAccept: "-2",
+			},
+			expected: hatypes.TuneConfig{},
+			logging:  `WARN ignoring invalid max accept config: -2`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(test.config)
+		c.createUpdater().buildGlobalTune(d)
+		c.compareObjects("tune", i, d.global.Tune, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestNormalizeURI(t *testing.T) {
+	testCases := []struct {
+		modes    string
+		expected []string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			modes:    "path-merge-slashes",
+			expected: []string{"path-merge-slashes"},
+		},
+		// 2
+		{
+			modes:    "path-merge-slashes, path-strip-dotdot  full,percent-to-uppercase strict",
+			expected: []string{"path-merge-slashes", "path-strip-dotdot full", "percent-to-uppercase strict"},
+		},
+		// 3
+		{
+			modes:    "path-merge-slashes,merge-slashes,path-strip-dot full",
+			expected: []string{"path-merge-slashes"},
+			logging: `
+WARN ignoring invalid normalize-uri mode: merge-slashes
+WARN ignoring invalid normalize-uri mode: path-strip-dot full`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createGlobalData(map[string]string{
+			ingtypes.GlobalNormalizeURI: test.modes,
+		})
+		c.createUpdater().buildGlobalNormalizeURI(d)
+		c.compareObjects("normalize-uri", i, d.global.NormalizeURI, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
@@ -17,8 +17,15 @@ limitations under the License.
 package annotations
 
 import (
+	"regexp"
+	"strings"
+
 	ingtypes "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/ingress/types"
+	ingutils "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/ingress/utils"
 	convtypes "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/types"
+	convutils "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/utils"
+	hatypes "github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy/types"
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/utils"
 )
 
 func (c *updater) buildHostAuthTLS(d *hostData) {
@@ -54,6 +61,61 @@ func (c *updater) buildHostAuthTLS(d *hostData) {
 	tls.CAErrorPage = d.mapper.Get(ingtypes.HostAuthTLSErrorPage).Value
 }
 
+func (c *updater) buildHostBuckets(d *hostData) {
+	buckets := d.mapper.Get(ingtypes.HostBucketBackends)
+	if buckets.Source == nil || buckets.Value == "" {
+		return
+	}
+	count := c.haproxy.Global().Bucket.Count
+	if count == 0 {
+		c.logger.Warn("ignoring bucket backends on %v: bucket cookie is not configured", buckets.Source)
+		return
+	}
+	for _, bucket := range utils.Split(buckets.Value, ",") {
+		first, last, svcName, svcPort, err := ingutils.ParseBucket(bucket)
+		if err != nil {
+			c.logger.Warn("ignoring bucket on %v: %v", buckets.Source, err)
+			continue
+		}
+		if last >= count {
+			c.logger.Warn("ignoring bucket on %v: bucket out of range, should be lower than %d: %s", buckets.Source, count, bucket)
+			continue
+		}
+		// warns already logged when ingress parser tried to acquire the backend
+		svc, err := c.cache.GetService(buckets.Source.Namespace, svcName)
+		if err != nil {
+			continue
+		}
+		port := convutils.FindServicePort(svc, svcPort)
+		if port == nil {
+			continue
+		}
+		backend := c.haproxy.Backends().FindBackend(svc.Namespace, svc.Name, port.TargetPort.String())
+		if backend == nil {
+			continue
+		}
+		d.host.Buckets = append(d.host.Buckets, &hatypes.HostBucket{
+			First:   first,
+			Last:    last,
+			Backend: backend.ID,
+		})
+	}
+}
+
+var canonicalPathPrefixRegex = regexp.MustCompile(`^(/[^/\s#'"%]+)+$`)
+
+func (c *updater) buildHostCanonicalPathPrefix(d *hostData) {
+	prefix := d.mapper.Get(ingtypes.HostCanonicalPathPrefix)
+	if prefix.Value == "" {
+		return
+	}
+	if !canonicalPathPrefixRegex.MatchString(prefix.Value) {
+		c.logger.Warn("ignoring invalid canonical path prefix on %v: %s", prefix.Source, prefix.Value)
+		return
+	}
+	d.host.CanonicalPathPrefix = prefix.Value
+}
+
 func (c *updater) buildHostCertSigner(d *hostData) {
 	signer := d.mapper.Get(ingtypes.HostCertSigner)
 	if signer.Value == "" {
@@ -71,6 +133,19 @@ func (c *updater) buildHostCertSigner(d *hostData) {
 	// just the warnings, ingress.syncIngress() has already added the domains
 }
 
+func (c *updater) buildHostContentSecurityPolicy(d *hostData) {
+	policy := d.mapper.Get(ingtypes.HostContentSecurityPolicy)
+	if policy.Value == "" {
+		return
+	}
+	// single quotes are part of the policy syntax, eg 'self'
+	if strings.ContainsAny(policy.Value, "\"\r\n") {
+		c.logger.Warn("ignoring invalid content security policy on %v: %s", policy.Source, policy.Value)
+		return
+	}
+	d.host.ContentSecurityPolicy = strings.TrimSpace(policy.Value)
+}
+
 func (c *updater) buildHostRedirect(d *hostData) {
 	// TODO need a host<->host tracking if a target is found
 	redir := d.mapper.Get(ingtypes.HostRedirectFrom)
@@ -87,6 +162,15 @@ func (c *updater) buildHostRedirect(d *hostData) {
 	} else {
 		d.host.Redirect.RedirectHostRegex = redirRegex.Value
 	}
+	redirWWW := d.mapper.Get(ingtypes.HostRedirectWWW)
+	if redirWWW.Bool() {
+		if d.host.Hostname == hatypes.DefaultHost || strings.HasPrefix(d.host.Hostname, "*.") {
+			c.logger.Warn("ignoring www redirect on %v: hostname '%s' should not be the default host or a wildcard",
+				redirWWW.Source, d.host.Hostname)
+		} else {
+			d.host.Redirect.WWW = true
+		}
+	}
 }
 
 func (c *updater) buildHostSSLPassthrough(d *hostData) {
@@ -112,6 +196,8 @@ func (c *updater) buildHostSSLPassthrough(d *hostData) {
 	d.host.SetSSLPassthrough(true)
 }
 
+var alpnRegex = regexp.MustCompile(`^[A-Za-z0-9/._-]+(,[A-Za-z0-9/._-]+)*$`)
+
 func (c *updater) buildHostTLSConfig(d *hostData) {
 	if cfg := d.mapper.Get(ingtypes.HostSSLCiphers); cfg.Source != nil {
 		d.host.TLS.Ciphers = cfg.Value
@@ -120,7 +206,19 @@ func (c *updater) buildHostTLSConfig(d *hostData) {
 		d.host.TLS.CipherSuites = cfg.Value
 	}
 	if cfg := d.mapper.Get(ingtypes.HostTLSALPN); cfg.Source != nil {
-		d.host.TLS.ALPN = cfg.Value
+		if alpnRegex.MatchString(cfg.Value) {
+			d.host.TLS.ALPN = cfg.Value
+		} else {
+			c.logger.Warn("ignoring invalid tls-alpn on %v: %s", cfg.Source, cfg.Value)
+		}
+	}
+	d.host.TLS.EarlyData = d.mapper.Get(ingtypes.HostSSLEarlyData).Bool()
+	if minVer := d.mapper.Get(ingtypes.HostSSLMinVerHost); minVer.Value != "" {
+		if sslVersionRegex.MatchString(minVer.Value) {
+			d.host.TLS.MinVersion = minVer.Value
+		} else {
+			c.logger.Warn("ignoring invalid ssl-min-ver-host on %v: %s", minVer.Source, minVer.Value)
+		}
 	}
 	d.host.TLS.Options = d.mapper.Get(ingtypes.HostSSLOptionsHost).Value
 }
@@ -17,14 +17,189 @@ limitations under the License.
 package annotations
 
 import (
+	"strings"
 	"testing"
 
+	conv_helper "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/helper_test"
 	ingtypes "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/ingress/types"
 	hatypes "github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy/types"
 )
 
+func TestBuckets(t *testing.T) {
+	testCases := []struct {
+		ann      map[string]string
+		count    int
+		expected []*hatypes.HostBucket
+		logging  string
+	}{
+		// 0
+		{
+			count: 100,
+		},
+		// 1
+		{
+			ann: map[string]string{
+				ingtypes.HostBucketBackends: "0-49=app:8080",
+			},
+			logging: `WARN ignoring bucket backends on ingress 'default/ing1': bucket cookie is not configured`,
+		},
+		// 2
+		{
+			ann: map[string]string{
+				ingtypes.HostBucketBackends: "0-49=app:8080, 50-99=app-v2:8080",
+			},
+			count: 100,
+			expected: []*hatypes.HostBucket{
+				{First: 0, Last: 49, Backend: "default_app_8080"},
+				{First: 50, Last: 99, Backend: "default_app-v2_8080"},
+			},
+		},
+		// 3
+		{
+			ann: map[string]string{
+				ingtypes.HostBucketBackends: "0=app:8080,1=other/app:http",
+			},
+			count: 2,
+			expected: []*hatypes.HostBucket{
+				{First: 0, Last: 0, Backend: "default_app_8080"},
+				{First: 1, Last: 1, Backend: "other_app_8000"},
+			},
+		},
+		// 4
+		{
+			ann: map[string]string{
+				ingtypes.HostBucketBackends: "0-9=app:8080,5-1=app:8080,8-10=app-v2:8080,0=notfound:8080",
+			},
+			count: 10,
+			expected: []*hatypes.HostBucket{
+				{First: 0, Last: 9, Backend: "default_app_8080"},
+			},
+			logging: `
+WARN ignoring bucket on ingress 'default/ing1': invalid bucket range: 5-1=app:8080
+WARN ignoring bucket on ingress 'default/ing1': bucket out of range, should be lower than 10: 8-10=app-v2:8080`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		for _, svc := range []struct{ name, port string }{
+			{"default/app", "8080"},
+			{"default/app-v2", "8080"},
+			{"other/app", "http:80:8000"},
+		} {
+			s, _ := conv_helper.CreateService(svc.name, svc.port, "")
+			c.cache.SvcList = append(c.cache.SvcList, s)
+			ssvc := strings.Split(svc.name, "/")
+			c.haproxy.Backends().AcquireBackend(ssvc[0], ssvc[1], s.Spec.Ports[0].TargetPort.String())
+		}
+		c.haproxy.Global().Bucket.Count = test.count
+		d := c.createHostData(source, test.ann, map[string]string{})
+		c.createUpdater().buildHostBuckets(d)
+		c.compareObjects("buckets", i, d.host.Buckets, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestCanonicalPathPrefix(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			prefix:   "/app",
+			expected: "/app",
+		},
+		// 2
+		{
+			prefix:   "/web/v1",
+			expected: "/web/v1",
+		},
+		// 3
+		{
+			prefix:  "/app/",
+			logging: `WARN ignoring invalid canonical path prefix on ingress 'default/ing1': /app/`,
+		},
+		// 4
+		{
+			prefix:  "app",
+			logging: `WARN ignoring invalid canonical path prefix on ingress 'default/ing1': app`,
+		},
+		// 5
+		{
+			prefix:  "/my app",
+			logging: `WARN ignoring invalid canonical path prefix on ingress 'default/ing1': /my app`,
+		},
+		// 6
+		{
+			prefix:  "/",
+			logging: `WARN ignoring invalid canonical path prefix on ingress 'default/ing1': /`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createHostData(source, map[string]string{ingtypes.HostCanonicalPathPrefix: test.prefix}, map[string]string{})
+		c.createUpdater().buildHostCanonicalPathPrefix(d)
+		c.compareObjects("canonical path prefix", i, d.host.CanonicalPathPrefix, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	testCases := []struct {
+		policy   string
+		expected string
+		logging  string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			policy:   "default-src 'self'; img-src *",
+			expected: "default-src 'self'; img-src *",
+		},
+		// 2
+		{
+			policy:   "  default-src https:  ",
+			expected: "default-src https:",
+		},
+		// 3
+		{
+			policy:  `default-src "self"`,
+			logging: `WARN ignoring invalid content security policy on ingress 'default/ing1': default-src "self"`,
+		},
+		// 4
+		{
+			policy:  "default-src 'self';\r\nimg-src *",
+			logging: `WARN ignoring invalid content security policy on ingress 'default/ing1': default-src 'self';` + "\r\n" + `img-src *`,
+		},
+		// 5
+		{
+			policy: "default-src 'self';\nimg-src *",
+			logging: `WARN ignoring invalid content security policy on ingress 'default/ing1': default-src 'self';
+img-src *`,
+		},
+	}
+	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
+	for i, test := range testCases {
+		c := setup(t)
+		d := c.createHostData(source, map[string]string{ingtypes.HostContentSecurityPolicy: test.policy}, map[string]string{})
+		c.createUpdater().buildHostContentSecurityPolicy(d)
+		c.compareObjects("content security policy", i, d.host.ContentSecurityPolicy, test.expected)
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
 func TestBuildHostRedirect(t *testing.T) {
 	testCases := []struct {
+		hostname   string
 		annPrev    map[string]string
 		ann        map[string]string
 		annDefault map[string]string
@@ -93,6 +268,28 @@ func TestBuildHostRedirect(t *testing.T) {
 			},
 			logging: `WARN ignoring redirect from '*.d.local' on ingress 'default/ing1', it's already targeting to 'dprev.local'`,
 		},
+		// 7
+		{
+			ann: map[string]string{
+				ingtypes.HostRedirectWWW: "true",
+			},
+			expected: hatypes.HostRedirectConfig{WWW: true},
+		},
+		// 8
+		{
+			ann: map[string]string{
+				ingtypes.HostRedirectWWW: "false",
+			},
+			expected: hatypes.HostRedirectConfig{},
+		},
+		// 9
+		{
+			hostname: "*.d.local",
+			ann: map[string]string{
+				ingtypes.HostRedirectWWW: "true",
+			},
+			logging: `WARN ignoring www redirect on ingress 'default/ing1': hostname '*.d.local' should not be the default host or a wildcard`,
+		},
 	}
 	sprev := &Source{Namespace: "prev", Name: "ingprev", Type: "ingress"}
 	source := &Source{Namespace: "default", Name: "ing1", Type: "ingress"}
@@ -104,7 +301,10 @@ func TestBuildHostRedirect(t *testing.T) {
 		dprev := c.createHostData(sprev, test.annPrev, test.annDefault)
 		d := c.createHostData(source, test.ann, test.annDefault)
 		dprev.host = c.haproxy.Hosts().AcquireHost("dprev.local")
-		d.host = c.haproxy.Hosts().AcquireHost("d.local")
+		if test.hostname == "" {
+			test.hostname = "d.local"
+		}
+		d.host = c.haproxy.Hosts().AcquireHost(test.hostname)
 		updater := c.createUpdater()
 		updater.buildHostRedirect(dprev)
 		updater.buildHostRedirect(d)
@@ -301,6 +501,58 @@ func TestTLSConfig(t *testing.T) {
 					Options: "ssl-min-ver TLSv1.0 ssl-max-ver TLSv1.2",
 				}},
 		},
+		// 18
+		{
+			annDefault: map[string]string{
+				ingtypes.HostSSLEarlyData: "false",
+			},
+			ann: map[string]string{
+				ingtypes.HostSSLEarlyData: "true",
+			},
+			expected: hatypes.HostTLSConfig{
+				TLSConfig: hatypes.TLSConfig{
+					EarlyData: true,
+				}},
+		},
+		// 19
+		{
+			ann: map[string]string{
+				ingtypes.HostSSLMinVerHost: "TLSv1.2",
+			},
+			expected: hatypes.HostTLSConfig{
+				TLSConfig: hatypes.TLSConfig{
+					MinVersion: "TLSv1.2",
+				}},
+		},
+		// 20
+		{
+			ann: map[string]string{
+				ingtypes.HostSSLMinVerHost: "TLSv1.4",
+			},
+			expected: hatypes.HostTLSConfig{},
+			logging:  "WARN ignoring invalid ssl-min-ver-host on ingress 'system/ing1': TLSv1.4",
+		},
+		// 21
+		{
+			annDefault: map[string]string{
+				ingtypes.HostTLSALPN: "h2,http/1.1",
+			},
+			ann: map[string]string{
+				ingtypes.HostTLSALPN: "http/1.1",
+			},
+			expected: hatypes.HostTLSConfig{
+				TLSConfig: hatypes.TLSConfig{
+					ALPN: "http/1.1",
+				}},
+		},
+		// 22
+		{
+			ann: map[string]string{
+				ingtypes.HostTLSALPN: "h2, http/1.1",
+			},
+			expected: hatypes.HostTLSConfig{},
+			logging:  "WARN ignoring invalid tls-alpn on ingress 'system/ing1': h2, http/1.1",
+		},
 	}
 	source := &Source{Namespace: "system", Name: "ing1", Type: "ingress"}
 	for i, test := range testCases {
@@ -230,5 +230,9 @@ func (m *PathConfig) String() string {
 
 // String ...
 func (s *Source) String() string {
+	if s == nil {
+		// configmap and default values don't have a source
+		return "global config"
+	}
 	return fmt.Sprintf("%s '%s'", s.Type, s.FullName())
 }
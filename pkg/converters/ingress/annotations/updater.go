@@ -17,8 +17,10 @@ limitations under the License.
 package annotations
 
 import (
+	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 
 	ingtypes "github.com/jcmoraisjr/haproxy-ingress/pkg/converters/ingress/types"
@@ -90,6 +92,57 @@ func (c *updater) validateTime(cfg *ConfigValue) string {
 	return cfg.Value
 }
 
+// parseTime validates a time in the haproxy format, returning a
+// descriptive error if it cannot be used as is.
+func parseTime(value string) (string, error) {
+	if regexValidTime.MatchString(value) {
+		return value, nil
+	}
+	if value == "" {
+		return "", fmt.Errorf("time is empty")
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		return "", fmt.Errorf("time unit is missing, eg %ss or %sms", value, value)
+	}
+	return "", fmt.Errorf("time should be a number followed by one of the units us, ms, s, m, h or d")
+}
+
+// singleLine joins the fields of a possibly multi-line configuration
+// value, so it can be safely rendered in a single line of the haproxy config.
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// validateRedirectCode reads a redirect status code of the global config,
+// returning `fallback` if it is missing or isn't supported by haproxy.
+func (c *updater) validateRedirectCode(d *globalData, key string, fallback int) int {
+	cfg := d.mapper.Get(key)
+	if cfg.Value == "" {
+		return fallback
+	}
+	code, _ := strconv.Atoi(cfg.Value)
+	switch code {
+	case 301, 302, 303, 307, 308:
+		return code
+	}
+	c.logger.Warn("ignoring invalid redirect code on '%s' configmap option, supported codes are 301, 302, 303, 307 and 308: %s", key, cfg.Value)
+	return fallback
+}
+
+// validateDenyStatus reads the status code of a deny rule, returning zero,
+// which means the haproxy's default status, if it is missing or invalid.
+func (c *updater) validateDenyStatus(cfg *ConfigValue) int {
+	if cfg.Value == "" {
+		return 0
+	}
+	code, _ := strconv.Atoi(cfg.Value)
+	if code < 400 || code > 599 {
+		c.logger.Warn("ignoring invalid deny status on %v, expected an error code between 400 and 599: %s", cfg.Source, cfg.Value)
+		return 0
+	}
+	return code
+}
+
 func (c *updater) validateAllowDeny(d *globalData, key string) (allow bool) {
 	cfg := d.mapper.Get(key)
 	value := strings.ToLower(cfg.Value)
@@ -144,11 +197,13 @@ func (c *updater) UpdateGlobalConfig(haproxyConfig haproxy.Config, mapper *Mappe
 	}
 	d.global.AdminSocket = c.options.AdminSocket
 	d.global.MaxConn = mapper.Get(ingtypes.GlobalMaxConnections).Int()
+	d.global.MaxConnRate = mapper.Get(ingtypes.GlobalMaxConnectionRate).Int()
+	d.global.MaxSessRate = mapper.Get(ingtypes.GlobalMaxSessionRate).Int()
+	d.global.ClientTCPKeepAlive = mapper.Get(ingtypes.GlobalClientTCPKeepAlive).Bool()
 	d.global.DefaultBackendRedir = mapper.Get(ingtypes.GlobalDefaultBackendRedirect).String()
-	d.global.DefaultBackendRedirCode = mapper.Get(ingtypes.GlobalDefaultBackendRedirectCode).Int()
+	d.global.DefaultBackendRedirCode = c.validateRedirectCode(d, ingtypes.GlobalDefaultBackendRedirectCode, 302)
 	d.global.DrainSupport.Drain = mapper.Get(ingtypes.GlobalDrainSupport).Bool()
 	d.global.DrainSupport.Redispatch = mapper.Get(ingtypes.GlobalDrainSupportRedispatch).Bool()
-	d.global.Cookie.Key = mapper.Get(ingtypes.GlobalCookieKey).Value
 	d.global.External.HasLua = mapper.Get(ingtypes.GlobalExternalHasLua).Bool()
 	d.global.External.MasterSocket = c.options.MasterSocket
 	d.global.LoadServerState = mapper.Get(ingtypes.GlobalLoadServerState).Bool()
@@ -157,32 +212,51 @@ func (c *updater) UpdateGlobalConfig(haproxyConfig haproxy.Config, mapper *Mappe
 	d.global.StrictHost = mapper.Get(ingtypes.GlobalStrictHost).Bool()
 	d.global.UseHTX = mapper.Get(ingtypes.GlobalUseHTX).Bool()
 	//
-	c.haproxy.Frontend().RedirectFromCode = mapper.Get(ingtypes.GlobalRedirectFromCode).Int()
-	c.haproxy.Frontend().RedirectToCode = mapper.Get(ingtypes.GlobalRedirectToCode).Int()
+	c.haproxy.Frontend().Description = singleLine(mapper.Get(ingtypes.GlobalFrontendDescription).Value)
+	c.haproxy.Frontend().HTTPIgnoreProbes = mapper.Get(ingtypes.GlobalHTTPIgnoreProbes).Bool()
+	c.haproxy.Frontend().RedirectFromCode = c.validateRedirectCode(d, ingtypes.GlobalRedirectFromCode, 302)
+	c.haproxy.Frontend().RedirectToCode = c.validateRedirectCode(d, ingtypes.GlobalRedirectToCode, 302)
+	c.haproxy.Frontend().SocketStats = mapper.Get(ingtypes.GlobalSocketStats).Bool()
 	//
 	c.buildGlobalAcme(d)
+	c.buildGlobalAdminSocket(d)
 	c.buildGlobalAuthProxy(d)
 	c.buildGlobalBind(d)
+	c.buildGlobalBlockedPaths(d)
+	c.buildGlobalBucket(d)
 	c.buildGlobalCloseSessions(d)
+	c.buildGlobalCookie(d)
 	c.buildGlobalCustomConfig(d)
 	c.buildGlobalDNS(d)
 	c.buildGlobalDynamic(d)
+	c.buildGlobalErrorFiles(d)
 	c.buildGlobalForwardFor(d)
+	c.buildGlobalFrontendMaxConn(d)
+	c.buildGlobalGeoIP(d)
 	c.buildGlobalHTTPStoHTTP(d)
 	c.buildGlobalModSecurity(d)
+	c.buildGlobalNormalizeURI(d)
 	c.buildGlobalPathTypeOrder(d)
+	c.buildGlobalPeers(d)
 	c.buildGlobalProc(d)
 	c.buildSecurity(d)
 	c.buildGlobalSSL(d)
 	c.buildGlobalStats(d)
 	c.buildGlobalSyslog(d)
 	c.buildGlobalTimeout(d)
+	c.buildGlobalTune(d)
+	c.buildGlobalUniqueID(d)
 }
 
 func (c *updater) UpdateTCPPortConfig(tcp *hatypes.TCPServicePort, mapper *Mapper) {
+	tcp.AllowedIP.Rule, tcp.AllowedIP.Exception = c.splitDualCIDR(mapper.Get(ingtypes.TCPTCPServiceAllowlist))
 	tcp.CustomConfig = utils.LineToSlice(mapper.Get(ingtypes.TCPConfigTCPService).Value)
+	tcp.InspectDelay = c.validateTime(mapper.Get(ingtypes.TCPTCPServiceInspectDelay))
 	tcp.LogFormat = mapper.Get(ingtypes.TCPTCPServiceLogFormat).Value
 	tcp.ProxyProt = mapper.Get(ingtypes.TCPTCPServiceProxyProto).Bool()
+	tcp.SetDst = singleLine(mapper.Get(ingtypes.TCPTCPServiceSetDst).Value)
+	tcp.SetDstPort = singleLine(mapper.Get(ingtypes.TCPTCPServiceSetDstPort).Value)
+	tcp.TimeoutClientFin = c.validateTime(mapper.Get(ingtypes.TCPTCPServiceTimeoutClientFin))
 }
 
 func (c *updater) UpdateTCPHostConfig(host *hatypes.TCPServiceHost, mapper *Mapper) {
@@ -199,7 +273,10 @@ func (c *updater) UpdateHostConfig(host *hatypes.Host, mapper *Mapper) {
 	host.TLS.UseDefaultCrt = mapper.Get(ingtypes.HostSSLAlwaysAddHTTPS).Bool()
 	host.VarNamespace = mapper.Get(ingtypes.HostVarNamespace).Bool()
 	c.buildHostAuthTLS(data)
+	c.buildHostBuckets(data)
+	c.buildHostCanonicalPathPrefix(data)
 	c.buildHostCertSigner(data)
+	c.buildHostContentSecurityPolicy(data)
 	c.buildHostRedirect(data)
 	c.buildHostSSLPassthrough(data)
 	c.buildHostTLSConfig(data)
@@ -211,34 +288,58 @@ func (c *updater) UpdateBackendConfig(backend *hatypes.Backend, mapper *Mapper)
 		mapper:  mapper,
 	}
 	// TODO check ModeTCP with HTTP annotations
-	backend.BalanceAlgorithm = mapper.Get(ingtypes.BackBalanceAlgorithm).Value
+	backend.Description = singleLine(mapper.Get(ingtypes.BackBackendDescription).Value)
+	backend.ForwardForDisabled = mapper.Get(ingtypes.BackForwardforDisabled).Bool()
+	backend.IndependentStreams = mapper.Get(ingtypes.BackIndependentStreams).Bool()
 	backend.Server.MaxConn = mapper.Get(ingtypes.BackMaxconnServer).Int()
 	backend.Server.MaxQueue = mapper.Get(ingtypes.BackMaxQueueServer).Int()
+	backend.TCPKeepAlive = mapper.Get(ingtypes.BackServerTCPKeepAlive).Bool()
 	c.buildBackendAffinity(data)
+	c.buildBackendAllowedMethods(data)
 	c.buildBackendAuthExternal(data)
 	c.buildBackendAuthHTTP(data)
+	c.buildBackendBalance(data)
 	c.buildBackendBlueGreenBalance(data)
 	c.buildBackendBlueGreenSelector(data)
 	c.buildBackendBodySize(data)
+	c.buildBackendCache(data)
+	c.buildBackendCanary(data)
+	c.buildBackendConnectionMode(data)
+	c.buildBackendConnectionPool(data)
 	c.buildBackendCors(data)
 	c.buildBackendCustomConfig(data)
 	c.buildBackendDNS(data)
 	c.buildBackendDynamic(data)
 	c.buildBackendAgentCheck(data)
+	c.buildBackendFallback(data)
+	c.buildBackendHeaderMatch(data)
 	c.buildBackendHeaders(data)
+	c.buildBackendHeadersRemove(data)
+	c.buildBackendHeadersReplace(data)
 	c.buildBackendHealthCheck(data)
 	c.buildBackendHSTS(data)
 	c.buildBackendLimit(data)
+	c.buildBackendLogLevel(data)
 	c.buildBackendOAuth(data)
+	c.buildBackendPersist(data)
+	c.buildBackendPriority(data)
 	c.buildBackendProtocol(data)
 	c.buildBackendProxyProtocol(data)
+	c.buildBackendQoS(data)
+	c.buildBackendRedispatch(data)
+	c.buildBackendRequiredHeader(data)
+	c.buildBackendResolveDst(data)
+	c.buildBackendResponseStatus(data)
 	c.buildBackendRewriteURL(data)
 	c.buildBackendServerNaming(data)
 	c.buildBackendSourceAddressIntf(data)
 	c.buildBackendSSL(data)
 	c.buildBackendSSLRedirect(data)
+	c.buildBackendStaticResponse(data)
 	c.buildBackendTimeout(data)
+	c.buildBackendUpstreamHost(data)
 	c.buildBackendWAF(data)
+	c.buildBackendWaitForBody(data)
 	c.buildBackendWhitelistHTTP(data)
 	c.buildBackendWhitelistTCP(data)
 }
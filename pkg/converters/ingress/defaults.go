@@ -30,12 +30,15 @@ const (
 
 func createDefaults() map[string]string {
 	return map[string]string{
-		types.TCPTCPServiceLogFormat: "default",
+		types.TCPTCPServiceInspectDelay: "5s",
+		types.TCPTCPServiceLogFormat:    "default",
 		//
 		types.HostAuthTLSStrict:     "false",
+		types.HostRedirectWWW:       "false",
 		types.HostSSLAlwaysAddHTTPS: "false",
 		types.HostSSLCiphers:        defaultSSLCiphers,
 		types.HostSSLCipherSuites:   defaultSSLCipherSuites,
+		types.HostSSLEarlyData:      "false",
 		types.HostSSLOptionsHost:    "",
 		types.HostTLSALPN:           "h2,http/1.1",
 		//
@@ -47,25 +50,38 @@ func createDefaults() map[string]string {
 		types.BackBackendServerSlotsInc:  "1",
 		types.BackSlotsMinFree:           "6",
 		types.BackBalanceAlgorithm:       "roundrobin",
+		types.BackCacheMaxAge:            "60",
+		types.BackCacheTotalMaxSize:      "4",
+		types.BackCanaryWeight:           "0",
 		types.BackCorsAllowHeaders:       "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Authorization",
 		types.BackCorsAllowMethods:       "GET, PUT, POST, DELETE, PATCH, OPTIONS",
 		types.BackCorsAllowOrigin:        "*",
 		types.BackCorsMaxAge:             "86400",
 		types.BackDynamicScaling:         "true",
+		types.BackForwardforDisabled:     "false",
+		types.BackHealthCheckDisabled:    "false",
 		types.BackHealthCheckInterval:    "2s",
+		types.BackHealthCheckLog:         "false",
 		types.BackHSTS:                   "true",
 		types.BackHSTSIncludeSubdomains:  "false",
 		types.BackHSTSMaxAge:             "15768000",
 		types.BackHSTSPreload:            "false",
+		types.BackInitAddr:               "none",
 		types.BackInitialWeight:          "1",
+		types.BackLimitAction:            "deny",
 		types.BackOAuthHeaders:           "X-Auth-Request-Email",
+		types.BackServerTCPKeepAlive:     "false",
 		types.BackSessionCookieDynamic:   "true",
 		types.BackSessionCookiePreserve:  "false",
 		types.BackSessionCookieValue:     "server-name",
+		types.BackSessionTableExpire:     "30m",
+		types.BackSessionTableSize:       "100k",
 		types.BackSSLRedirect:            "true",
 		types.BackSSLCipherSuitesBackend: defaultSSLCipherSuites,
 		types.BackSSLCiphersBackend:      defaultSSLCiphers,
 		types.BackSSLOptionsBackend:      defaultSSLOptions,
+		types.BackStaticResponseCType:    "text/plain",
+		types.BackStaticResponseStatus:   "200",
 		types.BackTimeoutConnect:         "5s",
 		types.BackTimeoutHTTPRequest:     "5s",
 		types.BackTimeoutKeepAlive:       "1m",
@@ -76,16 +92,21 @@ func createDefaults() map[string]string {
 		types.BackWAFMode:                "deny",
 		//
 		types.GlobalAcmeExpiring:                 "30",
+		types.GlobalAdminSocketMode:              "600",
 		types.GlobalAuthProxy:                    "_front__auth:14415-14499",
+		types.GlobalBucketCount:                  "100",
+		types.GlobalClientTCPKeepAlive:           "false",
 		types.GlobalCookieKey:                    "Ingress",
 		types.GlobalDNSAcceptedPayloadSize:       "8192",
 		types.GlobalDNSClusterDomain:             "cluster.local",
 		types.GlobalDNSHoldObsolete:              "0s",
 		types.GlobalDNSHoldValid:                 "1s",
 		types.GlobalDNSTimeoutRetry:              "1s",
+		types.GlobalDontLogNull:                  "true",
 		types.GlobalDrainSupportRedispatch:       "true",
 		types.GlobalForwardfor:                   "add",
 		types.GlobalHealthzPort:                  "10253",
+		types.GlobalHTTPIgnoreProbes:             "false",
 		types.GlobalHTTPPort:                     "80",
 		types.GlobalHTTPSPort:                    "443",
 		types.GlobalMasterExitOnFailure:          "true",
@@ -103,6 +124,7 @@ func createDefaults() map[string]string {
 		types.GlobalSSLDHDefaultMaxSize:          "2048",
 		types.GlobalSSLHeadersPrefix:             "X-SSL",
 		types.GlobalSSLOptions:                   defaultSSLOptions,
+		types.GlobalSocketStats:                  "false",
 		types.GlobalStatsPort:                  "1936",
 		types.GlobalSyslogFormat:               "rfc5424",
 		types.GlobalSyslogLength:               "1024",
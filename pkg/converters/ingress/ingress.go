@@ -17,6 +17,7 @@ limitations under the License.
 package ingress
 
 import (
+	"crypto/x509"
 	"fmt"
 	"hash/fnv"
 	"reflect"
@@ -35,6 +36,7 @@ import (
 	"github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy"
 	hatypes "github.com/jcmoraisjr/haproxy-ingress/pkg/haproxy/types"
 	"github.com/jcmoraisjr/haproxy-ingress/pkg/types"
+	"github.com/jcmoraisjr/haproxy-ingress/pkg/utils"
 )
 
 // Config ...
@@ -425,6 +427,44 @@ func (c *converter) syncIngressHTTP(source *annotations.Source, ing *networking.
 					c.logger.Warn("skipping http port config of ssl-passthrough on %v: %v", source, err)
 				}
 			}
+			// pre-building the bucket backends
+			for _, bucket := range utils.Split(annHost[ingtypes.HostBucketBackends], ",") {
+				_, _, bucketSvcName, bucketSvcPort, err := ingutils.ParseBucket(bucket)
+				if err != nil {
+					// warn logged by the updater
+					continue
+				}
+				if strings.Index(bucketSvcName, "/") < 0 {
+					bucketSvcName = ing.Namespace + "/" + bucketSvcName
+				}
+				if _, err := c.addBackend(source, pathLink, bucketSvcName, bucketSvcPort, map[string]string{}); err != nil {
+					c.logger.Warn("skipping bucket backend on %v: %v", source, err)
+				}
+			}
+			// pre-building the canary backend
+			if canary := annBack[ingtypes.BackCanaryBackend]; canary != "" {
+				canarySvcName, canarySvcPort, err := ingutils.ParseService(canary)
+				if err == nil {
+					if strings.Index(canarySvcName, "/") < 0 {
+						canarySvcName = ing.Namespace + "/" + canarySvcName
+					}
+					if _, err := c.addBackend(source, pathLink, canarySvcName, canarySvcPort, map[string]string{}); err != nil {
+						c.logger.Warn("skipping canary backend on %v: %v", source, err)
+					}
+				}
+			}
+			// pre-building the fallback backend
+			if fallback := annBack[ingtypes.BackFallbackBackend]; fallback != "" {
+				fallbackSvcName, fallbackSvcPort, err := ingutils.ParseService(fallback)
+				if err == nil {
+					if strings.Index(fallbackSvcName, "/") < 0 {
+						fallbackSvcName = ing.Namespace + "/" + fallbackSvcName
+					}
+					if _, err := c.addBackend(source, pathLink, fallbackSvcName, fallbackSvcPort, map[string]string{}); err != nil {
+						c.logger.Warn("skipping fallback backend on %v: %v", source, err)
+					}
+				}
+			}
 			// pre-building the auth-url backend
 			// TODO move to updater.buildBackendAuthExternal()
 			if url := annBack[ingtypes.BackAuthURL]; url != "" {
@@ -547,7 +587,7 @@ func (c *converter) syncIngressTCP(source *annotations.Source, ing *networking.I
 			c.logger.Warn("skipping TLS of tcp service on %v: backend was not configured", source)
 			return
 		}
-		tlsPath := c.addTLS(source, normalizeHostname(rawHostname, tcpServicePort), secretName)
+		tlsPath := c.addTLS(source, rawHostname, secretName)
 		if tcpPort.TLS.TLSHash == "" {
 			tcpPort.TLS.TLSFilename = tlsPath.Filename
 			tcpPort.TLS.TLSHash = tlsPath.SHA1Hash
@@ -915,6 +955,9 @@ func (c *converter) addTLS(source *annotations.Source, hostname, secretName stri
 			[]convtypes.TrackingRef{{Context: source.Type, UniqueName: source.FullName()}},
 		)
 		if err == nil {
+			if err := verifyHostname(tlsFile.Certificate, hostname); err != nil {
+				c.logger.Warn("certificate of secret '%s' on %s does not cover hostname '%s': %v", secretName, source, hostname, err)
+			}
 			return tlsFile
 		}
 		c.logger.Warn("using default certificate due to an error reading secret '%s' on %s: %v", secretName, source, err)
@@ -922,6 +965,25 @@ func (c *converter) addTLS(source *annotations.Source, hostname, secretName stri
 	return c.defaultCrt
 }
 
+// verifyHostname checks if the certificate covers hostname. Wildcard hostnames
+// should be declared as is in the certificate SAN list. Missing certificate
+// or hostname, e.g. file based certificates or tls entries without hosts,
+// are not verified.
+func verifyHostname(crt *x509.Certificate, hostname string) error {
+	if crt == nil || hostname == "" {
+		return nil
+	}
+	if strings.HasPrefix(hostname, "*.") {
+		for _, name := range crt.DNSNames {
+			if strings.EqualFold(name, hostname) {
+				return nil
+			}
+		}
+		return fmt.Errorf("certificate is valid for %s, not %s", strings.Join(crt.DNSNames, ", "), hostname)
+	}
+	return crt.VerifyHostname(hostname)
+}
+
 func (c *converter) addEndpoints(svc *api.Service, svcPort *api.ServicePort, backend *hatypes.Backend) error {
 	ready, notReady, err := convutils.CreateEndpoints(c.cache, svc, svcPort)
 	if err != nil {
@@ -517,6 +517,69 @@ func TestSyncTLSCustom(t *testing.T) {
     tlsfilename: /tls/default/tls-echo.pem`)
 }
 
+func TestSyncTLSHostnameNotCovered(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.createSvc1Auto()
+	c.createSecretTLS1("default/tls-echo")
+	c.cache.SecretTLSSAN = map[string][]string{
+		"default/tls-echo": {"app.example.com"},
+	}
+	c.Sync(c.createIngTLS1("default/echo", "echo.example.com", "/", "echo:8080", "tls-echo"))
+
+	c.compareConfigFront(`
+- hostname: echo.example.com
+  paths:
+  - path: /
+    backend: default_echo_8080
+  tls:
+    tlsfilename: /tls/default/tls-echo.pem`)
+
+	c.logger.CompareLogging(`
+WARN certificate of secret 'tls-echo' on Ingress 'default/echo' does not cover hostname 'echo.example.com': x509: certificate is valid for app.example.com, not echo.example.com`)
+}
+
+func TestSyncTLSHostnameCovered(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.createSvc1Auto()
+	c.createSecretTLS1("default/tls-echo1")
+	c.createSecretTLS1("default/tls-echo2")
+	c.cache.SecretTLSSAN = map[string][]string{
+		"default/tls-echo1": {"app.example.com", "echo.example.com"},
+		"default/tls-echo2": {"*.example.com"},
+	}
+	c.Sync(
+		c.createIngTLS1("default/echo1", "echo.example.com", "/", "echo:8080", "tls-echo1"),
+		c.createIngTLS1("default/echo2", "'*.example.com'", "/", "echo:8080", "tls-echo2:*.example.com"),
+		c.createIngTLS1("default/echo3", "www.example.com", "/", "echo:8080", "tls-echo2"),
+	)
+
+	c.compareConfigFront(`
+- hostname: '*.example.com'
+  paths:
+  - path: /
+    backend: default_echo_8080
+  tls:
+    tlsfilename: /tls/default/tls-echo2.pem
+- hostname: echo.example.com
+  paths:
+  - path: /
+    backend: default_echo_8080
+  tls:
+    tlsfilename: /tls/default/tls-echo1.pem
+- hostname: www.example.com
+  paths:
+  - path: /
+    backend: default_echo_8080
+  tls:
+    tlsfilename: /tls/default/tls-echo2.pem`)
+
+	c.logger.CompareLogging(``)
+}
+
 func TestSyncRedeclareTLS(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
@@ -2240,6 +2303,76 @@ func TestSyncAnnAuthURL(t *testing.T) {
 	c.logger.CompareLogging(`WARN skipping auth-url on Ingress 'default/echo2': service not found: 'default/authsvc2'`)
 }
 
+func TestSyncAnnCanaryBackend(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.createSvc1("default/echo", "http:8080", "172.17.1.101")
+	c.createSvc1("default/canary1", "http:8080", "172.17.1.110")
+	c.Sync(
+		c.createIng1Ann("default/echo1", "echo1.example.com", "/", "echo:8080",
+			map[string]string{
+				"ingress.kubernetes.io/canary-backend": "canary1:8080",
+				"ingress.kubernetes.io/canary-weight":  "10",
+			}),
+		c.createIng1Ann("default/echo2", "echo2.example.com", "/", "echo:8080",
+			map[string]string{
+				"ingress.kubernetes.io/canary-backend": "canary2:8080",
+				"ingress.kubernetes.io/canary-weight":  "10",
+			}),
+	)
+
+	c.compareConfigBack(`
+- id: default_canary1_8080
+  endpoints:
+  - ip: 172.17.1.110
+    port: 8080
+- id: default_echo_8080
+  endpoints:
+  - ip: 172.17.1.101
+    port: 8080
+- id: system_default_8080
+  endpoints:
+  - ip: 172.17.0.99
+    port: 8080
+`)
+	c.logger.CompareLogging(`WARN skipping canary backend on Ingress 'default/echo2': service not found: 'default/canary2'`)
+}
+
+func TestSyncAnnFallbackBackend(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.createSvc1("default/echo", "http:8080", "172.17.1.101")
+	c.createSvc1("default/fallback1", "http:8080", "172.17.1.110")
+	c.Sync(
+		c.createIng1Ann("default/echo1", "echo1.example.com", "/", "echo:8080",
+			map[string]string{
+				"ingress.kubernetes.io/fallback-backend": "fallback1:8080",
+			}),
+		c.createIng1Ann("default/echo2", "echo2.example.com", "/", "echo:8080",
+			map[string]string{
+				"ingress.kubernetes.io/fallback-backend": "fallback2:8080",
+			}),
+	)
+
+	c.compareConfigBack(`
+- id: default_echo_8080
+  endpoints:
+  - ip: 172.17.1.101
+    port: 8080
+- id: default_fallback1_8080
+  endpoints:
+  - ip: 172.17.1.110
+    port: 8080
+- id: system_default_8080
+  endpoints:
+  - ip: 172.17.0.99
+    port: 8080
+`)
+	c.logger.CompareLogging(`WARN skipping fallback backend on Ingress 'default/echo2': service not found: 'default/fallback2'`)
+}
+
 func TestSyncAnnPassthrough(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
@@ -18,19 +18,29 @@ package types
 
 // TCP Service Annotations
 const (
-	TCPConfigTCPService     = "config-tcp-service"
-	TCPTCPServiceLogFormat  = "tcp-service-log-format"
-	TCPTCPServicePort       = "tcp-service-port"
-	TCPTCPServiceProxyProto = "tcp-service-proxy-protocol"
+	TCPConfigTCPService           = "config-tcp-service"
+	TCPTCPServiceAllowlist        = "tcp-service-allowlist-source-range"
+	TCPTCPServiceInspectDelay     = "tcp-service-inspect-delay"
+	TCPTCPServiceLogFormat        = "tcp-service-log-format"
+	TCPTCPServicePort             = "tcp-service-port"
+	TCPTCPServiceProxyProto       = "tcp-service-proxy-protocol"
+	TCPTCPServiceSetDst           = "tcp-service-set-dst"
+	TCPTCPServiceSetDstPort       = "tcp-service-set-dst-port"
+	TCPTCPServiceTimeoutClientFin = "tcp-service-timeout-client-fin"
 )
 
 var (
 	// AnnTCP ...
 	AnnTCP = map[string]struct{}{
-		TCPConfigTCPService:     {},
-		TCPTCPServiceLogFormat:  {},
-		TCPTCPServicePort:       {},
-		TCPTCPServiceProxyProto: {},
+		TCPConfigTCPService:           {},
+		TCPTCPServiceAllowlist:        {},
+		TCPTCPServiceInspectDelay:     {},
+		TCPTCPServiceLogFormat:        {},
+		TCPTCPServicePort:             {},
+		TCPTCPServiceProxyProto:       {},
+		TCPTCPServiceSetDst:           {},
+		TCPTCPServiceSetDstPort:       {},
+		TCPTCPServiceTimeoutClientFin: {},
 	}
 )
 
@@ -42,14 +52,20 @@ const (
 	HostAuthTLSSecret          = "auth-tls-secret"
 	HostAuthTLSStrict          = "auth-tls-strict"
 	HostAuthTLSVerifyClient    = "auth-tls-verify-client"
+	HostBucketBackends         = "bucket-backends"
+	HostCanonicalPathPrefix    = "canonical-path-prefix"
 	HostCertSigner             = "cert-signer"
+	HostContentSecurityPolicy  = "content-security-policy"
 	HostRedirectFrom           = "redirect-from"
 	HostRedirectFromRegex      = "redirect-from-regex"
+	HostRedirectWWW            = "redirect-www"
 	HostServerAlias            = "server-alias"
 	HostServerAliasRegex       = "server-alias-regex"
 	HostSSLAlwaysAddHTTPS      = "ssl-always-add-https"
 	HostSSLCiphers             = "ssl-ciphers"
 	HostSSLCipherSuites        = "ssl-cipher-suites"
+	HostSSLEarlyData           = "ssl-early-data"
+	HostSSLMinVerHost          = "ssl-min-ver-host"
 	HostSSLOptionsHost         = "ssl-options-host"
 	HostSSLPassthrough         = "ssl-passthrough"
 	HostSSLPassthroughHTTPPort = "ssl-passthrough-http-port"
@@ -66,14 +82,20 @@ var (
 		HostAuthTLSSecret:          {},
 		HostAuthTLSStrict:          {},
 		HostAuthTLSVerifyClient:    {},
+		HostBucketBackends:         {},
+		HostCanonicalPathPrefix:    {},
 		HostCertSigner:             {},
+		HostContentSecurityPolicy:  {},
 		HostServerAlias:            {},
 		HostRedirectFrom:           {},
 		HostRedirectFromRegex:      {},
+		HostRedirectWWW:            {},
 		HostServerAliasRegex:       {},
 		HostSSLAlwaysAddHTTPS:      {},
 		HostSSLCiphers:             {},
 		HostSSLCipherSuites:        {},
+		HostSSLEarlyData:           {},
+		HostSSLMinVerHost:          {},
 		HostSSLOptionsHost:         {},
 		HostSSLPassthrough:         {},
 		HostSSLPassthroughHTTPPort: {},
@@ -89,6 +111,8 @@ const (
 	BackAgentCheckInterval     = "agent-check-interval"
 	BackAgentCheckPort         = "agent-check-port"
 	BackAgentCheckSend         = "agent-check-send"
+	BackAllowedMethods         = "allowed-methods"
+	BackAllowlistDenyStatus    = "allowlist-deny-status"
 	BackAllowlistSourceRange   = "allowlist-source-range"
 	BackAllowlistSourceHeader  = "allowlist-source-header"
 	BackAssignBackendServerID  = "assign-backend-server-id"
@@ -102,15 +126,25 @@ const (
 	BackAuthMethod             = "auth-method"
 	BackAuthURL                = "auth-url"
 	BackBackendCheckInterval   = "backend-check-interval"
+	BackBackendDescription     = "backend-description"
 	BackBackendProtocol        = "backend-protocol"
 	BackBackendServerNaming    = "backend-server-naming"
 	BackBackendServerSlotsInc  = "backend-server-slots-increment"
 	BackBalanceAlgorithm       = "balance-algorithm"
+	BackBalanceRandomDraws     = "balance-random-draws"
+	BackBalanceURIDepth        = "balance-uri-depth"
+	BackBalanceURILen          = "balance-uri-len"
 	BackBlueGreenBalance       = "blue-green-balance"
 	BackBlueGreenCookie        = "blue-green-cookie"
 	BackBlueGreenDeploy        = "blue-green-deploy"
 	BackBlueGreenHeader        = "blue-green-header"
 	BackBlueGreenMode          = "blue-green-mode"
+	BackCacheMaxAge            = "cache-max-age"
+	BackCacheMaxObjectSize     = "cache-max-object-size"
+	BackCacheName              = "cache-name"
+	BackCacheTotalMaxSize      = "cache-total-max-size"
+	BackCanaryBackend          = "canary-backend"
+	BackCanaryWeight           = "canary-weight"
 	BackConfigBackend          = "config-backend"
 	BackCorsAllowCredentials   = "cors-allow-credentials"
 	BackCorsAllowHeaders       = "cors-allow-headers"
@@ -119,32 +153,75 @@ const (
 	BackCorsEnable             = "cors-enable"
 	BackCorsExposeHeaders      = "cors-expose-headers"
 	BackCorsMaxAge             = "cors-max-age"
+	BackDenylistDenyStatus     = "denylist-deny-status"
 	BackDenylistSourceRange    = "denylist-source-range"
+	BackDNSSRVPort             = "dns-srv-port"
 	BackDynamicScaling         = "dynamic-scaling"
+	BackFallbackBackend        = "fallback-backend"
+	BackFastCGIDocRoot         = "fcgi-docroot"
+	BackFastCGIIndex           = "fcgi-index"
+	BackFastCGIPathInfo        = "fcgi-path-info"
+	BackForwardforDisabled     = "forwardfor-disabled"
+	BackHashType               = "hash-type"
+	BackHeaderMatch            = "header-match"
+	BackHeaderMatchRegex       = "header-match-regex"
 	BackHeaders                = "headers"
+	BackHeadersReplace         = "headers-replace"
+	BackHeadersResponseRemove  = "headers-response-remove"
 	BackHealthCheckAddr        = "health-check-addr"
+	BackHealthCheckDisabled    = "health-check-disabled"
 	BackHealthCheckFallCount   = "health-check-fall-count"
 	BackHealthCheckInterval    = "health-check-interval"
+	BackHealthCheckLog         = "health-check-log"
 	BackHealthCheckPort        = "health-check-port"
 	BackHealthCheckRiseCount   = "health-check-rise-count"
+	BackHealthCheckSNI         = "health-check-sni"
+	BackHealthCheckSSLMode     = "health-check-ssl-mode"
 	BackHealthCheckURI         = "health-check-uri"
 	BackHSTS                   = "hsts"
 	BackHSTSIncludeSubdomains  = "hsts-include-subdomains"
 	BackHSTSMaxAge             = "hsts-max-age"
 	BackHSTSPreload            = "hsts-preload"
+	BackHTTPConnectionMode     = "http-connection-mode"
+	BackIndependentStreams     = "independent-streams"
+	BackInitAddr               = "init-addr"
 	BackInitialWeight          = "initial-weight"
+	BackLimitAction            = "limit-action"
 	BackLimitConnections       = "limit-connections"
+	BackLimitDenyStatus        = "limit-deny-status"
+	BackLimitGPC0Increment     = "limit-gpc0-increment"
+	BackLimitGPC0Threshold     = "limit-gpc0-threshold"
+	BackLimitPathRPS           = "limit-path-rps"
 	BackLimitRPS               = "limit-rps"
+	BackLimitSessionKey        = "limit-session-key"
+	BackLimitSessionRPS        = "limit-session-rps"
 	BackLimitWhitelist         = "limit-whitelist"
+	BackLogLevel               = "log-level"
 	BackMaxconnServer          = "maxconn-server"
 	BackMaxQueueServer         = "maxqueue-server"
 	BackOAuth                  = "oauth"
 	BackOAuthHeaders           = "oauth-headers"
 	BackOAuthURIPrefix         = "oauth-uri-prefix"
 	BackPathType               = "path-type"
+	BackPersist                = "persist"
+	BackPersistForce           = "persist-force"
+	BackPersistIgnore          = "persist-ignore"
+	BackPoolMaxConn            = "pool-max-conn"
+	BackPoolPurgeDelay         = "pool-purge-delay"
+	BackPriorityClass          = "priority-class"
+	BackPriorityOffset         = "priority-offset"
 	BackProxyBodySize          = "proxy-body-size"
 	BackProxyProtocol          = "proxy-protocol"
+	BackQoSMark                = "qos-mark"
+	BackQoSTOS                 = "qos-tos"
 	BackRedirectTo             = "redirect-to"
+	BackRedispatchInterval     = "redispatch-interval"
+	BackRequiredHeader         = "required-header"
+	BackResolveDstDomains      = "resolve-dst-domains"
+	BackResolveDstHeader       = "resolve-dst-header"
+	BackResolveDstResolver     = "resolve-dst-resolver"
+	BackResponseSetStatus      = "response-set-status"
+	BackRetries                = "retries"
 	BackRewriteTarget          = "rewrite-target"
 	BackSlotsMinFree           = "slots-min-free"
 	BackSecureBackends         = "secure-backends"
@@ -152,6 +229,7 @@ const (
 	BackSecureSNI              = "secure-sni"
 	BackSecureVerifyCASecret   = "secure-verify-ca-secret"
 	BackSecureVerifyHostname   = "secure-verify-hostname"
+	BackServerTCPKeepAlive     = "server-tcp-keepalive"
 	BackServiceUpstream        = "service-upstream"
 	BackSessionCookieDynamic   = "session-cookie-dynamic"
 	BackSessionCookieKeywords  = "session-cookie-keywords"
@@ -161,22 +239,33 @@ const (
 	BackSessionCookieShared    = "session-cookie-shared"
 	BackSessionCookieStrategy  = "session-cookie-strategy"
 	BackSessionCookieValue     = "session-cookie-value-strategy"
+	BackSessionTableExpire     = "session-table-expire"
+	BackSessionTableSize       = "session-table-size"
 	BackSourceAddressIntf      = "source-address-intf"
 	BackSSLCipherSuitesBackend = "ssl-cipher-suites-backend"
 	BackSSLCiphersBackend      = "ssl-ciphers-backend"
 	BackSSLFingerprintLower    = "ssl-fingerprint-lower"
 	BackSSLOptionsBackend      = "ssl-options-backend"
 	BackSSLRedirect            = "ssl-redirect"
+	BackStaticResponse         = "static-response"
+	BackStaticResponseCType    = "static-response-content-type"
+	BackStaticResponseFile     = "static-response-file"
+	BackStaticResponseStatus   = "static-response-status"
+	BackTimeoutCheck           = "timeout-check"
 	BackTimeoutConnect         = "timeout-connect"
 	BackTimeoutHTTPRequest     = "timeout-http-request"
 	BackTimeoutKeepAlive       = "timeout-keep-alive"
 	BackTimeoutQueue           = "timeout-queue"
 	BackTimeoutServer          = "timeout-server"
 	BackTimeoutServerFin       = "timeout-server-fin"
+	BackTimeoutTarpit          = "timeout-tarpit"
 	BackTimeoutTunnel          = "timeout-tunnel"
+	BackUpstreamVhost          = "upstream-vhost"
 	BackUseResolver            = "use-resolver"
 	BackWAF                    = "waf"
 	BackWAFMode                = "waf-mode"
+	BackWaitForBodyAtLeast     = "wait-for-body-at-least"
+	BackWaitForBodyTime        = "wait-for-body-time"
 	BackWhitelistSourceRange   = "whitelist-source-range"
 )
 
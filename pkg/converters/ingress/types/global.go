@@ -23,6 +23,9 @@ const (
 	GlobalAcmeExpiring                 = "acme-expiring"
 	GlobalAcmeShared                   = "acme-shared"
 	GlobalAcmeTermsAgreed              = "acme-terms-agreed"
+	GlobalAdminSocketGroup             = "admin-socket-group"
+	GlobalAdminSocketMode              = "admin-socket-mode"
+	GlobalAdminSocketUser              = "admin-socket-user"
 	GlobalAuthLogFormat                = "auth-log-format"
 	GlobalAuthProxy                    = "auth-proxy"
 	GlobalBindFrontingProxy            = "bind-fronting-proxy"
@@ -33,6 +36,10 @@ const (
 	GlobalBindIPAddrPrometheus         = "bind-ip-addr-prometheus"
 	GlobalBindIPAddrStats              = "bind-ip-addr-stats"
 	GlobalBindIPAddrTCP                = "bind-ip-addr-tcp"
+	GlobalBlockedPaths                 = "blocked-paths"
+	GlobalBucketCookie                 = "bucket-cookie"
+	GlobalBucketCount                  = "bucket-count"
+	GlobalClientTCPKeepAlive           = "client-tcp-keepalive"
 	GlobalCloseSessionsDuration        = "close-sessions-duration"
 	GlobalConfigDefaults               = "config-defaults"
 	GlobalConfigFrontend               = "config-frontend"
@@ -50,17 +57,31 @@ const (
 	GlobalDefaultBackendRedirectCode   = "default-backend-redirect-code"
 	GlobalDNSAcceptedPayloadSize       = "dns-accepted-payload-size"
 	GlobalDNSClusterDomain             = "dns-cluster-domain"
+	GlobalDNSHoldNX                    = "dns-hold-nx"
 	GlobalDNSHoldObsolete              = "dns-hold-obsolete"
+	GlobalDNSHoldOther                 = "dns-hold-other"
+	GlobalDNSHoldRefused               = "dns-hold-refused"
+	GlobalDNSHoldTimeout               = "dns-hold-timeout"
 	GlobalDNSHoldValid                 = "dns-hold-valid"
+	GlobalDNSResolveRetries            = "dns-resolve-retries"
 	GlobalDNSResolvers                 = "dns-resolvers"
+	GlobalDNSTimeoutResolve            = "dns-timeout-resolve"
 	GlobalDNSTimeoutRetry              = "dns-timeout-retry"
+	GlobalDontLogNull                  = "dontlognull"
 	GlobalDrainSupport                 = "drain-support"
 	GlobalDrainSupportRedispatch       = "drain-support-redispatch"
+	GlobalErrorFiles                   = "error-files"
 	GlobalExternalHasLua               = "external-has-lua"
 	GlobalForwardfor                   = "forwardfor"
+	GlobalFrontendDescription          = "frontend-description"
+	GlobalFrontendMaxConn              = "frontend-maxconn"
 	GlobalFrontingProxyPort            = "fronting-proxy-port"
+	GlobalGeoIPActionMap               = "geoip-action-map"
+	GlobalGeoIPCountryMap              = "geoip-country-map"
 	GlobalGroupname                    = "groupname"
 	GlobalHealthzPort                  = "healthz-port"
+	GlobalHTTP2MaxConcurrentStreams    = "http2-max-concurrent-streams"
+	GlobalHTTPIgnoreProbes             = "http-ignore-probes"
 	GlobalHTTPLogFormat                = "http-log-format"
 	GlobalHTTPPort                     = "http-port"
 	GlobalHTTPSLogFormat               = "https-log-format"
@@ -68,7 +89,10 @@ const (
 	GlobalHTTPStoHTTPPort              = "https-to-http-port"
 	GlobalLoadServerState              = "load-server-state"
 	GlobalMasterExitOnFailure          = "master-exit-on-failure"
+	GlobalMaxAccept                    = "max-accept"
+	GlobalMaxConnectionRate            = "max-connection-rate"
 	GlobalMaxConnections               = "max-connections"
+	GlobalMaxSessionRate               = "max-session-rate"
 	GlobalModsecurityEndpoints         = "modsecurity-endpoints"
 	GlobalModsecurityTimeoutConnect    = "modsecurity-timeout-connect"
 	GlobalModsecurityTimeoutHello      = "modsecurity-timeout-hello"
@@ -79,7 +103,9 @@ const (
 	GlobalNbprocSSL                    = "nbproc-ssl"
 	GlobalNbthread                     = "nbthread"
 	GlobalNoTLSRedirectLocations       = "no-tls-redirect-locations"
+	GlobalNormalizeURI                 = "normalize-uri"
 	GlobalPathTypeOrder                = "path-type-order"
+	GlobalPeers                        = "peers"
 	GlobalUsername                     = "username"
 	GlobalPrometheusPort               = "prometheus-port"
 	GlobalRedirectFromCode             = "redirect-from-code"
@@ -88,9 +114,13 @@ const (
 	GlobalSSLDHParam                   = "ssl-dh-param"
 	GlobalSSLEngine                    = "ssl-engine"
 	GlobalSSLHeadersPrefix             = "ssl-headers-prefix"
+	GlobalSSLMinVer                    = "ssl-min-ver"
 	GlobalSSLModeAsync                 = "ssl-mode-async"
 	GlobalSSLOptions                   = "ssl-options"
 	GlobalSSLRedirectCode              = "ssl-redirect-code"
+	GlobalSSLSessionCacheSize          = "ssl-session-cache-size"
+	GlobalSSLSessionLifetime           = "ssl-session-lifetime"
+	GlobalSocketStats                  = "socket-stats"
 	GlobalStatsAuth                    = "stats-auth"
 	GlobalStatsPort                    = "stats-port"
 	GlobalStatsProxyProtocol           = "stats-proxy-protocol"
@@ -99,11 +129,15 @@ const (
 	GlobalSyslogEndpoint               = "syslog-endpoint"
 	GlobalSyslogFormat                 = "syslog-format"
 	GlobalSyslogLength                 = "syslog-length"
+	GlobalSyslogRing                   = "syslog-ring"
+	GlobalSyslogRingSize               = "syslog-ring-size"
 	GlobalSyslogTag                    = "syslog-tag"
 	GlobalTCPLogFormat                 = "tcp-log-format"
 	GlobalTimeoutClient                = "timeout-client"
 	GlobalTimeoutClientFin             = "timeout-client-fin"
 	GlobalTimeoutStop                  = "timeout-stop"
+	GlobalUniqueIDFormat               = "unique-id-format"
+	GlobalUniqueIDHeader               = "unique-id-header"
 	GlobalUseChroot                    = "use-chroot"
 	GlobalUseCPUMap                    = "use-cpu-map"
 	GlobalUseForwardedProto            = "use-forwarded-proto"
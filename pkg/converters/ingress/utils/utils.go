@@ -19,6 +19,7 @@ package utils
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -54,3 +55,42 @@ func ParseURL(url string) (urlProto, urlHost, urlPort, urlPath string, err error
 	}
 	return
 }
+
+var parseBucketRegex = regexp.MustCompile(`^([0-9]+)(-([0-9]+))?=([-a-z0-9]+/)?([-a-z0-9.]+):([-a-z0-9]+)$`)
+
+// ParseBucket ...
+func ParseBucket(bucket string) (first, last int, svcName, svcPort string, err error) {
+	bucketParse := parseBucketRegex.FindStringSubmatch(bucket)
+	if len(bucketParse) < 7 {
+		err = fmt.Errorf("invalid bucket syntax: %s", bucket)
+		return
+	}
+	// <first>[-<last>]=[<namespace>/]<name>:<port>
+	first, _ = strconv.Atoi(bucketParse[1])
+	last = first
+	if bucketParse[3] != "" {
+		last, _ = strconv.Atoi(bucketParse[3])
+	}
+	if last < first {
+		err = fmt.Errorf("invalid bucket range: %s", bucket)
+		return 0, 0, "", "", err
+	}
+	svcName = bucketParse[4] + bucketParse[5]
+	svcPort = bucketParse[6]
+	return
+}
+
+var parseServiceRegex = regexp.MustCompile(`^([-a-z0-9]+/)?([-a-z0-9.]+):([-a-z0-9]+)$`)
+
+// ParseService ...
+func ParseService(service string) (svcName, svcPort string, err error) {
+	serviceParse := parseServiceRegex.FindStringSubmatch(service)
+	if len(serviceParse) < 4 {
+		err = fmt.Errorf("invalid service syntax: %s", service)
+		return
+	}
+	// [<namespace>/]<name>:<port>
+	svcName = serviceParse[1] + serviceParse[2]
+	svcPort = serviceParse[3]
+	return
+}
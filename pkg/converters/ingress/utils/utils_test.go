@@ -126,3 +126,105 @@ func TestParseURL(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBucket(t *testing.T) {
+	testCases := []struct {
+		bucket string
+		exp    string
+		err    string
+	}{
+		// 0
+		{
+			bucket: "0=app:8080",
+			exp:    "0 | 0 | app | 8080",
+		},
+		// 1
+		{
+			bucket: "0-49=app-v1:http",
+			exp:    "0 | 49 | app-v1 | http",
+		},
+		// 2
+		{
+			bucket: "50-99=ns/app-v2:8080",
+			exp:    "50 | 99 | ns/app-v2 | 8080",
+		},
+		// 3
+		{
+			bucket: "10-5=app:8080",
+			err:    "invalid bucket range: 10-5=app:8080",
+		},
+		// 4
+		{
+			bucket: "0-9=app",
+			err:    "invalid bucket syntax: 0-9=app",
+		},
+		// 5
+		{
+			bucket: "a=app:8080",
+			err:    "invalid bucket syntax: a=app:8080",
+		},
+	}
+	for i, test := range testCases {
+		first, last, name, port, err := ParseBucket(test.bucket)
+		actual := fmt.Sprintf("%d | %d | %s | %s", first, last, name, port)
+		if test.exp == "" {
+			test.exp = "0 | 0 |  | "
+		}
+		if actual != test.exp {
+			t.Errorf("expected '%s' on %d, but was '%s'", test.exp, i, actual)
+		}
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("expected error '%s' on %d, but was '%s'", test.err, i, err.Error())
+			}
+		} else if test.err != "" {
+			t.Errorf("expected error '%s' on %d, but there was no error", test.err, i)
+		}
+	}
+}
+
+func TestParseService(t *testing.T) {
+	testCases := []struct {
+		service string
+		exp     string
+		err     string
+	}{
+		// 0
+		{
+			service: "app:8080",
+			exp:     "app | 8080",
+		},
+		// 1
+		{
+			service: "ns/app-v2:http",
+			exp:     "ns/app-v2 | http",
+		},
+		// 2
+		{
+			service: "app",
+			err:     "invalid service syntax: app",
+		},
+		// 3
+		{
+			service: "ns/app:",
+			err:     "invalid service syntax: ns/app:",
+		},
+	}
+	for i, test := range testCases {
+		name, port, err := ParseService(test.service)
+		actual := fmt.Sprintf("%s | %s", name, port)
+		if test.exp == "" {
+			test.exp = " | "
+		}
+		if actual != test.exp {
+			t.Errorf("expected '%s' on %d, but was '%s'", test.exp, i, actual)
+		}
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("expected error '%s' on %d, but was '%s'", test.err, i, err.Error())
+			}
+		} else if test.err != "" {
+			t.Errorf("expected error '%s' on %d, but there was no error", test.err, i)
+		}
+	}
+}
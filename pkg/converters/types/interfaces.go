@@ -17,6 +17,7 @@ limitations under the License.
 package types
 
 import (
+	"crypto/x509"
 	"net"
 	"time"
 
@@ -151,8 +152,9 @@ type File struct {
 
 // CrtFile ...
 type CrtFile struct {
-	Filename   string
-	SHA1Hash   string
-	CommonName string
-	NotAfter   time.Time
+	Filename    string
+	SHA1Hash    string
+	CommonName  string
+	NotAfter    time.Time
+	Certificate *x509.Certificate
 }
@@ -177,8 +177,13 @@ func (c *config) WriteFrontendMaps() error {
 		HTTPSSNIMap:  mapBuilder.AddMap(mapsDir + "/_front_https_sni.map"),
 		//
 		RedirFromRootMap:  mapBuilder.AddMap(mapsDir + "/_front_redir_fromroot.map"),
+		RedirPathPfxMap:   mapBuilder.AddMap(mapsDir + "/_front_redir_pathprefix.map"),
 		RedirFromMap:      mapBuilder.AddMap(mapsDir + "/_front_redir_from.map"),
 		RedirToMap:        mapBuilder.AddMap(mapsDir + "/_front_redir_to.map"),
+		RedirToApexList:   mapBuilder.AddMap(mapsDir + "/_front_redir_toapex.list"),
+		RedirToWWWList:    mapBuilder.AddMap(mapsDir + "/_front_redir_towww.list"),
+		BucketMap:         mapBuilder.AddMap(mapsDir + "/_front_bucket.map"),
+		CSPMap:            mapBuilder.AddMap(mapsDir + "/_front_csp.map"),
 		SSLPassthroughMap: mapBuilder.AddMap(mapsDir + "/_front_sslpassthrough.map"),
 		VarNamespaceMap:   mapBuilder.AddMap(mapsDir + "/_front_namespace.map"),
 		//
@@ -192,6 +197,9 @@ func (c *config) WriteFrontendMaps() error {
 	// TODO crtList* to be removed after implement a template to the crt list
 	c.frontend.CrtListFile = mapsDir + "/_front_bind_crt.list"
 	var crtListItems []*hatypes.HostsMapEntry
+	// the first crt-list entry is the one haproxy uses when the SNI extension is
+	// missing or doesn't match any other entry; `!*` prevents it from being
+	// selected by its own CN/SAN, so it's only used as the default certificate
 	crtListItems = append(crtListItems, &hatypes.HostsMapEntry{Key: c.frontend.DefaultCrtFile + " !*"})
 	hasVarNamespace := c.hosts.HasVarNamespace()
 	defaultHost := c.hosts.DefaultHost()
@@ -200,11 +208,46 @@ func (c *config) WriteFrontendMaps() error {
 			fmaps.DefaultHostMap.AddHostnamePathMapping("", path, path.Backend.ID)
 		}
 	}
+	var canaryRoutes []*hatypes.CanaryRoute
+	var fallbackRoutes []*hatypes.FallbackRoute
+	var headerRoutes []*hatypes.HeaderRoute
 	for _, host := range c.hosts.BuildSortedItems() {
 		for _, path := range host.Paths {
 			backendID := path.Backend.ID
+			backendPath := c.findBackendPath(host, path)
+			if backendPath != nil && backendPath.Canary.Backend != "" && backendPath.HeaderMatch.Name == "" && !host.SSLPassthrough() {
+				// the frontend moves a percentage of the requests of the path
+				// to the canary backend, paths with header match use their own
+				// use_backend and are not covered
+				canaryRoutes = append(canaryRoutes, &hatypes.CanaryRoute{
+					Backend:   backendID,
+					Canary:    backendPath.Canary.Backend,
+					Weight:    backendPath.Canary.Weight,
+					BaseRegex: hatypes.HostPathRegex(host.Hostname, path),
+				})
+			}
+			if backendPath != nil && backendPath.FallbackBackend != "" && backendPath.HeaderMatch.Name == "" && !host.SSLPassthrough() {
+				// the frontend changes the chosen backend if it doesn't have
+				// any server available, paths with header match use their own
+				// use_backend and are not covered
+				fallbackRoutes = append(fallbackRoutes, &hatypes.FallbackRoute{
+					Backend:   backendID,
+					Fallback:  backendPath.FallbackBackend,
+					BaseRegex: hatypes.HostPathRegex(host.Hostname, path),
+				})
+			}
 			// IMPLEMENT check if host.Alias.AliasName was already used as a hostname
-			if backendID != "" {
+			if backendPath != nil && backendPath.HeaderMatch.Name != "" {
+				// paths with header match are routed via acl and use_backend,
+				// so requests without the header don't reach the backend
+				headerRoutes = append(headerRoutes, &hatypes.HeaderRoute{
+					ID:        fmt.Sprintf("header_match%02d", len(headerRoutes)+1),
+					Backend:   backendID,
+					BaseRegex: hatypes.HostPathRegex(host.Hostname, path),
+					HTTPS:     host.HasTLS(),
+					Match:     backendPath.HeaderMatch,
+				})
+			} else if backendID != "" {
 				if host.SSLPassthrough() {
 					// no ssl offload, cannot inspect incomming path, so tracking root only
 					if path.Path == "/" {
@@ -247,12 +290,26 @@ func (c *config) WriteFrontendMaps() error {
 		if host.SSLPassthrough() {
 			continue
 		}
+		for _, bucket := range host.Buckets {
+			for i := bucket.First; i <= bucket.Last; i++ {
+				fmaps.BucketMap.AddHostnameMapping(fmt.Sprintf("%s#%d", host.Hostname, i), bucket.Backend)
+			}
+		}
 		if host.Redirect.RedirectHost != "" {
 			fmaps.RedirFromMap.AddHostnameMapping(host.Redirect.RedirectHost, host.Hostname)
 		}
 		if host.Redirect.RedirectHostRegex != "" {
 			fmaps.RedirFromMap.AddHostnameMappingRegex(host.Redirect.RedirectHostRegex, host.Hostname)
 		}
+		if host.Redirect.WWW {
+			// the hostname is the canonical form, the list has
+			// the other form, which should be redirected to it
+			if apex := strings.TrimPrefix(host.Hostname, "www."); apex != host.Hostname {
+				fmaps.RedirToWWWList.AddHostnameMapping(apex, "")
+			} else {
+				fmaps.RedirToApexList.AddHostnameMapping("www."+host.Hostname, "")
+			}
+		}
 		if host.HasTLSAuth() {
 			fmaps.TLSAuthList.AddHostnameMapping(host.Hostname, "")
 			if !host.TLS.CAVerifyOptional {
@@ -271,6 +328,12 @@ func (c *config) WriteFrontendMaps() error {
 		if host.RootRedirect != "" {
 			fmaps.RedirFromRootMap.AddHostnameMapping(host.Hostname, host.RootRedirect)
 		}
+		if host.CanonicalPathPrefix != "" {
+			fmaps.RedirPathPfxMap.AddHostnameMapping(host.Hostname, host.CanonicalPathPrefix)
+		}
+		if host.ContentSecurityPolicy != "" {
+			fmaps.CSPMap.AddHostnameMapping(host.Hostname, host.ContentSecurityPolicy)
+		}
 		//
 		tls := host.TLS
 		crtFile := tls.TLSFilename
@@ -282,6 +345,8 @@ func (c *config) WriteFrontendMaps() error {
 			tls.CAFilename != "" ||
 			tls.Ciphers != "" ||
 			tls.CipherSuites != "" ||
+			tls.EarlyData ||
+			tls.MinVersion != "" ||
 			tls.Options != "" {
 			// has custom tls config
 			//
@@ -304,6 +369,12 @@ func (c *config) WriteFrontendMaps() error {
 			if tls.CipherSuites != "" {
 				bindConf = append(bindConf, "ciphersuites", tls.CipherSuites)
 			}
+			if tls.EarlyData {
+				bindConf = append(bindConf, "allow-0rtt")
+			}
+			if tls.MinVersion != "" {
+				bindConf = append(bindConf, "ssl-min-ver", tls.MinVersion)
+			}
 			if tls.Options != "" {
 				bindConf = append(bindConf, tls.Options)
 			}
@@ -320,13 +391,45 @@ func (c *config) WriteFrontendMaps() error {
 	if err := c.options.mapsTemplate.WriteOutput(crtListItems, c.frontend.CrtListFile); err != nil {
 		return err
 	}
+	c.frontend.BlockedPathsFile = ""
+	if len(c.frontend.BlockedPaths) > 0 {
+		blockedItems := make([]*hatypes.HostsMapEntry, len(c.frontend.BlockedPaths))
+		for i, path := range c.frontend.BlockedPaths {
+			// "/.git/" matches "/.git" and "/.git/config" on the path with a trailing
+			// slash, but not "/.github"
+			if !strings.HasSuffix(path, "/") {
+				path += "/"
+			}
+			blockedItems[i] = &hatypes.HostsMapEntry{Key: path}
+		}
+		c.frontend.BlockedPathsFile = mapsDir + "/_front_blocked_paths.list"
+		if err := c.options.mapsTemplate.WriteOutput(blockedItems, c.frontend.BlockedPathsFile); err != nil {
+			return err
+		}
+	}
 	if err := writeMaps(mapBuilder, c.options.mapsTemplate); err != nil {
 		return err
 	}
 	c.frontend.Maps = fmaps
+	c.frontend.CanaryRoutes = canaryRoutes
+	c.frontend.FallbackRoutes = fallbackRoutes
+	c.frontend.HeaderRoutes = headerRoutes
 	return nil
 }
 
+// findBackendPath finds the backend path of a http host path, so the frontend
+// can be configured based on path scoped configurations of the backends.
+func (c *config) findBackendPath(host *hatypes.Host, path *hatypes.HostPath) *hatypes.BackendPath {
+	if path.Backend.ID == "" || host.SSLPassthrough() {
+		return nil
+	}
+	backend := c.backends.FindBackend(path.Backend.Namespace, path.Backend.Name, path.Backend.Port)
+	if backend == nil {
+		return nil
+	}
+	return backend.FindBackendPath(path.Link)
+}
+
 // WriteBackendMaps reads the model and writes haproxy's maps
 // used in the backends. Should be called before write the main
 // config file. This func doesn't change model state, except the
@@ -17,13 +17,18 @@ limitations under the License.
 package haproxy
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jcmoraisjr/haproxy-ingress/pkg/acme"
@@ -39,6 +44,7 @@ type InstanceOptions struct {
 	AcmeSigner        acme.Signer
 	AcmeQueue         utils.Queue
 	BackendShards     int
+	ConfigTimestamp   bool
 	HAProxyCfgDir     string
 	HAProxyMapsDir    string
 	LeaderElector     types.LeaderElector
@@ -46,12 +52,20 @@ type InstanceOptions struct {
 	AdminSocket       string
 	MaxOldConfigFiles int
 	Metrics           types.Metrics
+	ReloadEnv         []string
 	ReloadQueue       utils.Queue
 	ReloadStrategy    string
+	ReloadWorkDir     string
+	ShutdownCmd       string
 	SortEndpointsBy   string
 	StopCh            chan struct{}
+	SyncReload        bool
+	TemplatesDir      string
 	TrackInstances    bool
+	UpdateLocker      sync.Locker
+	UpdateWindow      time.Duration
 	ValidateConfig    bool
+	Version           string
 	// TODO Fake is used to skip real haproxy calls. Use a mock instead.
 	fake bool
 }
@@ -62,19 +76,23 @@ type Instance interface {
 	ParseTemplates() error
 	Config() Config
 	CalcIdleMetric()
-	Update(timer *utils.Timer)
-	Reload(timer *utils.Timer)
+	Update(timer *utils.Timer) error
+	Reload(timer *utils.Timer) error
+	Shutdown(ctx context.Context) error
 }
 
 // CreateInstance ...
 func CreateInstance(logger types.Logger, options InstanceOptions) Instance {
+	haproxyTmpl := template.CreateConfig()
+	haproxyTmpl.SetVersionHeader(options.Version, options.ConfigTimestamp)
 	return &instance{
 		logger:      logger,
 		options:     &options,
-		haproxyTmpl: template.CreateConfig(),
+		haproxyTmpl: haproxyTmpl,
 		mapsTmpl:    template.CreateConfig(),
 		modsecTmpl:  template.CreateConfig(),
 		conns:       newConnections(options.MasterSocket, options.AdminSocket),
+		reloadCmd:   "/haproxy-reload.sh",
 		metrics:     options.Metrics,
 	}
 }
@@ -90,6 +108,10 @@ type instance struct {
 	config      Config
 	conns       *connections
 	metrics     types.Metrics
+	reloadCmd   string
+	updateMutex sync.Mutex
+	updatePend  *time.Timer
+	updateTimer *utils.Timer
 }
 
 func (i *instance) AcmeCheck(source string) (int, error) {
@@ -147,12 +169,16 @@ func (i *instance) acmeRemoveStorage(storage string) {
 }
 
 func (i *instance) ParseTemplates() error {
+	templatesDir := i.options.TemplatesDir
+	if templatesDir == "" {
+		templatesDir = "/etc/templates"
+	}
 	i.haproxyTmpl.ClearTemplates()
 	i.mapsTmpl.ClearTemplates()
 	i.modsecTmpl.ClearTemplates()
 	if err := i.modsecTmpl.NewTemplate(
 		"modsecurity.tmpl",
-		"/etc/templates/modsecurity/modsecurity.tmpl",
+		filepath.Join(templatesDir, "modsecurity/modsecurity.tmpl"),
 		"/etc/haproxy/spoe-modsecurity.conf",
 		0,
 		1024,
@@ -161,7 +187,7 @@ func (i *instance) ParseTemplates() error {
 	}
 	if err := i.haproxyTmpl.NewTemplate(
 		"haproxy.tmpl",
-		"/etc/templates/haproxy/haproxy.tmpl",
+		filepath.Join(templatesDir, "haproxy/haproxy.tmpl"),
 		"/etc/haproxy/haproxy.cfg",
 		i.options.MaxOldConfigFiles,
 		16384,
@@ -170,7 +196,7 @@ func (i *instance) ParseTemplates() error {
 	}
 	err := i.mapsTmpl.NewTemplate(
 		"map.tmpl",
-		"/etc/templates/map/map.tmpl",
+		filepath.Join(templatesDir, "map/map.tmpl"),
 		"",
 		0,
 		2048,
@@ -213,9 +239,48 @@ func (i *instance) CalcIdleMetric() {
 	i.metrics.AddIdleFactor(idle)
 }
 
-func (i *instance) Update(timer *utils.Timer) {
-	i.acmeUpdate()
-	i.haproxyUpdate(timer)
+// Update applies the current config state, either reloading haproxy or
+// dynamically updating it. The update is scheduled if an update window
+// is configured and a reload is enqueued if a reload queue is configured,
+// a nil error is returned in both cases. SyncReload enforces a synchronous
+// update and reload, so any failure is returned to the caller.
+func (i *instance) Update(timer *utils.Timer) error {
+	if i.options.SyncReload || i.options.UpdateWindow <= 0 {
+		i.acmeUpdate()
+		return i.haproxyUpdate(timer)
+	}
+	i.updateMutex.Lock()
+	defer i.updateMutex.Unlock()
+	i.updateTimer = timer
+	if i.updatePend != nil {
+		// an update is already scheduled and will use the last config state
+		i.logger.InfoV(2, "coalescing update, already scheduled")
+		return nil
+	}
+	i.updatePend = time.AfterFunc(i.options.UpdateWindow, func() {
+		// UpdateLocker, if configured, should be the same lock used by
+		// the caller while changing the config state
+		if locker := i.options.UpdateLocker; locker != nil {
+			locker.Lock()
+			defer locker.Unlock()
+		}
+		i.updateMutex.Lock()
+		if i.updatePend == nil {
+			// canceled by Shutdown after the timer fired
+			i.updateMutex.Unlock()
+			return
+		}
+		timer := i.updateTimer
+		i.updatePend = nil
+		i.updateTimer = nil
+		i.updateMutex.Unlock()
+		i.logger.InfoV(2, "update delayed by %s, applying the last config state", i.options.UpdateWindow)
+		i.acmeUpdate()
+		if err := i.haproxyUpdate(timer); err == nil {
+			i.logger.Info("finish scheduled haproxy update")
+		}
+	})
+	return nil
 }
 
 func (i *instance) acmeUpdate() {
@@ -240,10 +305,10 @@ func (i *instance) acmeUpdate() {
 	}
 }
 
-func (i *instance) haproxyUpdate(timer *utils.Timer) {
+func (i *instance) haproxyUpdate(timer *utils.Timer) error {
 	// nil config, just ignore
 	if i.config == nil {
-		return
+		return nil
 	}
 	//
 	// this should be taken into account when refactoring this func:
@@ -257,18 +322,19 @@ func (i *instance) haproxyUpdate(timer *utils.Timer) {
 	if err := i.config.WriteTCPServicesMaps(); err != nil {
 		i.logger.Error("error building tcp services maps: %v", err)
 		i.metrics.IncUpdateNoop()
-		return
+		return fmt.Errorf("error building tcp services maps: %w", err)
 	}
 	if err := i.config.WriteFrontendMaps(); err != nil {
 		i.logger.Error("error building frontend maps: %v", err)
 		i.metrics.IncUpdateNoop()
-		return
+		return fmt.Errorf("error building frontend maps: %w", err)
 	}
 	if err := i.config.WriteBackendMaps(); err != nil {
 		i.logger.Error("error building backend maps: %v", err)
 		i.metrics.IncUpdateNoop()
-		return
+		return fmt.Errorf("error building backend maps: %w", err)
 	}
+	i.mapsTmpl.PurgeHashes()
 	timer.Tick("write_maps")
 	if !i.options.fake {
 		// TODO update tests and remove `if !fake` above
@@ -293,7 +359,7 @@ func (i *instance) haproxyUpdate(timer *utils.Timer) {
 		if err != nil {
 			i.logger.Error("error writing configuration: %v", err)
 			i.metrics.IncUpdateNoop()
-			return
+			return fmt.Errorf("error writing configuration: %w", err)
 		}
 	}
 	i.updateCertExpiring()
@@ -303,11 +369,12 @@ func (i *instance) haproxyUpdate(timer *utils.Timer) {
 		}
 	}()
 	if updated {
+		var err error
 		if updater.cmdCnt > 0 {
 			if i.options.ValidateConfig {
-				var err error
 				if err = i.check(); err != nil {
 					i.logger.Error("error validating config file:\n%v", err)
+					err = fmt.Errorf("error validating config file: %w", err)
 				}
 				timer.Tick("validate_cfg")
 				i.updateSuccessful(err == nil)
@@ -318,17 +385,17 @@ func (i *instance) haproxyUpdate(timer *utils.Timer) {
 			i.logger.Info("old and new configurations match")
 			i.metrics.IncUpdateNoop()
 		}
-		return
+		return err
 	}
-	if i.options.ReloadQueue != nil {
+	if i.options.ReloadQueue != nil && !i.options.SyncReload {
 		i.options.ReloadQueue.Notify()
 		i.logger.InfoV(2, "haproxy reload enqueued")
-	} else {
-		i.Reload(timer)
+		return nil
 	}
+	return i.Reload(timer)
 }
 
-func (i *instance) Reload(timer *utils.Timer) {
+func (i *instance) Reload(timer *utils.Timer) error {
 	i.metrics.IncUpdateFull()
 	if i.options.TrackInstances {
 		timeoutStopDur := i.config.Global().TimeoutStopDuration
@@ -343,7 +410,7 @@ func (i *instance) Reload(timer *utils.Timer) {
 		if i.options.TrackInstances {
 			i.conns.ReleaseLastInstance()
 		}
-		return
+		return fmt.Errorf("error reloading server: %w", err)
 	}
 	i.up = true
 	i.updateSuccessful(true)
@@ -357,6 +424,7 @@ func (i *instance) Reload(timer *utils.Timer) {
 		message += "; tracked instance(s): " + strconv.Itoa(i.conns.OldInstancesCount())
 	}
 	i.logger.Info(message)
+	return nil
 }
 
 func (i *instance) logChanged() {
@@ -482,6 +550,9 @@ func (i *instance) updateCertExpiring() {
 }
 
 func (i *instance) check() error {
+	if err := i.checkMaps(); err != nil {
+		return err
+	}
 	if i.options.fake {
 		i.logger.Info("(test) check was skipped")
 		return nil
@@ -490,15 +561,97 @@ func (i *instance) check() error {
 		// TODO check config on remote haproxy
 	} else {
 		// TODO Move all magic strings to a single place
-		out, err := exec.Command("haproxy", "-c", "-f", i.options.HAProxyCfgDir).CombinedOutput()
+		out, err := i.command("haproxy", "-c", "-f", i.options.HAProxyCfgDir).CombinedOutput()
 		outstr := string(out)
 		if err != nil {
+			if i.options.HAProxyMapsDir != "" && strings.Contains(outstr, i.options.HAProxyMapsDir) {
+				return fmt.Errorf("error parsing map files:\n%s", outstr)
+			}
 			return fmt.Errorf(outstr)
 		}
 	}
 	return nil
 }
 
+// checkMaps validates the syntax of the key/value map files, the ones
+// with the `.map` extension. haproxy would otherwise only report such
+// errors on `-c` if the map is referenced by the configuration file.
+func (i *instance) checkMaps() error {
+	mapsDir := i.options.HAProxyMapsDir
+	if mapsDir == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(mapsDir)
+	if err != nil {
+		return fmt.Errorf("error reading maps dir: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".map") {
+			continue
+		}
+		if err := checkMapFile(filepath.Join(mapsDir, file.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkMapFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("error reading map file: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || entry[0] == '#' {
+			continue
+		}
+		if len(strings.Fields(entry)) < 2 {
+			return fmt.Errorf("invalid map file '%s' on line %d: missing value of key '%s'", fileName, line, entry)
+		}
+	}
+	return scanner.Err()
+}
+
+// Shutdown gracefully stops haproxy running the configured shutdown command,
+// eg a script that soft-stops the running instance and waits for its
+// connections to drain. The command is killed if ctx is done before it
+// finishes. A pending update, see UpdateWindow, is canceled in any case.
+func (i *instance) Shutdown(ctx context.Context) error {
+	i.updateMutex.Lock()
+	if i.updatePend != nil {
+		i.updatePend.Stop()
+		i.updatePend = nil
+		i.updateTimer = nil
+		i.logger.Warn("canceling scheduled haproxy update, the last config state was not applied")
+	}
+	i.updateMutex.Unlock()
+	args := strings.Fields(i.options.ShutdownCmd)
+	if len(args) == 0 {
+		return nil
+	}
+	if i.options.fake {
+		i.logger.Info("(test) shutdown was skipped")
+		return nil
+	}
+	i.logger.Info("shutting down haproxy")
+	cmd := i.commandContext(ctx, args[0], args[1:]...)
+	// output is sent straight to the controller's stdout/stderr: a pipe
+	// would be held open by child processes after a timeout kill.
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("haproxy shutdown did not finish: %w", ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("error running shutdown command: %w", err)
+	}
+	return nil
+}
+
 func (i *instance) reloadHAProxy() error {
 	if i.options.fake {
 		i.logger.Info("(test) reload was skipped")
@@ -516,7 +669,9 @@ func (i *instance) reloadEmbedded() error {
 		state = "1"
 	}
 	// TODO Move all magic strings to a single place
-	out, err := exec.Command("/haproxy-reload.sh", i.options.ReloadStrategy, i.options.HAProxyCfgDir, state).CombinedOutput()
+	// the admin socket exposes the listeners, the reload script
+	// uses it to pass them to the new process on `reusesocket`
+	out, err := i.command(i.reloadCmd, i.options.ReloadStrategy, i.options.HAProxyCfgDir, state, i.options.AdminSocket).CombinedOutput()
 	outstr := string(out)
 	if len(outstr) > 0 {
 		i.logger.Warn("output from haproxy:\n%v", outstr)
@@ -524,6 +679,22 @@ func (i *instance) reloadEmbedded() error {
 	return err
 }
 
+// command creates the command used to check, reload or shutdown the embedded
+// haproxy, running from the configured working directory, if any. Configured
+// env vars are added to the environment inherited from the controller.
+func (i *instance) command(name string, arg ...string) *exec.Cmd {
+	return i.commandContext(context.Background(), name, arg...)
+}
+
+func (i *instance) commandContext(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Dir = i.options.ReloadWorkDir
+	if len(i.options.ReloadEnv) > 0 {
+		cmd.Env = append(os.Environ(), i.options.ReloadEnv...)
+	}
+	return cmd
+}
+
 func (i *instance) reloadExternal() error {
 	masterSock := i.conns.Master()
 	if !i.up {
@@ -17,14 +17,18 @@ limitations under the License.
 package haproxy
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kylelemons/godebug/diff"
 	yaml "gopkg.in/yaml.v2"
@@ -73,6 +77,21 @@ func TestBackends(t *testing.T) {
 			expected: `
     cookie Ingress prefix dynamic
     dynamic-cookie-key "Ingress"`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				g.Cookie.Key = "a secret key"
+				b.Cookie.Name = "serverid"
+				b.Cookie.Strategy = "insert"
+				b.Cookie.Keywords = "indirect nocache httponly"
+				b.Cookie.Dynamic = true
+				e1 := *endpointS1
+				b.Endpoints = []*hatypes.Endpoint{&e1}
+				b.Endpoints[0].CookieValue = "s1"
+			},
+			expected: `
+    cookie serverid insert indirect nocache httponly dynamic
+    dynamic-cookie-key "a secret key"`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
@@ -301,6 +320,13 @@ d1.local#/ path01`,
     http-request del-header x-forwarded-for
     option forwardfor`,
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				g.ForwardFor = "add"
+				b.ForwardForDisabled = true
+			},
+			expected: ``,
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.FindBackendPath(h.FindPath("/app")[0].Link).RewriteURL = "/"
@@ -550,6 +576,36 @@ d1.local#/api path02`,
     acl deny_exception_tcp src 192.168.95.0/24
     tcp-request content reject if deny_rule_tcp !deny_exception_tcp`,
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/")[0].Link).AllowedMethods = []string{"GET", "HEAD"}
+			},
+			expected: `
+    acl allowed_method0 method GET HEAD
+    http-request deny deny_status 405 if !allowed_method0`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/app")[0].Link).AllowedMethods = []string{"GET", "HEAD"}
+				b.FindBackendPath(h.FindPath("/api")[0].Link).AllowedMethods = []string{"GET", "POST", "PUT"}
+			},
+			path: []string{"/", "/app", "/api"},
+			expected: `
+    # path01 = d1.local/
+    # path03 = d1.local/api
+    # path02 = d1.local/app
+    http-request set-var(txn.pathID) var(req.base),lower,map_beg(/etc/haproxy/maps/_back_d1_app_8080_idpath__begin.map)
+    acl allowed_method1 method GET POST PUT
+    http-request deny deny_status 405 if { var(txn.pathID) path03 } !allowed_method1
+    acl allowed_method2 method GET HEAD
+    http-request deny deny_status 405 if { var(txn.pathID) path02 } !allowed_method2`,
+			expCheck: map[string]string{
+				"_back_d1_app_8080_idpath__begin.map": `
+d1.local#/app path02
+d1.local#/api path03
+d1.local#/ path01`,
+			},
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.FindBackendPath(h.FindPath("/")[0].Link).MaxBodySize = 1024
@@ -575,6 +631,97 @@ d1.local#/app path02
 d1.local#/ path01`,
 			},
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/")[0].Link).Priority = hatypes.Priority{Class: 10}
+				b.FindBackendPath(h.FindPath("/app")[0].Link).Priority = hatypes.Priority{Class: 10}
+			},
+			path: []string{"/", "/app"},
+			expected: `
+    http-request set-priority-class int(10)`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/app")[0].Link).Priority = hatypes.Priority{Class: -5, Offset: -1000}
+			},
+			path: []string{"/", "/app"},
+			expected: `
+    # path01 = d1.local/
+    # path02 = d1.local/app
+    http-request set-var(txn.pathID) var(req.base),lower,map_beg(/etc/haproxy/maps/_back_d1_app_8080_idpath__begin.map)
+    http-request set-priority-class int(-5) if { var(txn.pathID) path02 }
+    http-request set-priority-offset int(-1000) if { var(txn.pathID) path02 }`,
+			expCheck: map[string]string{
+				"_back_d1_app_8080_idpath__begin.map": `
+d1.local#/app path02
+d1.local#/ path01`,
+			},
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				tos, mark := int64(46), int64(10)
+				b.FindBackendPath(h.FindPath("/")[0].Link).QoS = hatypes.QoS{TOS: &tos, Mark: &mark}
+			},
+			expected: `
+    http-request set-tos 46
+    http-request set-mark 10`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				tos := int64(0)
+				b.FindBackendPath(h.FindPath("/")[0].Link).QoS = hatypes.QoS{TOS: &tos}
+			},
+			expected: `
+    http-request set-tos 0`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				tos, mark := int64(184), int64(256)
+				b.FindBackendPath(h.FindPath("/app")[0].Link).QoS = hatypes.QoS{TOS: &tos, Mark: &mark}
+			},
+			path: []string{"/", "/app"},
+			expected: `
+    # path01 = d1.local/
+    # path02 = d1.local/app
+    http-request set-var(txn.pathID) var(req.base),lower,map_beg(/etc/haproxy/maps/_back_d1_app_8080_idpath__begin.map)
+    http-request set-tos 184 if { var(txn.pathID) path02 }
+    http-request set-mark 256 if { var(txn.pathID) path02 }`,
+			expCheck: map[string]string{
+				"_back_d1_app_8080_idpath__begin.map": `
+d1.local#/app path02
+d1.local#/ path01`,
+			},
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/")[0].Link).LogLevel = "silent"
+			},
+			expected: `
+    http-request set-log-level silent`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.FindBackendPath(h.FindPath("/app")[0].Link).LogLevel = "silent"
+			},
+			path: []string{"/", "/app"},
+			expected: `
+    # path01 = d1.local/
+    # path02 = d1.local/app
+    http-request set-var(txn.pathID) var(req.base),lower,map_beg(/etc/haproxy/maps/_back_d1_app_8080_idpath__begin.map)
+    http-request set-log-level silent if { var(txn.pathID) path02 }`,
+			expCheck: map[string]string{
+				"_back_d1_app_8080_idpath__begin.map": `
+d1.local#/app path02
+d1.local#/ path01`,
+			},
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.UpstreamHost = "app.internal:8080"
+			},
+			expected: `
+    http-request set-header Host app.internal:8080`,
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.Headers = []*hatypes.BackendHeader{
@@ -594,6 +741,38 @@ d1.local#/ path01`,
 			expected: `
     http-request set-header X-ID abc
     http-request set-header Host app.domain`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HeadersRemove = []string{"Server", "X-Powered-By"}
+			},
+			expected: `
+    http-after-response del-header Server
+    http-after-response del-header X-Powered-By`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Headers = []*hatypes.BackendHeader{{Name: "X-Path", Value: "/"}}
+				b.HeadersReplace = []*hatypes.BackendHeaderReplace{
+					{Name: "Cookie", Match: `^(.*)session=[^;]*(.*)$`, Replace: `\1\2`},
+					{Name: "X-Forwarded-Host", Match: `^(.*):[0-9]+$`, Replace: `\1`},
+				}
+			},
+			expected: `
+    http-request set-header X-Path /
+    http-request replace-header Cookie ^(.*)session=[^;]*(.*)$ \1\2
+    http-request replace-header X-Forwarded-Host ^(.*):[0-9]+$ \1`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ResponseStatus = []*hatypes.BackendResponseStatus{
+					{Code: 502, Condition: "{ status 200 } { res.hdr(X-Error) -m found }"},
+					{Code: 404},
+				}
+			},
+			expected: `
+    http-response set-status 502 if { status 200 } { res.hdr(X-Error) -m found }
+    http-response set-status 404`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
@@ -683,6 +862,24 @@ d1.local#/ path01`,
 			},
 			srvsuffix: "check inter 2s",
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.Disabled = false
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.RiseCount = 2
+			},
+			srvsuffix: "check inter 2s rise 2",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.Disabled = true
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.RiseCount = 2
+				b.HealthCheck.URI = "/check"
+				b.HealthCheck.Log = true
+			},
+			srvsuffix: "",
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.HealthCheck.URI = "/check"
@@ -692,6 +889,76 @@ d1.local#/ path01`,
     option httpchk /check`,
 			srvsuffix: "check port 4000",
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.URI = "/check"
+				b.HealthCheck.Log = true
+			},
+			expected: `
+    option httpchk /check
+    option log-health-checks`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.URI = "/check"
+				b.HealthCheck.SSLMode = "ssl"
+			},
+			expected: `
+    option httpchk /check
+    http-check connect ssl`,
+			srvsuffix: "check inter 2s check-ssl verify none",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.URI = "/check"
+				b.HealthCheck.SNI = "app.local"
+				b.HealthCheck.SSLMode = "ssl"
+			},
+			expected: `
+    option httpchk /check
+    http-check connect ssl sni app.local`,
+			srvsuffix: "check inter 2s check-ssl verify none",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.Secure = true
+				b.Server.CAFilename = "/var/haproxy/ssl/ca.pem"
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.URI = "/check"
+				b.HealthCheck.SNI = "app.local"
+				b.HealthCheck.SSLMode = "ssl"
+			},
+			expected: `
+    option httpchk /check
+    http-check connect ssl sni app.local`,
+			srvsuffix: "ssl verify required ca-file /var/haproxy/ssl/ca.pem check inter 2s check-ssl",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.HealthCheck.Interval = "2s"
+				b.HealthCheck.SSLMode = "ssl"
+			},
+			srvsuffix: "check inter 2s check-ssl verify none",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.Secure = true
+				b.Server.CAFilename = "/var/haproxy/ssl/ca.pem"
+				b.HealthCheck.Port = 8443
+				b.HealthCheck.SSLMode = "ssl"
+			},
+			srvsuffix: "ssl verify required ca-file /var/haproxy/ssl/ca.pem check port 8443 check-ssl",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.Secure = true
+				b.HealthCheck.Port = 8080
+				b.HealthCheck.SSLMode = "no-ssl"
+			},
+			srvsuffix: "ssl verify none check port 8080 no-check-ssl",
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.AgentCheck.Port = 8000
@@ -699,6 +966,12 @@ d1.local#/ path01`,
 			},
 			srvsuffix: "agent-check agent-port 8000 agent-inter 2s",
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.AgentCheck = hatypes.AgentCheck{Addr: "10.0.0.10", Interval: "5s", Port: 8000, Send: "load"}
+			},
+			srvsuffix: "agent-check agent-port 8000 agent-addr 10.0.0.10 agent-inter 5s agent-send load",
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.Server.Secure = true
@@ -759,6 +1032,28 @@ d1.local#/ path01`,
 			},
 			srvsuffix: "ssl sni ssl_fc_sni verify required ca-file /var/haproxy/ssl/ca.pem",
 		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.Secure = true
+				b.Server.SNI = "str(domain.tld)"
+			},
+			srvsuffix: "ssl sni str(domain.tld) verify none",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.Secure = true
+				b.Server.CAFilename = "/var/haproxy/ssl/ca.pem"
+				b.Server.SNI = "str(domain.tld)"
+				b.Server.VerifyHost = "domain.tld"
+			},
+			srvsuffix: "ssl sni str(domain.tld) verify required ca-file /var/haproxy/ssl/ca.pem verifyhost domain.tld",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.SNI = "str(domain.tld)"
+			},
+			srvsuffix: "",
+		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.Server.Secure = true
@@ -818,79 +1113,368 @@ d1.local#/ path01`,
     stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
     http-request track-sc1 src
     http-request deny deny_status 429 if { sc1_conn_cur gt 200 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.SourceAffinity = hatypes.SourceAffinity{Size: "100k", Expire: "30m"}
+			},
+			expected: `
+    stick-table type ip size 100k expire 30m
+    stick on src`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
 				b.ModeTCP = true
-				b.Limit.Connections = 200
-				b.Limit.RPS = 20
-				b.Limit.Whitelist = []string{"192.168.0.0/16", "10.1.1.101"}
+				b.SourceAffinity = hatypes.SourceAffinity{Size: "1m", Expire: "1h"}
 			},
 			expected: `
-    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
-    tcp-request content track-sc1 src
-    acl wlist_conn src 192.168.0.0/16 10.1.1.101
-    tcp-request content reject if !wlist_conn { sc1_conn_cur gt 200 }
-    tcp-request content reject if !wlist_conn { sc1_conn_rate gt 20 }`,
+    stick-table type ip size 1m expire 1h
+    stick on src`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
-				b.Server.SendProxy = "send-proxy-v2"
+				b.Limit.Connections = 200
+				b.SourceAffinity = hatypes.SourceAffinity{Size: "100k", Expire: "30m"}
 			},
-			srvsuffix: "send-proxy-v2",
+			expected: `
+    stick-table type ip size 100k expire 30m store conn_cur,conn_rate(1s)
+    stick on src
+    http-request track-sc1 src
+    http-request deny deny_status 429 if { sc1_conn_cur gt 200 }`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
-				b.BlueGreen.CookieName = "ServerName"
-				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
-				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
-				b.Endpoints[0].Label = "blue"
+				b.Limit.GPC0Increment = "{ path_beg /login } { method POST }"
+				b.Limit.GPC0Threshold = 10
 			},
-			skipSrv: true,
 			expected: `
-    use-server s31 if { req.cook(ServerName) blue }
-    server s31 172.17.0.131:8080 weight 100
-    server s32 172.17.0.132:8080 weight 100
-    server s33 172.17.0.133:8080 weight 100`,
+    stick-table type ip size 200k expire 5m store gpc0
+    http-request track-sc1 src
+    http-request sc-inc-gpc0(1) if { path_beg /login } { method POST }
+    http-request deny deny_status 429 if { sc1_get_gpc0 gt 10 }`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
-				b.BlueGreen.HeaderName = "X-Svc"
-				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
-				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
-				b.Endpoints[1].Label = "green"
+				b.Limit.RPS = 20
+				b.Limit.DenyStatus = 403
+				b.Limit.GPC0Threshold = 5
+				b.Limit.Whitelist = []string{"10.0.0.0/8"}
 			},
-			skipSrv: true,
 			expected: `
-    use-server s32 if { req.hdr(X-Svc) green }
-    server s31 172.17.0.131:8080 weight 100
-    server s32 172.17.0.132:8080 weight 100
-    server s33 172.17.0.133:8080 weight 100`,
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s),gpc0
+    http-request track-sc1 src
+    acl wlist_conn src 10.0.0.0/8
+    http-request deny deny_status 403 if !wlist_conn { sc1_conn_rate gt 20 }
+    http-request deny deny_status 403 if !wlist_conn { sc1_get_gpc0 gt 5 }`,
 		},
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
-				b.BlueGreen.CookieName = "ServerName"
-				b.BlueGreen.HeaderName = "X-Svc"
-				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
-				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
-				b.Endpoints[1].Label = "green"
-				b.Endpoints[2].Label = "green"
+				b.Limit.GPC0Threshold = 3
+				b.SourceAffinity = hatypes.SourceAffinity{Size: "100k", Expire: "30m"}
 			},
-			skipSrv: true,
 			expected: `
-    use-server s32 if { req.hdr(X-Svc) green }
-    use-server s33 if { req.hdr(X-Svc) green }
-    use-server s32 if { req.cook(ServerName) green }
-    use-server s33 if { req.cook(ServerName) green }
-    server s31 172.17.0.131:8080 weight 100
-    server s32 172.17.0.132:8080 weight 100
-    server s33 172.17.0.133:8080 weight 100`,
+    stick-table type ip size 100k expire 30m store gpc0
+    stick on src
+    http-request track-sc1 src
+    http-request deny deny_status 429 if { sc1_get_gpc0 gt 3 }`,
 		},
-		// simulates a config where the cookie value is a pod id
 		{
 			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
-				b.Cookie.Name = "serverId"
-				b.Cookie.Strategy = "insert"
+				b.Limit.PathRPS = 50
+			},
+			expected: `
+    http-request track-sc2 base table _limit_path_d1_app_8080
+    http-request deny deny_status 429 if { sc2_http_req_rate gt 50 }
+    server s1 172.17.0.11:8080 weight 100
+backend _limit_path_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)`,
+			skipSrv: true,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.RPS = 20
+				b.Limit.PathRPS = 50
+				b.Limit.Whitelist = []string{"10.1.1.101"}
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request track-sc2 base table _limit_path_d1_app_8080
+    acl wlist_conn src 10.1.1.101
+    http-request deny deny_status 429 if !wlist_conn { sc1_conn_rate gt 20 }
+    http-request deny deny_status 429 if !wlist_conn { sc2_http_req_rate gt 50 }
+    server s1 172.17.0.11:8080 weight 100
+backend _limit_path_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)`,
+			skipSrv: true,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.SessionKey = "req.cook(sessionid)"
+				b.Limit.SessionRPS = 10
+			},
+			expected: `
+    http-request track-sc0 req.cook(sessionid) table _limit_session_d1_app_8080
+    http-request deny deny_status 429 if { sc0_http_req_rate gt 10 }
+    server s1 172.17.0.11:8080 weight 100
+backend _limit_session_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)`,
+			skipSrv: true,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.RPS = 20
+				b.Limit.PathRPS = 50
+				b.Limit.SessionKey = "req.hdr(x-api-key)"
+				b.Limit.SessionRPS = 10
+				b.Limit.Whitelist = []string{"10.1.1.101"}
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request track-sc2 base table _limit_path_d1_app_8080
+    http-request track-sc0 req.hdr(x-api-key) table _limit_session_d1_app_8080
+    acl wlist_conn src 10.1.1.101
+    http-request deny deny_status 429 if !wlist_conn { sc1_conn_rate gt 20 }
+    http-request deny deny_status 429 if !wlist_conn { sc2_http_req_rate gt 50 }
+    http-request deny deny_status 429 if !wlist_conn { sc0_http_req_rate gt 10 }
+    server s1 172.17.0.11:8080 weight 100
+backend _limit_path_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)
+backend _limit_session_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)`,
+			skipSrv: true,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ModeTCP = true
+				b.Limit.Connections = 200
+				b.Limit.RPS = 20
+				b.Limit.Whitelist = []string{"192.168.0.0/16", "10.1.1.101"}
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    tcp-request content track-sc1 src
+    acl wlist_conn src 192.168.0.0/16 10.1.1.101
+    tcp-request content reject if !wlist_conn { sc1_conn_cur gt 200 }
+    tcp-request content reject if !wlist_conn { sc1_conn_rate gt 20 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.Action = "silent-drop"
+				b.Limit.Connections = 200
+				b.Limit.RPS = 20
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request silent-drop if { sc1_conn_cur gt 200 }
+    http-request silent-drop if { sc1_conn_rate gt 20 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.Action = "silent-drop"
+				b.Limit.PathRPS = 50
+				b.Limit.Whitelist = []string{"10.1.1.101"}
+			},
+			expected: `
+    http-request track-sc2 base table _limit_path_d1_app_8080
+    acl wlist_conn src 10.1.1.101
+    http-request silent-drop if !wlist_conn { sc2_http_req_rate gt 50 }
+    server s1 172.17.0.11:8080 weight 100
+backend _limit_path_d1_app_8080
+    stick-table type string len 128 size 200k expire 5m store http_req_rate(1s)`,
+			skipSrv: true,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ModeTCP = true
+				b.Limit.Action = "silent-drop"
+				b.Limit.RPS = 20
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    tcp-request content track-sc1 src
+    tcp-request content silent-drop if { sc1_conn_rate gt 20 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.Action = "tarpit"
+				b.Limit.RPS = 20
+				b.Timeout.Tarpit = "10s"
+			},
+			expected: `
+    timeout tarpit 10s
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request tarpit deny_status 429 if { sc1_conn_rate gt 20 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.RPS = 20
+				path := b.FindBackendPath(h.FindPath("/")[0].Link)
+				path.AllowedIPHTTP.Rule = []string{"10.0.0.0/8"}
+				path.AllowedIPHTTP.DenyStatus = 403
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request deny deny_status 429 if { sc1_conn_rate gt 20 }
+    acl allow_rule_src0 src 10.0.0.0/8
+    http-request deny deny_status 403 if !allow_rule_src0`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Limit.Connections = 200
+				b.Limit.DenyStatus = 503
+				path := b.FindBackendPath(h.FindPath("/")[0].Link)
+				path.DeniedIPHTTP.Rule = []string{"192.168.95.0/24"}
+				path.DeniedIPHTTP.DenyStatus = 401
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request deny deny_status 503 if { sc1_conn_cur gt 200 }
+    acl deny_rule_src0 src 192.168.95.0/24
+    http-request deny deny_status 401 if deny_rule_src0`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ModeTCP = true
+				b.Limit.Action = "tarpit"
+				b.Limit.RPS = 20
+			},
+			expected: `
+    stick-table type ip size 200k expire 5m store conn_cur,conn_rate(1s)
+    tcp-request content track-sc1 src
+    tcp-request content reject if { sc1_conn_rate gt 20 }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Redispatch = hatypes.BackendRedispatch{Interval: 2, Retries: 5}
+			},
+			expected: `
+    retries 5
+    option redispatch 2`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Redispatch = hatypes.BackendRedispatch{Interval: -1}
+			},
+			expected: `
+    option redispatch -1`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ResolveDst = hatypes.BackendResolveDst{Header: "X-Backend-Host", Resolver: "k8s"}
+			},
+			expected: `
+    http-request do-resolve(txn.addr,k8s) hdr(X-Backend-Host),lower
+    http-request set-dst var(txn.addr) if { var(txn.addr) -m found }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ResolveDst = hatypes.BackendResolveDst{Domains: []string{"svc.cluster.local", "example.com"}, Header: "X-Backend-Host", Resolver: "k8s"}
+			},
+			expected: `
+    http-request deny if !{ hdr(X-Backend-Host),lower -m str svc.cluster.local example.com } !{ hdr(X-Backend-Host),lower -m end .svc.cluster.local .example.com }
+    http-request do-resolve(txn.addr,k8s) hdr(X-Backend-Host),lower
+    http-request set-dst var(txn.addr) if { var(txn.addr) -m found }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ResolveDst = hatypes.BackendResolveDst{Header: "X-Backend-Host", Resolver: "k8s"}
+				b.WaitForBody = hatypes.BackendWaitForBody{Time: "10s"}
+			},
+			expected: `
+    http-request do-resolve(txn.addr,k8s) hdr(X-Backend-Host),lower
+    http-request set-dst var(txn.addr) if { var(txn.addr) -m found }
+    http-request wait-for-body time 10s`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.WaitForBody = hatypes.BackendWaitForBody{Time: "10s"}
+			},
+			expected: `
+    http-request wait-for-body time 10s`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.WaitForBody = hatypes.BackendWaitForBody{Time: "1s", AtLeast: 16384}
+			},
+			expected: `
+    http-request wait-for-body time 1s at-least 16384`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Persist.Enabled = true
+				b.Persist.Force = []string{"{ src 10.0.0.0/8 }", "{ hdr(x-maint) -m found }"}
+				b.Persist.Ignore = []string{"{ path_beg /static }"}
+			},
+			expected: `
+    option persist
+    force-persist if { src 10.0.0.0/8 }
+    force-persist if { hdr(x-maint) -m found }
+    ignore-persist if { path_beg /static }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Server.SendProxy = "send-proxy-v2"
+			},
+			srvsuffix: "send-proxy-v2",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BlueGreen.CookieName = "ServerName"
+				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
+				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
+				b.Endpoints[0].Label = "blue"
+			},
+			skipSrv: true,
+			expected: `
+    use-server s31 if { req.cook(ServerName) blue }
+    server s31 172.17.0.131:8080 weight 100
+    server s32 172.17.0.132:8080 weight 100
+    server s33 172.17.0.133:8080 weight 100`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BlueGreen.HeaderName = "X-Svc"
+				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
+				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
+				b.Endpoints[1].Label = "green"
+			},
+			skipSrv: true,
+			expected: `
+    use-server s32 if { req.hdr(X-Svc) green }
+    server s31 172.17.0.131:8080 weight 100
+    server s32 172.17.0.132:8080 weight 100
+    server s33 172.17.0.133:8080 weight 100`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BlueGreen.CookieName = "ServerName"
+				b.BlueGreen.HeaderName = "X-Svc"
+				e1, e2, e3 := *endpointS31, *endpointS32, *endpointS33
+				b.Endpoints = []*hatypes.Endpoint{&e1, &e2, &e3}
+				b.Endpoints[1].Label = "green"
+				b.Endpoints[2].Label = "green"
+			},
+			skipSrv: true,
+			expected: `
+    use-server s32 if { req.hdr(X-Svc) green }
+    use-server s33 if { req.hdr(X-Svc) green }
+    use-server s32 if { req.cook(ServerName) green }
+    use-server s33 if { req.cook(ServerName) green }
+    server s31 172.17.0.131:8080 weight 100
+    server s32 172.17.0.132:8080 weight 100
+    server s33 172.17.0.133:8080 weight 100`,
+		},
+		// simulates a config where the cookie value is a pod id
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Cookie.Name = "serverId"
+				b.Cookie.Strategy = "insert"
 				b.Cookie.Keywords = "nocache"
 				b.EpCookieStrategy = hatypes.EpCookiePodUID
 				ep1 := *endpointS1
@@ -922,60 +1506,200 @@ d1.local#/ path01`,
 			},
 			srvsuffix: "id 1234567",
 		},
-	}
-	for _, test := range testCases {
-		c := setup(t)
-
-		if len(test.path) == 0 {
-			test.path = []string{"/"}
-		}
-		if test.srvsuffix != "" {
-			test.srvsuffix = " " + test.srvsuffix
-		}
-
-		var h *hatypes.Host
-		var b *hatypes.Backend
-
-		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
-		ep := *endpointS1
-		b.Endpoints = []*hatypes.Endpoint{&ep}
-		h = c.config.Hosts().AcquireHost("d1.local")
-		for j, p := range test.path {
-			match := hatypes.MatchBegin
-			if test.match != nil {
-				match = test.match[j]
-			}
-			h.AddPath(b, p, match)
-		}
-		test.doconfig(c.config.Global(), h, b)
-
-		var mode string
-		if b.ModeTCP {
-			mode = "tcp"
-		} else {
-			mode = "http"
-		}
-
-		if test.expFronts == "" {
-			test.expFronts = "<<frontends-default>>"
-		}
-
-		var srv string
-		if !test.skipSrv {
-			srv = `
-    server s1 172.17.0.11:8080 weight 100` + test.srvsuffix
-		}
-		c.Update()
-		c.checkConfig(`
-<<global>>
-<<defaults>>
-backend d1_app_8080
-    mode ` + mode + test.expected + srv + `
-<<backends-default>>
-` + test.expFronts + `
-<<support>>
-`)
-
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				maxConn := 20
+				b.Server.PoolMaxConn = &maxConn
+			},
+			srvsuffix: "pool-max-conn 20",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				maxConn := 0
+				b.Server.PoolMaxConn = &maxConn
+			},
+			srvsuffix: "pool-max-conn 0",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				maxConn := -1
+				b.Server.PoolMaxConn = &maxConn
+				b.Server.PoolPurge = "10s"
+			},
+			srvsuffix: "pool-max-conn -1 pool-purge-delay 10s",
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ConnectionMode = "http-keep-alive"
+			},
+			expected: `
+    option http-keep-alive`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ConnectionMode = "http-server-close"
+			},
+			expected: `
+    option http-server-close`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ConnectionMode = "httpclose"
+			},
+			expected: `
+    option httpclose`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Timeout.Check = "10s"
+				b.Timeout.Connect = "3s"
+			},
+			expected: `
+    timeout check 10s
+    timeout connect 3s`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Timeout.KeepAlive = "30s"
+			},
+			expected: `
+    timeout http-keep-alive 30s`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Timeout.Server = "30s"
+				b.Timeout.ServerFin = "1s"
+			},
+			expected: `
+    timeout server 30s
+    timeout server-fin 1s`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Timeout.Tunnel = "8h"
+			},
+			expected: `
+    timeout tunnel 8h`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.Timeout.Tunnel = "8h"
+				b.IndependentStreams = true
+			},
+			expected: `
+    timeout tunnel 8h
+    option independent-streams`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ModeTCP = true
+				b.IndependentStreams = true
+			},
+			expected: `
+    option independent-streams`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.TCPKeepAlive = true
+			},
+			expected: `
+    option srvtcpka`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.ModeTCP = true
+				b.TCPKeepAlive = true
+			},
+			expected: `
+    option srvtcpka`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.RequiredHeader = "Authorization"
+			},
+			expected: `
+    http-request deny unless { req.hdr(Authorization) -m found }`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BalanceAlgorithm = "uri depth 3 len 20"
+				b.HashType = "consistent"
+			},
+			expected: `
+    balance uri depth 3 len 20
+    hash-type consistent`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BalanceAlgorithm = "random(2)"
+			},
+			expected: `
+    balance random(2)`,
+		},
+		{
+			doconfig: func(g *hatypes.Global, h *hatypes.Host, b *hatypes.Backend) {
+				b.BalanceAlgorithm = "source"
+				b.HashType = "consistent djb2 avalanche"
+			},
+			expected: `
+    balance source
+    hash-type consistent djb2 avalanche`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		if len(test.path) == 0 {
+			test.path = []string{"/"}
+		}
+		if test.srvsuffix != "" {
+			test.srvsuffix = " " + test.srvsuffix
+		}
+
+		var h *hatypes.Host
+		var b *hatypes.Backend
+
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		ep := *endpointS1
+		b.Endpoints = []*hatypes.Endpoint{&ep}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		for j, p := range test.path {
+			match := hatypes.MatchBegin
+			if test.match != nil {
+				match = test.match[j]
+			}
+			h.AddPath(b, p, match)
+		}
+		test.doconfig(c.config.Global(), h, b)
+
+		var from, mode string
+		if b.ModeTCP {
+			from = " from tcp"
+			mode = "tcp"
+		} else {
+			mode = "http"
+		}
+
+		if test.expFronts == "" {
+			test.expFronts = "<<frontends-default>>"
+		}
+
+		var srv string
+		if !test.skipSrv {
+			srv = `
+    server s1 172.17.0.11:8080 weight 100` + test.srvsuffix
+		}
+		c.Update()
+		c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080` + from + `
+    mode ` + mode + test.expected + srv + `
+<<backends-default>>
+` + test.expFronts + `
+<<support>>
+`)
+
 		for mapName, content := range test.expCheck {
 			c.checkMap(mapName, content)
 		}
@@ -985,6 +1709,82 @@ backend d1_app_8080
 	}
 }
 
+func TestCommandWorkDir(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(workdir)
+	workdir, _ = filepath.EvalSymlinks(workdir)
+	cwd, _ := os.Getwd()
+	cwd, _ = filepath.EvalSymlinks(cwd)
+	testCases := []struct {
+		workdir  string
+		expected string
+	}{
+		// 0
+		{
+			workdir:  "",
+			expected: cwd,
+		},
+		// 1
+		{
+			workdir:  workdir,
+			expected: workdir,
+		},
+	}
+	for i, test := range testCases {
+		instance := &instance{options: &InstanceOptions{ReloadWorkDir: test.workdir}}
+		out, err := instance.command("pwd", "-P").Output()
+		if err != nil {
+			t.Errorf("%d: error running command: %v", i, err)
+		}
+		if actual := strings.TrimSpace(string(out)); actual != test.expected {
+			t.Errorf("%d: expected workdir '%s' but was '%s'", i, test.expected, actual)
+		}
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	os.Setenv("HAPROXY_INGRESS_TEST_INHERITED", "inherited")
+	defer os.Unsetenv("HAPROXY_INGRESS_TEST_INHERITED")
+	testCases := []struct {
+		env      []string
+		expected string
+	}{
+		// 0
+		{
+			expected: `
+HAPROXY_INGRESS_TEST_INHERITED=inherited`,
+		},
+		// 1
+		{
+			env: []string{"HAPROXY_INGRESS_TEST_FLAG=on"},
+			expected: `
+HAPROXY_INGRESS_TEST_FLAG=on
+HAPROXY_INGRESS_TEST_INHERITED=inherited`,
+		},
+	}
+	for i, test := range testCases {
+		instance := &instance{options: &InstanceOptions{ReloadEnv: test.env}}
+		out, err := instance.command("env").Output()
+		if err != nil {
+			t.Errorf("%d: error running command: %v", i, err)
+		}
+		var env []string
+		for _, v := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(v, "HAPROXY_INGRESS_TEST_") {
+				env = append(env, v)
+			}
+		}
+		sort.Strings(env)
+		actual := "\n" + strings.Join(env, "\n")
+		if actual != test.expected {
+			t.Errorf("%d: expected env '%s' but was '%s'", i, test.expected, actual)
+		}
+	}
+}
+
 /* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
  *
  *  TEMPLATES
@@ -1068,6 +1868,8 @@ backend d1_app_8080
 func TestInstanceGlobalBind(t *testing.T) {
 	testCases := []struct {
 		bind          hatypes.GlobalBindConfig
+		alpn          string
+		minVer        string
 		expectedHTTP  string
 		expectedHTTPS string
 	}{
@@ -1092,6 +1894,27 @@ func TestInstanceGlobalBind(t *testing.T) {
 			expectedHTTP:  "bind 127.0.0.1:80",
 			expectedHTTPS: "bind 127.0.0.1:443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all",
 		},
+		// 3
+		{
+			bind: hatypes.GlobalBindConfig{
+				HTTPBind:  ":80",
+				HTTPSBind: ":443",
+			},
+			minVer:        "TLSv1.2",
+			expectedHTTP:  "bind :80",
+			expectedHTTPS: "bind :443 ssl alpn h2,http/1.1 ssl-min-ver TLSv1.2 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all",
+		},
+		// 4
+		{
+			bind: hatypes.GlobalBindConfig{
+				HTTPBind:  ":80",
+				HTTPSBind: ":443",
+			},
+			alpn:          "http/1.1",
+			minVer:        "TLSv1.3",
+			expectedHTTP:  "bind :80",
+			expectedHTTPS: "bind :443 ssl alpn http/1.1 ssl-min-ver TLSv1.3 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all",
+		},
 	}
 	for _, test := range testCases {
 		c := setup(t)
@@ -1104,6 +1927,10 @@ func TestInstanceGlobalBind(t *testing.T) {
 		h.AddPath(b, "/", hatypes.MatchBegin)
 
 		c.config.Global().Bind = test.bind
+		if test.alpn != "" {
+			c.config.Global().SSL.ALPN = test.alpn
+		}
+		c.config.Global().SSL.MinVersion = test.minVer
 		if test.expectedHTTP != "" {
 			test.expectedHTTP = "\n    " + test.expectedHTTP
 		}
@@ -1163,7 +1990,20 @@ global
     ssl-default-bind-options no-sslv3
     ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
     ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
-defaults
+defaults tcp
+    log global
+    maxconn 2000
+    mode tcp
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
     log global
     maxconn 2000
     option redispatch
@@ -1196,32 +2036,39 @@ empty#/ default_empty_8080`)
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestDefaultBackendRedir(t *testing.T) {
+func TestInstanceTimeoutQueue(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
-	c.config.Global().DefaultBackendRedir = "https://example.tld"
-	c.config.Hosts().AcquireHost("empty").AddPath(c.config.Backends().AcquireBackend("default", "empty", "8080"), "/", hatypes.MatchBegin)
+	c.config.Global().Timeout.Queue = "30s"
+
+	b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.Timeout.Queue = "2m"
+	c.config.Hosts().AcquireHost("d2.local").AddPath(b, "/", hatypes.MatchBegin)
 
 	c.Update()
 
 	c.checkConfig(`
-global
-    daemon
-    unix-bind mode 0600
-    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+<<global>>
+defaults tcp
+    log global
     maxconn 2000
-    hard-stop-after 15m
-    lua-prepend-path /etc/haproxy/lua/?.lua
-    lua-load /etc/haproxy/lua/auth-request.lua
-    lua-load /etc/haproxy/lua/services.lua
-    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
-    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
-    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
-    ssl-default-bind-options no-sslv3
-    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
-    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
-defaults
+    mode tcp
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           30s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
     log global
     maxconn 2000
     option redispatch
@@ -1233,35 +2080,337 @@ defaults
     timeout connect         5s
     timeout http-keep-alive 1m
     timeout http-request    5s
-    timeout queue           5s
+    timeout queue           30s
     timeout server          50s
     timeout server-fin      50s
     timeout tunnel          1h
-backend default_empty_8080
+backend d1_app_8080
     mode http
-backend _error404
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
     mode http
-    redirect location https://example.tld code 301
+    timeout queue 2m
+    server s21 172.17.0.121:8080 weight 100
+<<backends-default>>
 <<frontends-default>>
 <<support>>
 `)
 
-	c.checkMap("_front_http_host__begin.map", `
-empty#/ default_empty_8080`)
-	c.checkMap("_front_https_host__begin.map", `
-empty#/ default_empty_8080`)
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestInstanceEmptyExternal(t *testing.T) {
+func TestDefaultBackendRedir(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
-	c.config.global.External.MasterSocket = "/tmp/master.sock"
-	c.config.global.Master.WorkerMaxReloads = 20
-	c.config.global.Security.Username = "external"
-	c.config.global.Security.Groupname = "external"
-
+	c.config.Global().DefaultBackendRedir = "https://example.tld"
+	c.config.Hosts().AcquireHost("empty").AddPath(c.config.Backends().AcquireBackend("default", "empty", "8080"), "/", hatypes.MatchBegin)
+
+	c.Update()
+
+	c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+defaults tcp
+    log global
+    maxconn 2000
+    mode tcp
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
+    log global
+    maxconn 2000
+    option redispatch
+    option dontlognull
+    option http-server-close
+    option http-keep-alive
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout http-keep-alive 1m
+    timeout http-request    5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+backend default_empty_8080
+    mode http
+backend _error404
+    mode http
+    redirect location https://example.tld code 301
+<<frontends-default>>
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__begin.map", `
+empty#/ default_empty_8080`)
+	c.checkMap("_front_https_host__begin.map", `
+empty#/ default_empty_8080`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceHardStopAfter(t *testing.T) {
+	testCases := []struct {
+		stop     string
+		expected string
+	}{
+		// 0
+		{
+			stop:     "",
+			expected: "",
+		},
+		// 1
+		{
+			stop: "30m",
+			expected: `
+    hard-stop-after 30m`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		c.config.Global().Timeout.Stop = test.stop
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		c.Update()
+		c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000` + test.expected + `
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceTune(t *testing.T) {
+	testCases := []struct {
+		tune     hatypes.TuneConfig
+		expected string
+	}{
+		// 0
+		{
+			expected: "",
+		},
+		// 1
+		{
+			tune: hatypes.TuneConfig{H2MaxConcurrentStreams: 200},
+			expected: `
+    tune.h2.max-concurrent-streams 200`,
+		},
+		// 2
+		{
+			tune: hatypes.TuneConfig{MaxAccept: 64},
+			expected: `
+    tune.maxaccept 64`,
+		},
+		// 3
+		{
+			tune: hatypes.TuneConfig{H2MaxConcurrentStreams: 50, MaxAccept: -1},
+			expected: `
+    tune.h2.max-concurrent-streams 50
+    tune.maxaccept -1`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		c.config.Global().Tune = test.tune
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		c.Update()
+		c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000` + test.expected + `
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceSSLDHParam(t *testing.T) {
+	testCases := []struct {
+		dhParam  hatypes.DHParamConfig
+		expected string
+	}{
+		// 0
+		{
+			dhParam: hatypes.DHParamConfig{DefaultMaxSize: 2048},
+			expected: `
+    tune.ssl.default-dh-param 2048`,
+		},
+		// 1
+		{
+			dhParam: hatypes.DHParamConfig{DefaultMaxSize: 4096},
+			expected: `
+    tune.ssl.default-dh-param 4096`,
+		},
+		// 2
+		{
+			dhParam: hatypes.DHParamConfig{Filename: "/var/haproxy/ssl/dh.pem", DefaultMaxSize: 4096},
+			expected: `
+    ssl-dh-param-file /var/haproxy/ssl/dh.pem`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		c.config.Global().SSL.DHParam = test.dhParam
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		c.Update()
+		c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua` + test.expected + `
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceAdminSocket(t *testing.T) {
+	testCases := []struct {
+		perms    hatypes.AdminSocketPermsConfig
+		expected string
+	}{
+		// 0
+		{
+			expected: "level admin expose-fd listeners mode 600",
+		},
+		// 1
+		{
+			perms:    hatypes.AdminSocketPermsConfig{Mode: "660", Group: "haproxy"},
+			expected: "level admin expose-fd listeners mode 660 group haproxy",
+		},
+		// 2
+		{
+			perms:    hatypes.AdminSocketPermsConfig{Mode: "0640", User: "haproxy", Group: "monitor"},
+			expected: "level admin expose-fd listeners mode 0640 user haproxy group monitor",
+		},
+	}
+	socketRegex := regexp.MustCompile(`(?m)^    stats socket ([^ ]+) (.*)$`)
+	for i, test := range testCases {
+		c := setupOptions(testOptions{
+			t:           t,
+			adminSocket: "/var/run/haproxy/admin.sock",
+		})
+
+		// the converter copies the socket path from the same options used by the instance
+		c.config.Global().AdminSocket = c.instance.options.AdminSocket
+		c.config.Global().AdminSocketPerms = test.perms
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		c.Update()
+		socket := socketRegex.FindStringSubmatch(c.readConfig(filepath.Join(c.tempdir, "haproxy.cfg")))
+		if socket == nil {
+			t.Errorf("stats socket not found on %d", i)
+		} else {
+			c.compareText(fmt.Sprintf("socket path %d", i), socket[1], c.instance.conns.adminSock)
+			c.compareText(fmt.Sprintf("socket options %d", i), socket[2], test.expected)
+		}
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceEmptyExternal(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.config.global.External.MasterSocket = "/tmp/master.sock"
+	c.config.global.Master.WorkerMaxReloads = 20
+	c.config.global.Security.Username = "external"
+	c.config.global.Security.Groupname = "external"
+
 	c.config.Hosts().AcquireHost("empty").AddPath(c.config.Backends().AcquireBackend("default", "empty", "8080"), "/", hatypes.MatchBegin)
 	c.Update()
 
@@ -1425,23 +2574,104 @@ backend _error404
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestInstanceMatch(t *testing.T) {
+func TestInstanceProcs(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
-	b := c.config.Backends().AcquireBackend("default", "d1", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS1}
-	h := c.config.Hosts().AcquireHost("d1.local")
-	h.AddPath(b, "/app", hatypes.MatchPrefix)
-	h.AddPath(b, "/api/v[0-9]+/", hatypes.MatchRegex)
+	c.config.global.Procs.Nbproc = 1
+	c.config.global.Procs.Nbthread = 4
+	c.config.global.Procs.CPUMap = "auto:1/1-4 0-3"
+
+	c.config.Hosts().AcquireHost("empty").AddPath(c.config.Backends().AcquireBackend("default", "empty", "8080"), "/", hatypes.MatchBegin)
 	c.Update()
 
 	c.checkConfig(`
-<<global>>
-<<defaults>>
-backend default_d1_8080
-    mode http
-    server s1 172.17.0.11:8080 weight 100
+global
+    daemon
+    unix-bind mode 0600
+    nbthread 4
+    cpu-map auto:1/1-4 0-3
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend default_empty_8080
+    mode http
+backend _error404
+    mode http
+    http-request use-service lua.send-404
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceMaxRate(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.config.global.MaxConnRate = 500
+	c.config.global.MaxSessRate = 1000
+
+	c.config.Hosts().AcquireHost("empty").AddPath(c.config.Backends().AcquireBackend("default", "empty", "8080"), "/", hatypes.MatchBegin)
+	c.Update()
+
+	c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    maxconnrate 500
+    maxsessrate 1000
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend default_empty_8080
+    mode http
+backend _error404
+    mode http
+    http-request use-service lua.send-404
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceMatch(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	b := c.config.Backends().AcquireBackend("default", "d1", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h := c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/app", hatypes.MatchPrefix)
+	h.AddPath(b, "/api/v[0-9]+/", hatypes.MatchRegex)
+	c.Update()
+
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend default_d1_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
 <<backends-default>>
 frontend _front_http
     mode http
@@ -1826,12 +3056,17 @@ func TestInstanceTCPServices(t *testing.T) {
 	b3.Endpoints = []*hatypes.Endpoint{endpointS31, endpointS32}
 
 	services := []struct {
-		port      int
-		hostname  string
-		backend   hatypes.BackendID
-		proxyProt bool
-		tls       hatypes.TLSConfig
-		custom    []string
+		port         int
+		hostname     string
+		backend      hatypes.BackendID
+		proxyProt    bool
+		tls          hatypes.TLSConfig
+		custom       []string
+		inspectDelay string
+		clientFin    string
+		setDst       string
+		setDstPort   string
+		allowedIP    hatypes.AccessConfig
 	}{
 		{
 			port: 7000,
@@ -1940,6 +3175,43 @@ func TestInstanceTCPServices(t *testing.T) {
 			backend: b.BackendID(),
 			custom:  []string{"## custom for TCP 7013", "## multi line"},
 		},
+		{
+			port:         7014,
+			backend:      b.BackendID(),
+			inspectDelay: "10s",
+		},
+		{
+			port:         7014,
+			hostname:     "local5",
+			backend:      b2.BackendID(),
+			inspectDelay: "10s",
+		},
+		{
+			port:      7015,
+			backend:   b.BackendID(),
+			clientFin: "1s",
+		},
+		{
+			port:      7016,
+			backend:   b.BackendID(),
+			proxyProt: true,
+			setDst:    "var(sess.dst)",
+		},
+		{
+			port:       7017,
+			backend:    b.BackendID(),
+			setDst:     "ipv4(10.0.0.10)",
+			setDstPort: "int(8443)",
+		},
+		{
+			port:      7018,
+			backend:   b.BackendID(),
+			proxyProt: true,
+			allowedIP: hatypes.AccessConfig{
+				Rule:      []string{"10.0.0.0/8", "192.168.0.0/16"},
+				Exception: []string{"10.0.1.0/24"},
+			},
+		},
 	}
 
 	for _, svc := range services {
@@ -1951,6 +3223,11 @@ func TestInstanceTCPServices(t *testing.T) {
 		p.ProxyProt = svc.proxyProt
 		p.TLS = svc.tls
 		p.CustomConfig = svc.custom
+		p.InspectDelay = svc.inspectDelay
+		p.TimeoutClientFin = svc.clientFin
+		p.SetDst = svc.setDst
+		p.SetDstPort = svc.setDstPort
+		p.AllowedIP = svc.allowedIP
 		h.Backend = svc.backend
 	}
 
@@ -1970,50 +3247,50 @@ backend d3_app_8080
     server s31 172.17.0.131:8080 weight 100
     server s32 172.17.0.132:8080 weight 100
 <<backends-default>>
-frontend _front_tcp_7000
+frontend _front_tcp_7000 from tcp
     bind :7000
     mode tcp
-frontend _front_tcp_7001
+frontend _front_tcp_7001 from tcp
     bind :7001
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7002
+frontend _front_tcp_7002 from tcp
     bind :7002 accept-proxy
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7003
+frontend _front_tcp_7003 from tcp
     bind :7003 accept-proxy ssl crt /ssl/7003.pem
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7004
+frontend _front_tcp_7004 from tcp
     bind :7004 ssl crt /ssl/7004.pem
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7005
+frontend _front_tcp_7005 from tcp
     bind :7005 ssl crt /ssl/7005.pem ca-file /ssl/ca-7005.pem verify required
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7006
+frontend _front_tcp_7006 from tcp
     bind :7006 ssl crt /ssl/7006.pem ca-file /ssl/ca-7006.pem verify required crl-file /ssl/crl-7006.pem
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7007
+frontend _front_tcp_7007 from tcp
     bind :7007 ssl crt /ssl/7007.pem alpn h2,http/1.1
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7008
+frontend _front_tcp_7008 from tcp
     bind :7008 ssl crt /ssl/7008.pem ca-file /ssl/ca-7008.pem verify optional
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7009
+frontend _front_tcp_7009 from tcp
     bind :7009 ssl crt /ssl/7009.pem ciphers ECDHE-ECDSA-AES128-GCM-SHA256 ciphersuites TLS_AES_128_GCM_SHA256
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7010
+frontend _front_tcp_7010 from tcp
     bind :7010 ssl crt /ssl/7010.pem force-tlsv13
     mode tcp
     default_backend d1_app_8080
-frontend _front_tcp_7011
+frontend _front_tcp_7011 from tcp
     bind :7011
     mode tcp
     tcp-request inspect-delay 5s
@@ -2022,17 +3299,49 @@ frontend _front_tcp_7011
     tcp-request content accept if { req.ssl_hello_type 1 }
     use_backend %[var(req.tcpback)] if { var(req.tcpback) -m found }
     default_backend d1_app_8080
-frontend _front_tcp_7012
+frontend _front_tcp_7012 from tcp
     bind :7012
     mode tcp
     ## custom for TCP 7012
     default_backend d1_app_8080
-frontend _front_tcp_7013
+frontend _front_tcp_7013 from tcp
     bind :7013
     mode tcp
     ## custom for TCP 7013
     ## multi line
     default_backend d1_app_8080
+frontend _front_tcp_7014 from tcp
+    bind :7014
+    mode tcp
+    tcp-request inspect-delay 10s
+    tcp-request content set-var(req.tcpback) req.ssl_sni,lower,map_str(/etc/haproxy/maps/_tcp_sni_7014__exact.map)
+    tcp-request content accept if { req.ssl_hello_type 1 }
+    use_backend %[var(req.tcpback)] if { var(req.tcpback) -m found }
+    default_backend d1_app_8080
+frontend _front_tcp_7015 from tcp
+    bind :7015
+    mode tcp
+    timeout client-fin 1s
+    default_backend d1_app_8080
+frontend _front_tcp_7016 from tcp
+    bind :7016 accept-proxy
+    mode tcp
+    tcp-request content set-dst var(sess.dst)
+    default_backend d1_app_8080
+frontend _front_tcp_7017 from tcp
+    bind :7017
+    mode tcp
+    tcp-request content set-dst ipv4(10.0.0.10)
+    tcp-request content set-dst-port int(8443)
+    default_backend d1_app_8080
+frontend _front_tcp_7018 from tcp
+    bind :7018 accept-proxy
+    mode tcp
+    acl allow_rule_tcp src 10.0.0.0/8 192.168.0.0/16
+    acl allow_exception_tcp src 10.0.1.0/24
+    tcp-request session reject if allow_exception_tcp
+    tcp-request session reject if !allow_rule_tcp
+    default_backend d1_app_8080
 <<frontends-default>>
 <<support>>
 `)
@@ -2041,6 +3350,8 @@ local2 d2_app_8080
 local3 d3_app_8080`)
 	c.checkMap("_tcp_sni_7011__regex.map", `
 ^[^.]+\.local4$ d3_app_8080`)
+	c.checkMap("_tcp_sni_7014__exact.map", `
+local5 d2_app_8080`)
 	c.logger.CompareLogging(defaultLogging)
 }
 
@@ -2057,7 +3368,7 @@ func TestInstanceTCPBackend(t *testing.T) {
 				b.AddEndpoint("172.17.0.2", 5432)
 			},
 			expected: `
-listen _tcp_postgresql_5432
+listen _tcp_postgresql_5432 from tcp
     bind :5432
     mode tcp
     server srv001 172.17.0.2:5432`,
@@ -2071,7 +3382,7 @@ listen _tcp_postgresql_5432
 				b.CheckInterval = "2s"
 			},
 			expected: `
-listen _tcp_pq_5432
+listen _tcp_pq_5432 from tcp
     bind :5432
     mode tcp
     server srv001 172.17.0.2:5432 check port 5432 inter 2s
@@ -2086,7 +3397,7 @@ listen _tcp_pq_5432
 				b.ProxyProt.EncodeVersion = "v2"
 			},
 			expected: `
-listen _tcp_pq_5432
+listen _tcp_pq_5432 from tcp
     bind :5432 ssl crt /var/haproxy/ssl/pq.pem
     mode tcp
     server srv001 172.17.0.2:5432 send-proxy-v2`,
@@ -2103,7 +3414,7 @@ listen _tcp_pq_5432
 				c.config.Global().Bind.TCPBindIP = "127.0.0.1"
 			},
 			expected: `
-listen _tcp_pq_5432
+listen _tcp_pq_5432 from tcp
     bind 127.0.0.1:5432 ssl crt /var/haproxy/ssl/pq.pem accept-proxy
     mode tcp
     server srv001 172.17.0.2:5432 check port 5432 inter 2s send-proxy`,
@@ -2118,7 +3429,7 @@ listen _tcp_pq_5432
 				b.ProxyProt.EncodeVersion = "v2"
 			},
 			expected: `
-listen _tcp_pq_5432
+listen _tcp_pq_5432 from tcp
     bind :5432 ssl crt /var/haproxy/ssl/pq.pem ca-file /var/haproxy/ssl/pqca.pem verify required
     mode tcp
     server srv001 172.17.0.2:5432 send-proxy-v2`,
@@ -2134,7 +3445,7 @@ listen _tcp_pq_5432
 				b.ProxyProt.EncodeVersion = "v2"
 			},
 			expected: `
-listen _tcp_pq_5432
+listen _tcp_pq_5432 from tcp
     bind :5432 ssl crt /var/haproxy/ssl/pq.pem ca-file /var/haproxy/ssl/pqca.pem verify required crl-file /var/haproxy/ssl/pqcrl.pem
     mode tcp
     server srv001 172.17.0.2:5432 send-proxy-v2`,
@@ -2345,6 +3656,55 @@ d2.local#/ d2_app_8080
 `)
 	c.logger.CompareLogging(defaultLogging)
 }
+
+func TestInstanceEarlyData(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.TLS.EarlyData = true
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+<<backends-default>>
+<<frontend-http>>
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    http-request wait-for-handshake if { ssl_fc_early } !METH_GET !METH_OPTIONS
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+	c.checkMap("_front_bind_crt.list", `
+/var/haproxy/ssl/certs/default.pem !*
+/var/haproxy/ssl/certs/default.pem [allow-0rtt] d1.local
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
 func TestInstanceStrictHost(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
@@ -2549,131 +3909,249 @@ d2.local#/app -
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestInstanceFrontendCA(t *testing.T) {
+func TestInstanceFrontendDefaultCrt(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
-	def := c.config.Backends().AcquireBackend("default", "default-backend", "8080")
-	def.Endpoints = []*hatypes.Endpoint{endpointS0}
-	c.config.Backends().DefaultBackend = def
-
 	var h *hatypes.Host
 	var b *hatypes.Backend
 
-	b = c.config.Backends().AcquireBackend("d", "app", "8080")
-	h = c.config.Hosts().AcquireHost("*.d1.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.CAFilename = "/var/haproxy/ssl/ca/d1.local.pem"
-	h.TLS.CAHash = "1"
-	h.TLS.CAErrorPage = "http://d1.local/error.html"
-
-	h = c.config.Hosts().AcquireHost("d2.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.CAFilename = "/var/haproxy/ssl/ca/d2.local.pem"
-	h.TLS.CAHash = "2"
-	h.TLS.CRLFilename = "/var/haproxy/ssl/ca/d2.local.crl.pem"
-	h.TLS.CRLHash = "2"
-	h.TLS.CAErrorPage = "http://d2.local/error.html"
-
-	h = c.config.Hosts().AcquireHost("d3.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.Ciphers = "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384"
+	c.config.Frontend().DefaultCrtFile = "/var/haproxy/ssl/certs/fake-default.pem"
 
-	h = c.config.Hosts().AcquireHost("d4.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.CipherSuites = "TLS_CHACHA20_POLY1305_SHA256:TLS_AES_128_GCM_SHA256"
-
-	h = c.config.Hosts().AcquireHost("d5.local")
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.ALPN = "h2"
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/d1.pem"
+	h.TLS.TLSHash = "1"
 
-	h = c.config.Hosts().AcquireHost("d6.local")
+	h = c.config.Hosts().AcquireHost("d2.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.TLS.Options = "ssl-min-ver TLSv1.0 ssl-max-ver TLSv1.2"
-
-	for _, path := range b.Paths {
-		path.SSLRedirect = true
-	}
-	b.TLS.AddCertHeader = true
-	b.TLS.FingerprintLower = true
-	b.Endpoints = []*hatypes.Endpoint{endpointS1}
 
 	c.Update()
 	c.checkConfig(`
 <<global>>
 <<defaults>>
-backend d_app_8080
+backend d1_app_8080
     mode http
-    acl https-request ssl_fc
-    acl local-offload ssl_fc
-    http-request redirect scheme https if !https-request
-    http-request set-header X-SSL-Client-CN   %{+Q}[ssl_c_s_dn(cn)]
-    http-request set-header X-SSL-Client-DN   %{+Q}[ssl_c_s_dn]
-    http-request set-header X-SSL-Client-SHA1 %{+Q}[ssl_c_sha1,hex,lower]
-    http-request set-header X-SSL-Client-Cert %{+Q}[ssl_c_der,base64]
     server s1 172.17.0.11:8080 weight 100
-backend default_default-backend_8080
-    mode http
-    server s0 172.17.0.99:8080 weight 100
+<<backends-default>>
 frontend _front_http
     mode http
     bind :80
     <<set-req-base>>
     <<http-headers>>
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
-    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
-    default_backend default_default-backend_8080
+    default_backend _error404
 frontend _front_https
     mode http
     bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
     <<set-req-base>>
     http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
     <<https-headers>>
-    acl tls-has-crt ssl_c_used
-    acl tls-need-crt ssl_fc_sni -i -m str -f /etc/haproxy/maps/_front_tls_needcrt__exact.list
-    acl tls-need-crt ssl_fc_sni -i -m reg -f /etc/haproxy/maps/_front_tls_needcrt__regex.list
-    acl tls-host-need-crt var(req.host) -i -m str -f /etc/haproxy/maps/_front_tls_needcrt__exact.list
-    acl tls-host-need-crt var(req.host) -i -m reg -f /etc/haproxy/maps/_front_tls_needcrt__regex.list
-    acl tls-has-invalid-crt ssl_c_verify gt 0
-    acl tls-check-crt ssl_fc_sni -i -m str -f /etc/haproxy/maps/_front_tls_auth__exact.list
-    acl tls-check-crt ssl_fc_sni -i -m reg -f /etc/haproxy/maps/_front_tls_auth__regex.list
-    http-request set-var(req.snibase) ssl_fc_sni,lower,concat(\#,req.path)
-    http-request set-var(req.snibackend) var(req.snibase),lower,map_beg(/etc/haproxy/maps/_front_https_sni__begin.map)
-    http-request set-var(req.snibackend) var(req.snibase),map_reg(/etc/haproxy/maps/_front_https_sni__regex.map) if !{ var(req.snibackend) -m found }
-    http-request set-var(req.snibackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_sni__begin.map) if !{ var(req.snibackend) -m found } !tls-has-crt !tls-host-need-crt
-    http-request set-var(req.snibackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_sni__regex.map) if !{ var(req.snibackend) -m found } !tls-has-crt !tls-host-need-crt
-    http-request set-var(req.tls_nocrt_redir) ssl_fc_sni,lower,map_str(/etc/haproxy/maps/_front_tls_missingcrt_pages__exact.map,_internal) if !tls-has-crt tls-need-crt
-    http-request set-var(req.tls_nocrt_redir) ssl_fc_sni,lower,map_reg(/etc/haproxy/maps/_front_tls_missingcrt_pages__regex.map,_internal) if { var(req.tls_nocrt_redir) _internal }
-    http-request set-var(req.tls_invalidcrt_redir) ssl_fc_sni,lower,map_str(/etc/haproxy/maps/_front_tls_invalidcrt_pages__exact.map,_internal) if tls-has-invalid-crt tls-check-crt
-    http-request set-var(req.tls_invalidcrt_redir) ssl_fc_sni,lower,map_reg(/etc/haproxy/maps/_front_tls_invalidcrt_pages__regex.map,_internal) if { var(req.tls_invalidcrt_redir) _internal }
-    http-request redirect location %[var(req.tls_nocrt_redir)] code 303 if { var(req.tls_nocrt_redir) -m found } !{ var(req.tls_nocrt_redir) _internal }
-    http-request redirect location %[var(req.tls_invalidcrt_redir)] code 303 if { var(req.tls_invalidcrt_redir) -m found } !{ var(req.tls_invalidcrt_redir) _internal }
-    http-request use-service lua.send-421 if tls-has-crt { ssl_fc_has_sni } !{ ssl_fc_sni,strcmp(req.host) eq 0 }
-    http-request use-service lua.send-496 if { var(req.tls_nocrt_redir) _internal }
-    http-request use-service lua.send-421 if !tls-has-crt tls-host-need-crt
-    http-request use-service lua.send-495 if { var(req.tls_invalidcrt_redir) _internal }
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
-    use_backend %[var(req.snibackend)] if { var(req.snibackend) -m found }
-    default_backend default_default-backend_8080
+    default_backend _error404
 <<support>>
 `)
 
-	c.checkMap("_front_http_host__begin.map", `
-d2.local#/ d_app_8080
-d3.local#/ d_app_8080
+	c.checkMap("_front_bind_crt.list", `
+/var/haproxy/ssl/certs/fake-default.pem !*
+/var/haproxy/ssl/certs/d1.pem d1.local
+`)
+
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceFrontendCrtList(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/d1.pem"
+	h.TLS.TLSHash = "1"
+	h.TLS.ALPN = "h2"
+	h.TLS.MinVersion = "TLSv1.2"
+
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.MinVersion = "TLSv1.3"
+
+	h = c.config.Hosts().AcquireHost("d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	h = c.config.Hosts().AcquireHost("d4.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.ALPN = "http/1.1"
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_bind_crt.list", `
+/var/haproxy/ssl/certs/default.pem !*
+/var/haproxy/ssl/certs/d1.pem [alpn h2 ssl-min-ver TLSv1.2] d1.local
+/var/haproxy/ssl/certs/default.pem [ssl-min-ver TLSv1.3] d2.local
+/var/haproxy/ssl/certs/default.pem [alpn http/1.1] d4.local
+`)
+
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceFrontendCA(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	def := c.config.Backends().AcquireBackend("default", "default-backend", "8080")
+	def.Endpoints = []*hatypes.Endpoint{endpointS0}
+	c.config.Backends().DefaultBackend = def
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d", "app", "8080")
+	h = c.config.Hosts().AcquireHost("*.d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.CAFilename = "/var/haproxy/ssl/ca/d1.local.pem"
+	h.TLS.CAHash = "1"
+	h.TLS.CAErrorPage = "http://d1.local/error.html"
+
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.CAFilename = "/var/haproxy/ssl/ca/d2.local.pem"
+	h.TLS.CAHash = "2"
+	h.TLS.CRLFilename = "/var/haproxy/ssl/ca/d2.local.crl.pem"
+	h.TLS.CRLHash = "2"
+	h.TLS.CAErrorPage = "http://d2.local/error.html"
+
+	h = c.config.Hosts().AcquireHost("d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.Ciphers = "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384"
+
+	h = c.config.Hosts().AcquireHost("d4.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.CipherSuites = "TLS_CHACHA20_POLY1305_SHA256:TLS_AES_128_GCM_SHA256"
+
+	h = c.config.Hosts().AcquireHost("d5.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.ALPN = "h2"
+
+	h = c.config.Hosts().AcquireHost("d6.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.TLS.Options = "ssl-min-ver TLSv1.0 ssl-max-ver TLSv1.2"
+
+	for _, path := range b.Paths {
+		path.SSLRedirect = true
+	}
+	b.TLS.AddCertHeader = true
+	b.TLS.FingerprintLower = true
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d_app_8080
+    mode http
+    acl https-request ssl_fc
+    acl local-offload ssl_fc
+    http-request redirect scheme https if !https-request
+    http-request set-header X-SSL-Client-CN   %{+Q}[ssl_c_s_dn(cn)]
+    http-request set-header X-SSL-Client-DN   %{+Q}[ssl_c_s_dn]
+    http-request set-header X-SSL-Client-SHA1 %{+Q}[ssl_c_sha1,hex,lower]
+    http-request set-header X-SSL-Client-Cert %{+Q}[ssl_c_der,base64]
+    server s1 172.17.0.11:8080 weight 100
+backend default_default-backend_8080
+    mode http
+    server s0 172.17.0.99:8080 weight 100
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend default_default-backend_8080
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    acl tls-has-crt ssl_c_used
+    acl tls-need-crt ssl_fc_sni -i -m str -f /etc/haproxy/maps/_front_tls_needcrt__exact.list
+    acl tls-need-crt ssl_fc_sni -i -m reg -f /etc/haproxy/maps/_front_tls_needcrt__regex.list
+    acl tls-host-need-crt var(req.host) -i -m str -f /etc/haproxy/maps/_front_tls_needcrt__exact.list
+    acl tls-host-need-crt var(req.host) -i -m reg -f /etc/haproxy/maps/_front_tls_needcrt__regex.list
+    acl tls-has-invalid-crt ssl_c_verify gt 0
+    acl tls-check-crt ssl_fc_sni -i -m str -f /etc/haproxy/maps/_front_tls_auth__exact.list
+    acl tls-check-crt ssl_fc_sni -i -m reg -f /etc/haproxy/maps/_front_tls_auth__regex.list
+    http-request set-var(req.snibase) ssl_fc_sni,lower,concat(\#,req.path)
+    http-request set-var(req.snibackend) var(req.snibase),lower,map_beg(/etc/haproxy/maps/_front_https_sni__begin.map)
+    http-request set-var(req.snibackend) var(req.snibase),map_reg(/etc/haproxy/maps/_front_https_sni__regex.map) if !{ var(req.snibackend) -m found }
+    http-request set-var(req.snibackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_sni__begin.map) if !{ var(req.snibackend) -m found } !tls-has-crt !tls-host-need-crt
+    http-request set-var(req.snibackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_sni__regex.map) if !{ var(req.snibackend) -m found } !tls-has-crt !tls-host-need-crt
+    http-request set-var(req.tls_nocrt_redir) ssl_fc_sni,lower,map_str(/etc/haproxy/maps/_front_tls_missingcrt_pages__exact.map,_internal) if !tls-has-crt tls-need-crt
+    http-request set-var(req.tls_nocrt_redir) ssl_fc_sni,lower,map_reg(/etc/haproxy/maps/_front_tls_missingcrt_pages__regex.map,_internal) if { var(req.tls_nocrt_redir) _internal }
+    http-request set-var(req.tls_invalidcrt_redir) ssl_fc_sni,lower,map_str(/etc/haproxy/maps/_front_tls_invalidcrt_pages__exact.map,_internal) if tls-has-invalid-crt tls-check-crt
+    http-request set-var(req.tls_invalidcrt_redir) ssl_fc_sni,lower,map_reg(/etc/haproxy/maps/_front_tls_invalidcrt_pages__regex.map,_internal) if { var(req.tls_invalidcrt_redir) _internal }
+    http-request redirect location %[var(req.tls_nocrt_redir)] code 303 if { var(req.tls_nocrt_redir) -m found } !{ var(req.tls_nocrt_redir) _internal }
+    http-request redirect location %[var(req.tls_invalidcrt_redir)] code 303 if { var(req.tls_invalidcrt_redir) -m found } !{ var(req.tls_invalidcrt_redir) _internal }
+    http-request use-service lua.send-421 if tls-has-crt { ssl_fc_has_sni } !{ ssl_fc_sni,strcmp(req.host) eq 0 }
+    http-request use-service lua.send-496 if { var(req.tls_nocrt_redir) _internal }
+    http-request use-service lua.send-421 if !tls-has-crt tls-host-need-crt
+    http-request use-service lua.send-495 if { var(req.tls_invalidcrt_redir) _internal }
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    use_backend %[var(req.snibackend)] if { var(req.snibackend) -m found }
+    default_backend default_default-backend_8080
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__begin.map", `
+d2.local#/ d_app_8080
+d3.local#/ d_app_8080
 d4.local#/ d_app_8080
 d5.local#/ d_app_8080
 d6.local#/ d_app_8080
@@ -2963,6 +4441,53 @@ frontend _front_https
 	c.logger.CompareLogging(defaultLogging)
 }
 
+func TestInstanceGeoIP(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	c.config.Global().GeoIP.ActionMapFile = "/etc/haproxy/geoip/action.map"
+	c.config.Global().GeoIP.CountryMapFile = "/etc/haproxy/geoip/country.map"
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    http-request set-var(txn.country) src,map_ip(/etc/haproxy/geoip/country.map,-)
+    http-request deny if { var(txn.country),map(/etc/haproxy/geoip/action.map,allow) -m str deny }
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(txn.country) src,map_ip(/etc/haproxy/geoip/country.map,-)
+    http-request deny if { var(txn.country),map(/etc/haproxy/geoip/action.map,allow) -m str deny }
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
 func TestInstanceCustomSections(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
@@ -3011,7 +4536,7 @@ func TestInstanceCustomTCP(t *testing.T) {
 	c.checkConfig(`
 <<global>>
 <<defaults>>
-listen _tcp_default_postgresql_5432
+listen _tcp_default_postgresql_5432 from tcp
     bind :5432
     mode tcp
     ## custom for TCP
@@ -3074,7 +4599,7 @@ func TestInstanceCustomProxy(t *testing.T) {
 	c.checkConfig(`
 <<global>>
 <<defaults>>
-listen _tcp_default_pgsql_5432
+listen _tcp_default_pgsql_5432 from tcp
     bind :5432
     mode tcp
     ## custom for _tcp_default_pgsql_5432
@@ -3105,12 +4630,12 @@ frontend _front__auth
     bind 127.0.0.1:4001
     ## custom for _front__auth
     use_backend _auth_backend001_5000
-frontend _front_tcp_7001
+frontend _front_tcp_7001 from tcp
     bind :7001
     mode tcp
     ## custom for _front_tcp_7001
     default_backend d1_app_8080
-listen _front__tls
+listen _front__tls from tcp
     mode tcp
     bind :443
     tcp-request inspect-delay 5s
@@ -3122,147 +4647,1036 @@ listen _front__tls
 frontend _front_http
     mode http
     bind :80
-    <<set-req-base>>
-    <<http-headers>>
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    ## custom for _front_http
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind unix@/var/run/haproxy/_https_socket.sock accept-proxy ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    <<https-headers>>
+    ## custom for _front_https
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+listen stats
+    mode http
+    bind :1936
+    stats enable
+    stats uri /
+    no log
+    option httpclose
+    stats show-legends
+    ## custom for stats
+frontend healthz
+    mode http
+    bind :10253
+    monitor-uri /healthz
+    http-request use-service lua.send-404
+    no log
+    ## custom for healthz
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceSSLPassthrough(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	b.FindBackendPath(h.FindPath("/")[0].Link).SSLRedirect = true
+	b.Endpoints = []*hatypes.Endpoint{endpointS31}
+	// TODO should ingress converter configure mode tcp?
+	b.ModeTCP = true
+	h.SetSSLPassthrough(true)
+
+	b = c.config.Backends().AcquireBackend("d3", "app-ssl", "8443")
+	h = c.config.Hosts().AcquireHost("d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	b.Endpoints = []*hatypes.Endpoint{endpointS41s}
+	b.ModeTCP = true
+	h.SetSSLPassthrough(true)
+
+	b = c.config.Backends().AcquireBackend("d3", "app1-http", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
+	h.HTTPPassthroughBackend = b.ID
+
+	b = c.config.Backends().AcquireBackend("d3", "app2-http", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
+	h.AddPath(b, "/app", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d4", "app4-ssl", "8443")
+	h = c.config.Hosts().AcquireHost(hatypes.DefaultHost)
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	b.Endpoints = []*hatypes.Endpoint{endpointS41s}
+	b.ModeTCP = true
+	h.SetSSLPassthrough(true)
+
+	b = c.config.Backends().AcquireBackend("d4", "app4-http", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
+	h.HTTPPassthroughBackend = b.ID
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d2_app_8080 from tcp
+    mode tcp
+    server s31 172.17.0.131:8080 weight 100
+backend d3_app-ssl_8443 from tcp
+    mode tcp
+    server s41s 172.17.0.141:8443 weight 100
+backend d3_app1-http_8080
+    mode http
+    server s41h 172.17.0.141:8080 weight 100
+backend d3_app2-http_8080
+    mode http
+    server s41h 172.17.0.141:8080 weight 100
+backend d4_app4-http_8080
+    mode http
+    server s41h 172.17.0.141:8080 weight 100
+backend d4_app4-ssl_8443 from tcp
+    mode tcp
+    server s41s 172.17.0.141:8443 weight 100
+backend _redirect_https
+    mode http
+    http-request redirect scheme https
+<<backends-default>>
+listen _front__tls from tcp
+    mode tcp
+    bind :443
+    tcp-request inspect-delay 5s
+    tcp-request content set-var(req.sslpassback) req.ssl_sni,lower,map_str(/etc/haproxy/maps/_front_sslpassthrough__exact.map)
+    tcp-request content accept if { req.ssl_hello_type 1 }
+    use_backend %[var(req.sslpassback)] if { var(req.sslpassback) -m found }
+    use_backend d4_app4-ssl_8443
+    server _default_server_https_socket unix@/var/run/haproxy/_https_socket.sock send-proxy-v2
+<<frontend-http>>
+    use_backend d4_app4-http_8080
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind unix@/var/run/haproxy/_https_socket.sock accept-proxy ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_sslpassthrough__exact.map", `
+d2.local d2_app_8080
+d3.local d3_app-ssl_8443
+`)
+	c.checkMap("_front_http_host__begin.map", `
+d2.local#/ _redirect_https
+d3.local#/app d3_app2-http_8080
+d3.local#/ d3_app1-http_8080
+`)
+	c.checkMap("_front_bind_crt.list", `
+/var/haproxy/ssl/certs/default.pem !*
+`)
+
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceRootRedirect(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	h = c.config.Hosts().AcquireHost("*.d1.local")
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.RootRedirect = "/app"
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.AddPath(b, "/app1", hatypes.MatchBegin)
+	h.AddPath(b, "/app2", hatypes.MatchBegin)
+	h.RootRedirect = "/app1"
+	for _, path := range b.Paths {
+		path.SSLRedirect = true
+	}
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+
+	c.Update()
+
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    acl https-request ssl_fc
+    http-request redirect scheme https if !https-request
+    server s21 172.17.0.121:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    http-request set-var(req.rootredir) var(req.host),map_str(/etc/haproxy/maps/_front_redir_fromroot__exact.map)
+    http-request set-var(req.rootredir) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_fromroot__regex.map) if !{ var(req.rootredir) -m found }
+    http-request redirect location %[var(req.rootredir)] if { path / } { var(req.rootredir) -m found }
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request set-var(req.hostbackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_host__regex.map) if !{ var(req.hostbackend) -m found }
+    http-request set-var(req.rootredir) var(req.host),map_str(/etc/haproxy/maps/_front_redir_fromroot__exact.map)
+    http-request set-var(req.rootredir) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_fromroot__regex.map) if !{ var(req.rootredir) -m found }
+    http-request redirect location %[var(req.rootredir)] if { path / } { var(req.rootredir) -m found }
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__regex.map", `
+^[^.]+\.d1\.local#/ d1_app_8080
+`)
+	c.checkMap("_front_http_host__begin.map", `
+d2.local#/app2 d2_app_8080
+d2.local#/app1 d2_app_8080
+`)
+	c.checkMap("_front_http_host__regex.map", `
+^[^.]+\.d1\.local#/ d1_app_8080
+`)
+	c.checkMap("_front_redir_fromroot__exact.map", `
+d2.local /app1
+`)
+	c.checkMap("_front_redir_fromroot__regex.map", `
+^[^.]+\.d1\.local$ /app
+`)
+	c.checkMap("_front_https_host__begin.map", `
+d2.local#/app2 d2_app_8080
+d2.local#/app1 d2_app_8080
+`)
+	c.checkMap("_front_https_host__regex.map", `
+^[^.]+\.d1\.local#/ d1_app_8080
+`)
+
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceRedirectWWW(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.Redirect.WWW = true
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	h = c.config.Hosts().AcquireHost("www.d2.local")
+	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
+	h.TLS.TLSHash = "0"
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.Redirect.WWW = true
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+
+	c.Update()
+
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    http-request redirect prefix //%[var(req.host),regsub(^www[.],)] code 301 if { var(req.host) -m str -f /etc/haproxy/maps/_front_redir_toapex__exact.list }
+    http-request redirect prefix //www.%[var(req.host)] code 301 if { var(req.host) -m str -f /etc/haproxy/maps/_front_redir_towww__exact.list }
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request redirect prefix //%[var(req.host),regsub(^www[.],)] code 301 if { var(req.host) -m str -f /etc/haproxy/maps/_front_redir_toapex__exact.list }
+    http-request redirect prefix //www.%[var(req.host)] code 301 if { var(req.host) -m str -f /etc/haproxy/maps/_front_redir_towww__exact.list }
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_redir_toapex__exact.list", `
+www.d1.local
+`)
+	c.checkMap("_front_redir_towww__exact.list", `
+d2.local
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceAlias(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.Alias.AliasName = "*.d1.local"
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.Alias.AliasName = "sub.d2.local"
+	h.Alias.AliasRegex = "^[a-z]+\\.d2\\.local$"
+
+	b = c.config.Backends().AcquireBackend("d3", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS31}
+	h = c.config.Hosts().AcquireHost("d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.Alias.AliasRegex = "d3\\.local$"
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+backend d3_app_8080
+    mode http
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request set-var(req.hostbackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_host__regex.map) if !{ var(req.hostbackend) -m found }
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__begin.map", `
+d1.local#/ d1_app_8080
+d2.local#/ d2_app_8080
+d3.local#/ d3_app_8080
+sub.d2.local#/ d2_app_8080
+`)
+	c.checkMap("_front_https_host__begin.map", `
+d1.local#/ d1_app_8080
+d2.local#/ d2_app_8080
+d3.local#/ d3_app_8080
+sub.d2.local#/ d2_app_8080
+`)
+	c.checkMap("_front_https_host__regex.map", `
+^[a-z]+\.d2\.local#/ d2_app_8080
+^[^.]+\.d1\.local#/ d1_app_8080
+d3\.local#/ d3_app_8080
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceHeaderMatch(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/api", hatypes.MatchPrefix)
+	b.FindBackendPath(h.FindPath("/api")[0].Link).HeaderMatch = hatypes.HeaderMatch{Name: "X-Canary"}
+
+	b = c.config.Backends().AcquireBackend("d3", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS31}
+	h = c.config.Hosts().AcquireHost("*.d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	b.FindBackendPath(h.FindPath("/")[0].Link).HeaderMatch = hatypes.HeaderMatch{Name: "X-Version", Value: "v2"}
+	h.AddPath(b, "/app", hatypes.MatchExact)
+	b.FindBackendPath(h.FindPath("/app")[0].Link).HeaderMatch = hatypes.HeaderMatch{Name: "X-Version", Value: "^v[34]$", Regex: true}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+backend d3_app_8080
+    mode http
+    # path01 = *.d3.local/
+    # path02 = *.d3.local/app
+    http-request set-var(txn.pathID) var(req.base),map_reg(/etc/haproxy/maps/_back_d3_app_8080_idpath__regex.map)
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    acl header_match01 req.hdr(X-Version) -m reg ^v[34]$
+    use_backend d3_app_8080 if { var(req.base) -m reg ^[^.]+\.d3\.local#/app$ } header_match01
+    acl header_match02 req.hdr(X-Version) -m str v2
+    use_backend d3_app_8080 if { var(req.base) -m reg ^[^.]+\.d3\.local#/ } header_match02
+    acl header_match03 req.hdr(X-Canary) -m found
+    use_backend d2_app_8080 if { var(req.base) -m reg ^d1\.local#/api(/.*)? } header_match03
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    acl header_match01 req.hdr(X-Version) -m reg ^v[34]$
+    use_backend d3_app_8080 if { var(req.base) -m reg ^[^.]+\.d3\.local#/app$ } header_match01
+    acl header_match02 req.hdr(X-Version) -m str v2
+    use_backend d3_app_8080 if { var(req.base) -m reg ^[^.]+\.d3\.local#/ } header_match02
+    acl header_match03 req.hdr(X-Canary) -m found
+    use_backend d2_app_8080 if { var(req.base) -m reg ^d1\.local#/api(/.*)? } header_match03
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__begin.map", `
+d1.local#/ d1_app_8080
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceStaticResponse(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+	var bp *hatypes.BackendPath
+
+	c.config.Userlists().Replace("default_auth1", []hatypes.User{{Name: "usr1", Passwd: "clear1"}})
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.AddPath(b, "/robots.txt", hatypes.MatchExact)
+	bp = b.FindBackendPath(h.FindPath("/robots.txt")[0].Link)
+	bp.StaticResponse = hatypes.StaticResponse{Status: 200, ContentType: "text/plain", Body: "User-agent: * Disallow: /"}
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	h.AddPath(b, "/admin", hatypes.MatchPrefix)
+	bp = b.FindBackendPath(h.FindPath("/admin")[0].Link)
+	bp.AuthHTTP = hatypes.AuthHTTP{UserlistName: "default_auth1", Realm: "admin"}
+	bp.StaticResponse = hatypes.StaticResponse{Status: 503, ContentType: "text/plain", Body: "maintenance"}
+
+	b = c.config.Backends().AcquireBackend("d3", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS31}
+	h.AddPath(b, "/health", hatypes.MatchPrefix)
+	bp = b.FindBackendPath(h.FindPath("/health")[0].Link)
+	bp.AllowedIPHTTP = hatypes.AccessConfig{Rule: []string{"10.0.0.0/8"}}
+	bp.SSLRedirect = true
+	bp.StaticResponse = hatypes.StaticResponse{Status: 200, ContentType: "application/json", File: "/etc/haproxy/static/health.json"}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+userlist default_auth1
+    user usr1 insecure-password clear1
+backend d1_app_8080
+    mode http
+    # path01 = d1.local/
+    # path02 = d1.local/robots.txt
+    http-request set-var(txn.pathID) var(req.base),map_str(/etc/haproxy/maps/_back_d1_app_8080_idpath__exact.map)
+    http-request set-var(txn.pathID) var(req.base),lower,map_beg(/etc/haproxy/maps/_back_d1_app_8080_idpath__begin.map) if !{ var(txn.pathID) -m found }
+    http-request return status 200 content-type "text/plain" string "User-agent: * Disallow: /" if { var(txn.pathID) path02 }
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    http-request auth realm "admin" if !{ http_auth(default_auth1) }
+    http-request return status 503 content-type "text/plain" string "maintenance"
+    server s21 172.17.0.121:8080 weight 100
+backend d3_app_8080
+    mode http
+    acl https-request ssl_fc
+    http-request redirect scheme https if !https-request
+    acl allow_rule_src0 src 10.0.0.0/8
+    http-request deny if !allow_rule_src0
+    http-request return status 200 content-type "application/json" file /etc/haproxy/static/health.json
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),map_str(/etc/haproxy/maps/_front_http_host__exact.map)
+    http-request set-var(req.backend) var(req.base),map_dir(/etc/haproxy/maps/_front_http_host__prefix_02.map) if !{ var(req.backend) -m found }
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map) if !{ var(req.backend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),map_str(/etc/haproxy/maps/_front_https_host__exact.map)
+    http-request set-var(req.hostbackend) var(req.base),map_dir(/etc/haproxy/maps/_front_https_host__prefix_02.map) if !{ var(req.hostbackend) -m found }
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map) if !{ var(req.hostbackend) -m found }
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_http_host__exact.map", `
+d1.local#/robots.txt d1_app_8080
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceBucket(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	b = c.config.Backends().AcquireBackend("d1", "app-v2", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	h.Buckets = []*hatypes.HostBucket{
+		{First: 0, Last: 1, Backend: "d1_app_8080"},
+		{First: 2, Last: 3, Backend: "d1_app-v2_8080"},
+	}
+	c.config.Global().Bucket.Cookie = "sessid"
+	c.config.Global().Bucket.Count = 4
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app-v2_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    http-request set-var(txn.bucket) req.cook(sessid),crc32(1),mod(4) if { req.cook(sessid) -m found }
+    http-request set-var(req.bucketbackend) var(req.host),concat(\#,txn.bucket),map_str(/etc/haproxy/maps/_front_bucket__exact.map) if { var(txn.bucket) -m found }
+    use_backend %[var(req.bucketbackend)] if { var(req.bucketbackend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request set-var(txn.bucket) req.cook(sessid),crc32(1),mod(4) if { req.cook(sessid) -m found }
+    http-request set-var(req.bucketbackend) var(req.host),concat(\#,txn.bucket),map_str(/etc/haproxy/maps/_front_bucket__exact.map) if { var(txn.bucket) -m found }
+    <<https-headers>>
+    use_backend %[var(req.bucketbackend)] if { var(req.bucketbackend) -m found }
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+
+	c.checkMap("_front_bucket__exact.map", `
+d1.local#0 d1_app_8080
+d1.local#1 d1_app_8080
+d1.local#2 d1_app-v2_8080
+d1.local#3 d1_app-v2_8080
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceRedirectFrom(t *testing.T) {
+	testCases := []struct {
+		data     [3]hatypes.HostRedirectConfig
+		code     int
+		expHTTP  string
+		expHTTPS string
+		expMaps  map[string]string
+	}{
+		// 0
+		{
+			data: [3]hatypes.HostRedirectConfig{
+				{RedirectHost: "*.d1.local"},
+			},
+			code: 301,
+			expHTTP: `
+    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.backend) -m found }
+    http-request redirect prefix //%[var(req.redirdest)] code 301 if { var(req.redirdest) -m found }`,
+			expHTTPS: `
+    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.hostbackend) -m found }
+    http-request redirect prefix //%[var(req.redirdest)] code 301 if { var(req.redirdest) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_from__regex.map": `
+^[^.]+\.d1\.local$ d1.local
+`,
+			},
+		},
+		// 1
+		{
+			data: [3]hatypes.HostRedirectConfig{
+				{RedirectHost: "*.d1.local"},
+				{RedirectHost: "sub.d2.local", RedirectHostRegex: "^[a-z]+\\.d2\\.local$"},
+				{RedirectHostRegex: "\\.d3\\.local$"},
+			},
+			expHTTP: `
+    http-request set-var(req.redirdest) var(req.host),map_str(/etc/haproxy/maps/_front_redir_from__exact.map) if !{ var(req.backend) -m found }
+    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.backend) -m found } !{ var(req.redirdest) -m found }
+    http-request redirect prefix //%[var(req.redirdest)] code 302 if { var(req.redirdest) -m found }`,
+			expHTTPS: `
+    http-request set-var(req.redirdest) var(req.host),map_str(/etc/haproxy/maps/_front_redir_from__exact.map) if !{ var(req.hostbackend) -m found }
+    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.hostbackend) -m found } !{ var(req.redirdest) -m found }
+    http-request redirect prefix //%[var(req.redirdest)] code 302 if { var(req.redirdest) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_from__exact.map": `
+sub.d2.local d2.local
+`,
+				"_front_redir_from__regex.map": `
+^[a-z]+\.d2\.local$ d2.local
+^[^.]+\.d1\.local$ d1.local
+\.d3\.local$ d3.local
+`,
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		c := setup(t)
+		defer c.teardown()
+
+		var h *hatypes.Host
+		var b *hatypes.Backend
+
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+		h.Redirect = test.data[0]
+
+		b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS21}
+		h = c.config.Hosts().AcquireHost("d2.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+		h.Redirect = test.data[1]
+
+		b = c.config.Backends().AcquireBackend("d3", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS31}
+		h = c.config.Hosts().AcquireHost("d3.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+		h.Redirect = test.data[2]
+
+		if test.code != 0 {
+			c.config.frontend.RedirectFromCode = test.code
+		} else {
+			c.config.frontend.RedirectFromCode = 302
+		}
+
+		c.Update()
+		c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+backend d3_app_8080
+    mode http
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    http-request set-var(req.path) path
+    http-request set-var(req.host) hdr(host),field(1,:),lower
+    http-request set-var(req.base) var(req.host),concat(\#,req.path)
+    http-request set-header X-Forwarded-Proto http
+    http-request del-header X-SSL-Client-CN
+    http-request del-header X-SSL-Client-DN
+    http-request del-header X-SSL-Client-SHA1
+    http-request del-header X-SSL-Client-Cert
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)` + test.expHTTP + `
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    http-request set-var(req.path) path
+    http-request set-var(req.host) hdr(host),field(1,:),lower
+    http-request set-var(req.base) var(req.host),concat(\#,req.path)
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)` + test.expHTTPS + `
+    http-request set-header X-Forwarded-Proto https
+    http-request del-header X-SSL-Client-CN
+    http-request del-header X-SSL-Client-DN
+    http-request del-header X-SSL-Client-SHA1
+    http-request del-header X-SSL-Client-Cert
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+		for file, content := range test.expMaps {
+			c.checkMap(file, content)
+		}
+		c.logger.CompareLogging(defaultLogging)
+	}
+}
+
+func TestInstanceRedirectTo(t *testing.T) {
+	testCases := []struct {
+		to       [3]string
+		code     int
+		expected string
+		expMaps  map[string]string
+	}{
+		// 0
+		{
+			to: [3]string{
+				"https://app.local",
+			},
+			expected: `
+    http-request set-var(req.redirto) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_redir_to__begin.map)
+    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_to__begin.map": `
+d1.local#/ https://app.local
+`,
+			},
+		},
+		// 1
+		{
+			to: [3]string{
+				"https://app.local/login",
+				"https://app.local/app2",
+			},
+			expected: `
+    http-request set-var(req.redirto) var(req.base),map_dir(/etc/haproxy/maps/_front_redir_to__prefix_01.map)
+    http-request set-var(req.redirto) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_redir_to__begin.map) if !{ var(req.redirto) -m found }
+    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_to__prefix_01.map": `
+d1.local#/app2 https://app.local/app2
+`,
+				"_front_redir_to__begin.map": `
+d1.local#/ https://app.local/login
+`,
+			},
+		},
+		// 2
+		{
+			to: [3]string{
+				"",
+				"https://app.local/app2",
+				"https://app.local/app3",
+			},
+			expected: `
+    http-request set-var(req.redirto) var(req.base),map_dir(/etc/haproxy/maps/_front_redir_to__prefix.map)
+    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_to__prefix.map": `
+d1.local#/app3 https://app.local/app3
+d1.local#/app2 https://app.local/app2
+`,
+			},
+		},
+		// 3
+		{
+			to: [3]string{
+				"https://app.local",
+			},
+			code: 308,
+			expected: `
+    http-request set-var(req.redirto) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_redir_to__begin.map)
+    http-request redirect location %[var(req.redirto)] code 308 if { var(req.redirto) -m found }`,
+			expMaps: map[string]string{
+				"_front_redir_to__begin.map": `
+d1.local#/ https://app.local
+`,
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		c := setup(t)
+		defer c.teardown()
+
+		var h *hatypes.Host
+		var b *hatypes.Backend
+
+		h = c.config.Hosts().AcquireHost("d1.local")
+
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		if test.to[0] != "" {
+			h.AddRedirect("/", hatypes.MatchBegin, test.to[0])
+		} else {
+			h.AddPath(b, "/", hatypes.MatchBegin)
+		}
+
+		b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS21}
+		if test.to[1] != "" {
+			h.AddRedirect("/app2", hatypes.MatchPrefix, test.to[1])
+		} else {
+			h.AddPath(b, "/app2", hatypes.MatchBegin)
+		}
+
+		b = c.config.Backends().AcquireBackend("d3", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS31}
+		if test.to[2] != "" {
+			h.AddRedirect("/app3", hatypes.MatchPrefix, test.to[2])
+		} else {
+			h.AddPath(b, "/app3", hatypes.MatchBegin)
+		}
+
+		if test.code != 0 {
+			c.config.frontend.RedirectToCode = test.code
+		} else {
+			c.config.frontend.RedirectToCode = 302
+		}
+
+		c.Update()
+		c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    server s21 172.17.0.121:8080 weight 100
+backend d3_app_8080
+    mode http
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    http-request set-var(req.path) path
+    http-request set-var(req.host) hdr(host),field(1,:),lower
+    http-request set-var(req.base) var(req.host),concat(\#,req.path)
+    http-request set-header X-Forwarded-Proto http
+    http-request del-header X-SSL-Client-CN
+    http-request del-header X-SSL-Client-DN
+    http-request del-header X-SSL-Client-SHA1
+    http-request del-header X-SSL-Client-Cert` + test.expected + `
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
-    ## custom for _front_http
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
-    bind unix@/var/run/haproxy/_https_socket.sock accept-proxy ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
-    <<set-req-base>>
-    <<https-headers>>
-    ## custom for _front_https
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    http-request set-var(req.path) path
+    http-request set-var(req.host) hdr(host),field(1,:),lower
+    http-request set-var(req.base) var(req.host),concat(\#,req.path)` + test.expected + `
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request set-header X-Forwarded-Proto https
+    http-request del-header X-SSL-Client-CN
+    http-request del-header X-SSL-Client-DN
+    http-request del-header X-SSL-Client-SHA1
+    http-request del-header X-SSL-Client-Cert
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
-listen stats
-    mode http
-    bind :1936
-    stats enable
-    stats uri /
-    no log
-    option httpclose
-    stats show-legends
-    ## custom for stats
-frontend healthz
-    mode http
-    bind :10253
-    monitor-uri /healthz
-    http-request use-service lua.send-404
-    no log
-    ## custom for healthz
+<<support>>
 `)
-	c.logger.CompareLogging(defaultLogging)
+		for file, content := range test.expMaps {
+			c.checkMap(file, content)
+		}
+		c.logger.CompareLogging(defaultLogging)
+	}
 }
 
-func TestInstanceSSLPassthrough(t *testing.T) {
+func TestInstanceSyslog(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
 	var h *hatypes.Host
 	var b *hatypes.Backend
 
-	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
-	h = c.config.Hosts().AcquireHost("d2.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	b.FindBackendPath(h.FindPath("/")[0].Link).SSLRedirect = true
-	b.Endpoints = []*hatypes.Endpoint{endpointS31}
-	// TODO should ingress converter configure mode tcp?
-	b.ModeTCP = true
-	h.SetSSLPassthrough(true)
-
-	b = c.config.Backends().AcquireBackend("d3", "app-ssl", "8443")
-	h = c.config.Hosts().AcquireHost("d3.local")
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
-	b.Endpoints = []*hatypes.Endpoint{endpointS41s}
-	b.ModeTCP = true
-	h.SetSSLPassthrough(true)
-
-	b = c.config.Backends().AcquireBackend("d3", "app1-http", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
-	h.HTTPPassthroughBackend = b.ID
 
-	b = c.config.Backends().AcquireBackend("d3", "app2-http", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
-	h.AddPath(b, "/app", hatypes.MatchBegin)
+	tcpPort1, tcpHost1 := c.config.tcpservices.AcquireTCPService("<default>:7001")
+	tcpPort1.LogFormat = "default"
+	tcpHost1.Backend = b.BackendID()
 
-	b = c.config.Backends().AcquireBackend("d4", "app4-ssl", "8443")
-	h = c.config.Hosts().AcquireHost(hatypes.DefaultHost)
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	b.Endpoints = []*hatypes.Endpoint{endpointS41s}
-	b.ModeTCP = true
-	h.SetSSLPassthrough(true)
+	tcpPort2, tcpHost2 := c.config.tcpservices.AcquireTCPService("<default>:7002")
+	tcpPort2.LogFormat = "%[src]"
+	tcpHost2.Backend = b.BackendID()
 
-	b = c.config.Backends().AcquireBackend("d4", "app4-http", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS41h}
-	h.HTTPPassthroughBackend = b.ID
+	syslog := &c.config.Global().Syslog
+	syslog.Endpoint = "127.0.0.1:1514"
+	syslog.Format = "rfc3164"
+	syslog.Length = 2048
+	syslog.Tag = "ingress"
 
 	c.Update()
 	c.checkConfig(`
-<<global>>
-<<defaults>>
-backend d2_app_8080
-    mode tcp
-    server s31 172.17.0.131:8080 weight 100
-backend d3_app-ssl_8443
-    mode tcp
-    server s41s 172.17.0.141:8443 weight 100
-backend d3_app1-http_8080
-    mode http
-    server s41h 172.17.0.141:8080 weight 100
-backend d3_app2-http_8080
-    mode http
-    server s41h 172.17.0.141:8080 weight 100
-backend d4_app4-http_8080
-    mode http
-    server s41h 172.17.0.141:8080 weight 100
-backend d4_app4-ssl_8443
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    log 127.0.0.1:1514 len 2048 format rfc3164 local0
+    log-tag ingress
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+defaults tcp
+    log global
+    maxconn 2000
     mode tcp
-    server s41s 172.17.0.141:8443 weight 100
-backend _redirect_https
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
+    log global
+    maxconn 2000
+    option redispatch
+    option dontlognull
+    option http-server-close
+    option http-keep-alive
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout http-keep-alive 1m
+    timeout http-request    5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+backend d1_app_8080
     mode http
-    http-request redirect scheme https
+    server s1 172.17.0.11:8080 weight 100
 <<backends-default>>
-listen _front__tls
+frontend _front_tcp_7001 from tcp
+    bind :7001
     mode tcp
-    bind :443
-    tcp-request inspect-delay 5s
-    tcp-request content set-var(req.sslpassback) req.ssl_sni,lower,map_str(/etc/haproxy/maps/_front_sslpassthrough__exact.map)
-    tcp-request content accept if { req.ssl_hello_type 1 }
-    use_backend %[var(req.sslpassback)] if { var(req.sslpassback) -m found }
-    use_backend d4_app4-ssl_8443
-    server _default_server_https_socket unix@/var/run/haproxy/_https_socket.sock send-proxy-v2
-<<frontend-http>>
-    use_backend d4_app4-http_8080
+    option tcplog
+    default_backend d1_app_8080
+frontend _front_tcp_7002 from tcp
+    bind :7002
+    mode tcp
+    log-format %[src]
+    default_backend d1_app_8080
+frontend _front_http
+    mode http
+    bind :80
+    option httplog
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
-    bind unix@/var/run/haproxy/_https_socket.sock accept-proxy ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    option httplog
     <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
     <<https-headers>>
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
 <<support>>
 `)
-
-	c.checkMap("_front_sslpassthrough__exact.map", `
-d2.local d2_app_8080
-d3.local d3_app-ssl_8443
-`)
-	c.checkMap("_front_http_host__begin.map", `
-d2.local#/ _redirect_https
-d3.local#/app d3_app2-http_8080
-d3.local#/ d3_app1-http_8080
-`)
-	c.checkMap("_front_bind_crt.list", `
-/var/haproxy/ssl/certs/default.pem !*
-`)
-
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestInstanceRootRedirect(t *testing.T) {
+func TestInstanceSyslogRing(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
@@ -3270,258 +5684,270 @@ func TestInstanceRootRedirect(t *testing.T) {
 	var b *hatypes.Backend
 
 	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
-	h = c.config.Hosts().AcquireHost("*.d1.local")
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.RootRedirect = "/app"
 	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
 
-	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
-	h = c.config.Hosts().AcquireHost("d2.local")
-	h.TLS.TLSFilename = "/var/haproxy/ssl/certs/default.pem"
-	h.TLS.TLSHash = "0"
-	h.AddPath(b, "/app1", hatypes.MatchBegin)
-	h.AddPath(b, "/app2", hatypes.MatchBegin)
-	h.RootRedirect = "/app1"
-	for _, path := range b.Paths {
-		path.SSLRedirect = true
-	}
-	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	syslog := &c.config.Global().Syslog
+	syslog.Endpoint = "127.0.0.1:1514"
+	syslog.Format = "rfc3164"
+	syslog.Length = 2048
+	syslog.Tag = "ingress"
+	syslog.Ring = hatypes.SyslogRing{Name: "syslog", Size: 32768}
 
 	c.Update()
-
 	c.checkConfig(`
-<<global>>
-<<defaults>>
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    log ring@syslog len 2048 format rfc3164 local0
+    log-tag ingress
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+ring syslog
+    description "HAProxy logs"
+    format rfc3164
+    maxlen 2048
+    size 32768
+    server syslog 127.0.0.1:1514
+defaults tcp
+    log global
+    maxconn 2000
+    mode tcp
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
+    log global
+    maxconn 2000
+    option redispatch
+    option dontlognull
+    option http-server-close
+    option http-keep-alive
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout http-keep-alive 1m
+    timeout http-request    5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
 backend d1_app_8080
     mode http
     server s1 172.17.0.11:8080 weight 100
-backend d2_app_8080
-    mode http
-    acl https-request ssl_fc
-    http-request redirect scheme https if !https-request
-    server s21 172.17.0.121:8080 weight 100
 <<backends-default>>
 frontend _front_http
     mode http
     bind :80
+    option httplog
     <<set-req-base>>
-    http-request set-var(req.rootredir) var(req.host),map_str(/etc/haproxy/maps/_front_redir_fromroot__exact.map)
-    http-request set-var(req.rootredir) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_fromroot__regex.map) if !{ var(req.rootredir) -m found }
-    http-request redirect location %[var(req.rootredir)] if { path / } { var(req.rootredir) -m found }
     <<http-headers>>
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
-    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
     bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    option httplog
     <<set-req-base>>
     http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
-    http-request set-var(req.hostbackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_host__regex.map) if !{ var(req.hostbackend) -m found }
-    http-request set-var(req.rootredir) var(req.host),map_str(/etc/haproxy/maps/_front_redir_fromroot__exact.map)
-    http-request set-var(req.rootredir) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_fromroot__regex.map) if !{ var(req.rootredir) -m found }
-    http-request redirect location %[var(req.rootredir)] if { path / } { var(req.rootredir) -m found }
     <<https-headers>>
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
 <<support>>
 `)
-
-	c.checkMap("_front_http_host__regex.map", `
-^[^.]+\.d1\.local#/ d1_app_8080
-`)
-	c.checkMap("_front_http_host__begin.map", `
-d2.local#/app2 d2_app_8080
-d2.local#/app1 d2_app_8080
-`)
-	c.checkMap("_front_http_host__regex.map", `
-^[^.]+\.d1\.local#/ d1_app_8080
-`)
-	c.checkMap("_front_redir_fromroot__exact.map", `
-d2.local /app1
-`)
-	c.checkMap("_front_redir_fromroot__regex.map", `
-^[^.]+\.d1\.local$ /app
-`)
-	c.checkMap("_front_https_host__begin.map", `
-d2.local#/app2 d2_app_8080
-d2.local#/app1 d2_app_8080
-`)
-	c.checkMap("_front_https_host__regex.map", `
-^[^.]+\.d1\.local#/ d1_app_8080
-`)
-
 	c.logger.CompareLogging(defaultLogging)
 }
 
-func TestInstanceAlias(t *testing.T) {
-	c := setup(t)
-	defer c.teardown()
+func TestInstanceDontLogNull(t *testing.T) {
+	testCases := []struct {
+		dontlognull bool
+		expected    string
+	}{
+		// 0
+		{
+			dontlognull: true,
+			expected: `
+    option redispatch
+    option dontlognull`,
+		},
+		// 1
+		{
+			dontlognull: false,
+			expected: `
+    option redispatch`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
 
-	var h *hatypes.Host
-	var b *hatypes.Backend
+		var h *hatypes.Host
+		var b *hatypes.Backend
 
-	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS1}
-	h = c.config.Hosts().AcquireHost("d1.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.Alias.AliasName = "*.d1.local"
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
 
-	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS21}
-	h = c.config.Hosts().AcquireHost("d2.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.Alias.AliasName = "sub.d2.local"
-	h.Alias.AliasRegex = "^[a-z]+\\.d2\\.local$"
+		c.config.Global().Syslog.DontLogNull = test.dontlognull
 
-	b = c.config.Backends().AcquireBackend("d3", "app", "8080")
-	b.Endpoints = []*hatypes.Endpoint{endpointS31}
-	h = c.config.Hosts().AcquireHost("d3.local")
-	h.AddPath(b, "/", hatypes.MatchBegin)
-	h.Alias.AliasRegex = "d3\\.local$"
+		c.Update()
+		c.checkConfig(`
+<<global>>
+defaults tcp
+    log global
+    maxconn 2000
+    mode tcp` + test.expected + `
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
+    log global
+    maxconn 2000` + test.expected + `
+    option http-server-close
+    option http-keep-alive
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout http-keep-alive 1m
+    timeout http-request    5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
 
-	c.Update()
-	c.checkConfig(`
+func TestInstanceHTTPIgnoreProbes(t *testing.T) {
+	testCases := []struct {
+		ignoreProbes bool
+		expected     string
+	}{
+		// 0
+		{
+			ignoreProbes: false,
+			expected:     "",
+		},
+		// 1
+		{
+			ignoreProbes: true,
+			expected: `
+    option http-ignore-probes`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		var h *hatypes.Host
+		var b *hatypes.Backend
+
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+
+		c.config.Frontend().HTTPIgnoreProbes = test.ignoreProbes
+
+		c.Update()
+		c.checkConfig(`
 <<global>>
 <<defaults>>
 backend d1_app_8080
     mode http
     server s1 172.17.0.11:8080 weight 100
-backend d2_app_8080
-    mode http
-    server s21 172.17.0.121:8080 weight 100
-backend d3_app_8080
-    mode http
-    server s31 172.17.0.131:8080 weight 100
 <<backends-default>>
 frontend _front_http
     mode http
-    bind :80
+    bind :80` + test.expected + `
     <<set-req-base>>
     <<http-headers>>
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
-    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
-    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all` + test.expected + `
     <<set-req-base>>
     http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
-    http-request set-var(req.hostbackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_host__regex.map) if !{ var(req.hostbackend) -m found }
     <<https-headers>>
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
 <<support>>
 `)
-
-	c.checkMap("_front_http_host__begin.map", `
-d1.local#/ d1_app_8080
-d2.local#/ d2_app_8080
-d3.local#/ d3_app_8080
-sub.d2.local#/ d2_app_8080
-`)
-	c.checkMap("_front_https_host__begin.map", `
-d1.local#/ d1_app_8080
-d2.local#/ d2_app_8080
-d3.local#/ d3_app_8080
-sub.d2.local#/ d2_app_8080
-`)
-	c.checkMap("_front_https_host__regex.map", `
-^[a-z]+\.d2\.local#/ d2_app_8080
-^[^.]+\.d1\.local#/ d1_app_8080
-d3\.local#/ d3_app_8080
-`)
-	c.logger.CompareLogging(defaultLogging)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
 }
 
-func TestInstanceRedirectFrom(t *testing.T) {
+func TestInstanceSocketStats(t *testing.T) {
 	testCases := []struct {
-		data     [3]hatypes.HostRedirectConfig
-		code     int
-		expHTTP  string
-		expHTTPS string
-		expMaps  map[string]string
+		ignoreProbes bool
+		socketStats  bool
+		expected     string
 	}{
 		// 0
 		{
-			data: [3]hatypes.HostRedirectConfig{
-				{RedirectHost: "*.d1.local"},
-			},
-			code: 301,
-			expHTTP: `
-    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.backend) -m found }
-    http-request redirect prefix //%[var(req.redirdest)] code 301 if { var(req.redirdest) -m found }`,
-			expHTTPS: `
-    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.hostbackend) -m found }
-    http-request redirect prefix //%[var(req.redirdest)] code 301 if { var(req.redirdest) -m found }`,
-			expMaps: map[string]string{
-				"_front_redir_from__regex.map": `
-^[^.]+\.d1\.local$ d1.local
-`,
-			},
+			socketStats: false,
+			expected:    "",
 		},
 		// 1
 		{
-			data: [3]hatypes.HostRedirectConfig{
-				{RedirectHost: "*.d1.local"},
-				{RedirectHost: "sub.d2.local", RedirectHostRegex: "^[a-z]+\\.d2\\.local$"},
-				{RedirectHostRegex: "\\.d3\\.local$"},
-			},
-			expHTTP: `
-    http-request set-var(req.redirdest) var(req.host),map_str(/etc/haproxy/maps/_front_redir_from__exact.map) if !{ var(req.backend) -m found }
-    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.backend) -m found } !{ var(req.redirdest) -m found }
-    http-request redirect prefix //%[var(req.redirdest)] code 302 if { var(req.redirdest) -m found }`,
-			expHTTPS: `
-    http-request set-var(req.redirdest) var(req.host),map_str(/etc/haproxy/maps/_front_redir_from__exact.map) if !{ var(req.hostbackend) -m found }
-    http-request set-var(req.redirdest) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_from__regex.map) if !{ var(req.hostbackend) -m found } !{ var(req.redirdest) -m found }
-    http-request redirect prefix //%[var(req.redirdest)] code 302 if { var(req.redirdest) -m found }`,
-			expMaps: map[string]string{
-				"_front_redir_from__exact.map": `
-sub.d2.local d2.local
-`,
-				"_front_redir_from__regex.map": `
-^[a-z]+\.d2\.local$ d2.local
-^[^.]+\.d1\.local$ d1.local
-\.d3\.local$ d3.local
-`,
-			},
+			socketStats: true,
+			expected: `
+    option socket-stats`,
+		},
+		// 2
+		{
+			ignoreProbes: true,
+			socketStats:  true,
+			expected: `
+    option http-ignore-probes
+    option socket-stats`,
 		},
 	}
-
 	for _, test := range testCases {
 		c := setup(t)
-		defer c.teardown()
 
 		var h *hatypes.Host
 		var b *hatypes.Backend
 
 		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
-		b.Endpoints = []*hatypes.Endpoint{endpointS1}
-		h = c.config.Hosts().AcquireHost("d1.local")
-		h.AddPath(b, "/", hatypes.MatchBegin)
-		h.Redirect = test.data[0]
-
-		b = c.config.Backends().AcquireBackend("d2", "app", "8080")
-		b.Endpoints = []*hatypes.Endpoint{endpointS21}
-		h = c.config.Hosts().AcquireHost("d2.local")
-		h.AddPath(b, "/", hatypes.MatchBegin)
-		h.Redirect = test.data[1]
-
-		b = c.config.Backends().AcquireBackend("d3", "app", "8080")
-		b.Endpoints = []*hatypes.Endpoint{endpointS31}
-		h = c.config.Hosts().AcquireHost("d3.local")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h = c.config.Hosts().AcquireHost("d1.local")
 		h.AddPath(b, "/", hatypes.MatchBegin)
-		h.Redirect = test.data[2]
 
-		if test.code != 0 {
-			c.config.frontend.RedirectFromCode = test.code
-		} else {
-			c.config.frontend.RedirectFromCode = 302
-		}
+		c.config.Frontend().HTTPIgnoreProbes = test.ignoreProbes
+		c.config.Frontend().SocketStats = test.socketStats
 
 		c.Update()
 		c.checkConfig(`
@@ -3530,147 +5956,59 @@ sub.d2.local d2.local
 backend d1_app_8080
     mode http
     server s1 172.17.0.11:8080 weight 100
-backend d2_app_8080
-    mode http
-    server s21 172.17.0.121:8080 weight 100
-backend d3_app_8080
-    mode http
-    server s31 172.17.0.131:8080 weight 100
 <<backends-default>>
 frontend _front_http
     mode http
-    bind :80
-    http-request set-var(req.path) path
-    http-request set-var(req.host) hdr(host),field(1,:),lower
-    http-request set-var(req.base) var(req.host),concat(\#,req.path)
-    http-request set-header X-Forwarded-Proto http
-    http-request del-header X-SSL-Client-CN
-    http-request del-header X-SSL-Client-DN
-    http-request del-header X-SSL-Client-SHA1
-    http-request del-header X-SSL-Client-Cert
-    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)` + test.expHTTP + `
+    bind :80` + test.expected + `
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
-    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
-    http-request set-var(req.path) path
-    http-request set-var(req.host) hdr(host),field(1,:),lower
-    http-request set-var(req.base) var(req.host),concat(\#,req.path)
-    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)` + test.expHTTPS + `
-    http-request set-header X-Forwarded-Proto https
-    http-request del-header X-SSL-Client-CN
-    http-request del-header X-SSL-Client-DN
-    http-request del-header X-SSL-Client-SHA1
-    http-request del-header X-SSL-Client-Cert
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all` + test.expected + `
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
 <<support>>
 `)
-		for file, content := range test.expMaps {
-			c.checkMap(file, content)
-		}
 		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
 	}
 }
 
-func TestInstanceRedirectTo(t *testing.T) {
+func TestInstanceFrontendMaxConn(t *testing.T) {
 	testCases := []struct {
-		to       [3]string
-		code     int
+		maxconn  int
 		expected string
-		expMaps  map[string]string
 	}{
 		// 0
 		{
-			to: [3]string{
-				"https://app.local",
-			},
-			expected: `
-    http-request set-var(req.redirto) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_redir_to__begin.map)
-    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
-			expMaps: map[string]string{
-				"_front_redir_to__begin.map": `
-d1.local#/ https://app.local
-`,
-			},
+			maxconn:  0,
+			expected: "",
 		},
 		// 1
 		{
-			to: [3]string{
-				"https://app.local/login",
-				"https://app.local/app2",
-			},
-			expected: `
-    http-request set-var(req.redirto) var(req.base),map_dir(/etc/haproxy/maps/_front_redir_to__prefix_01.map)
-    http-request set-var(req.redirto) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_redir_to__begin.map) if !{ var(req.redirto) -m found }
-    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
-			expMaps: map[string]string{
-				"_front_redir_to__prefix_01.map": `
-d1.local#/app2 https://app.local/app2
-`,
-				"_front_redir_to__begin.map": `
-d1.local#/ https://app.local/login
-`,
-			},
-		},
-		// 2
-		{
-			to: [3]string{
-				"",
-				"https://app.local/app2",
-				"https://app.local/app3",
-			},
+			maxconn: 5000,
 			expected: `
-    http-request set-var(req.redirto) var(req.base),map_dir(/etc/haproxy/maps/_front_redir_to__prefix.map)
-    http-request redirect location %[var(req.redirto)] code 302 if { var(req.redirto) -m found }`,
-			expMaps: map[string]string{
-				"_front_redir_to__prefix.map": `
-d1.local#/app3 https://app.local/app3
-d1.local#/app2 https://app.local/app2
-`,
-			},
+    maxconn 5000`,
 		},
 	}
-
 	for _, test := range testCases {
 		c := setup(t)
-		defer c.teardown()
 
 		var h *hatypes.Host
 		var b *hatypes.Backend
 
-		h = c.config.Hosts().AcquireHost("d1.local")
-
 		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
 		b.Endpoints = []*hatypes.Endpoint{endpointS1}
-		if test.to[0] != "" {
-			h.AddRedirect("/", hatypes.MatchBegin, test.to[0])
-		} else {
-			h.AddPath(b, "/", hatypes.MatchBegin)
-		}
-
-		b = c.config.Backends().AcquireBackend("d2", "app", "8080")
-		b.Endpoints = []*hatypes.Endpoint{endpointS21}
-		if test.to[1] != "" {
-			h.AddRedirect("/app2", hatypes.MatchPrefix, test.to[1])
-		} else {
-			h.AddPath(b, "/app2", hatypes.MatchBegin)
-		}
-
-		b = c.config.Backends().AcquireBackend("d3", "app", "8080")
-		b.Endpoints = []*hatypes.Endpoint{endpointS31}
-		if test.to[2] != "" {
-			h.AddRedirect("/app3", hatypes.MatchPrefix, test.to[2])
-		} else {
-			h.AddPath(b, "/app3", hatypes.MatchBegin)
-		}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
 
-		if test.code != 0 {
-			c.config.frontend.RedirectToCode = test.code
-		} else {
-			c.config.frontend.RedirectToCode = 302
-		}
+		c.config.Frontend().MaxConn = test.maxconn
 
 		c.Update()
 		c.checkConfig(`
@@ -3679,51 +6017,262 @@ d1.local#/app2 https://app.local/app2
 backend d1_app_8080
     mode http
     server s1 172.17.0.11:8080 weight 100
-backend d2_app_8080
+<<backends-default>>
+frontend _front_http
+    mode http` + test.expected + `
+    bind :80
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http` + test.expected + `
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceBlockedPaths(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.config.Frontend().BlockedPaths = []string{"/.git", "/.env", "/private/"}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
     mode http
-    server s21 172.17.0.121:8080 weight 100
-backend d3_app_8080
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
     mode http
-    server s31 172.17.0.131:8080 weight 100
+    bind :80
+    <<set-req-base>>
+    http-request deny deny_status 404 if { var(req.path),concat(/) -m beg -f /etc/haproxy/maps/_front_blocked_paths.list }
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request deny deny_status 404 if { var(req.path),concat(/) -m beg -f /etc/haproxy/maps/_front_blocked_paths.list }
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+	c.checkMap("_front_blocked_paths.list", `
+/.git/
+/.env/
+/private/
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceCanonicalPathPrefix(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.CanonicalPathPrefix = "/app"
+	h = c.config.Hosts().AcquireHost("*.d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.CanonicalPathPrefix = "/web/v1"
+	h = c.config.Hosts().AcquireHost("d3.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80
+    <<set-req-base>>
+    http-request set-var(req.pathprefix) var(req.host),map_str(/etc/haproxy/maps/_front_redir_pathprefix__exact.map)
+    http-request set-var(req.pathprefix) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_pathprefix__regex.map) if !{ var(req.pathprefix) -m found }
+    http-request set-var(req.pathprefixed) var(req.pathprefix),concat(\#,req.path,/) if { var(req.pathprefix) -m found }
+    http-request redirect prefix %[var(req.pathprefix)] if { var(req.pathprefix) -m found } !{ var(req.pathprefixed) -m reg '^([^#]*)#\1/' }
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    http-request set-var(req.backend) var(req.base),map_reg(/etc/haproxy/maps/_front_http_host__regex.map) if !{ var(req.backend) -m found }
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    http-request set-var(req.hostbackend) var(req.base),map_reg(/etc/haproxy/maps/_front_https_host__regex.map) if !{ var(req.hostbackend) -m found }
+    http-request set-var(req.pathprefix) var(req.host),map_str(/etc/haproxy/maps/_front_redir_pathprefix__exact.map)
+    http-request set-var(req.pathprefix) var(req.host),map_reg(/etc/haproxy/maps/_front_redir_pathprefix__regex.map) if !{ var(req.pathprefix) -m found }
+    http-request set-var(req.pathprefixed) var(req.pathprefix),concat(\#,req.path,/) if { var(req.pathprefix) -m found }
+    http-request redirect prefix %[var(req.pathprefix)] if { var(req.pathprefix) -m found } !{ var(req.pathprefixed) -m reg '^([^#]*)#\1/' }
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+	c.checkMap("_front_redir_pathprefix__exact.map", `
+d1.local /app
+`)
+	c.checkMap("_front_redir_pathprefix__regex.map", `
+^[^.]+\.d2\.local$ /web/v1
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceContentSecurityPolicy(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	h.ContentSecurityPolicy = "default-src 'self'; img-src *"
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
 <<backends-default>>
 frontend _front_http
     mode http
     bind :80
-    http-request set-var(req.path) path
-    http-request set-var(req.host) hdr(host),field(1,:),lower
-    http-request set-var(req.base) var(req.host),concat(\#,req.path)
-    http-request set-header X-Forwarded-Proto http
-    http-request del-header X-SSL-Client-CN
-    http-request del-header X-SSL-Client-DN
-    http-request del-header X-SSL-Client-SHA1
-    http-request del-header X-SSL-Client-Cert` + test.expected + `
+    <<set-req-base>>
+    http-request set-var(txn.csp) var(req.host),map_str(/etc/haproxy/maps/_front_csp__exact.map)
+    http-response set-header Content-Security-Policy %[var(txn.csp)] if { var(txn.csp) -m found }
+    <<http-headers>>
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
     use_backend %[var(req.backend)] if { var(req.backend) -m found }
     default_backend _error404
 frontend _front_https
     mode http
     bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
-    http-request set-var(req.path) path
-    http-request set-var(req.host) hdr(host),field(1,:),lower
-    http-request set-var(req.base) var(req.host),concat(\#,req.path)` + test.expected + `
+    <<set-req-base>>
     http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
-    http-request set-header X-Forwarded-Proto https
-    http-request del-header X-SSL-Client-CN
-    http-request del-header X-SSL-Client-DN
-    http-request del-header X-SSL-Client-SHA1
-    http-request del-header X-SSL-Client-Cert
+    http-request set-var(txn.csp) var(req.host),map_str(/etc/haproxy/maps/_front_csp__exact.map)
+    http-response set-header Content-Security-Policy %[var(txn.csp)] if { var(txn.csp) -m found }
+    <<https-headers>>
     use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
     default_backend _error404
 <<support>>
 `)
-		for file, content := range test.expMaps {
-			c.checkMap(file, content)
-		}
+	c.checkMap("_front_csp__exact.map", `
+d1.local default-src 'self'; img-src *
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceSSLSession(t *testing.T) {
+	testCases := []struct {
+		cacheSize int
+		lifetime  string
+		expected  string
+	}{
+		// 0
+		{
+			cacheSize: 40000,
+			lifetime:  "10m",
+			expected: `
+    tune.ssl.cachesize 40000
+    tune.ssl.lifetime 10m`,
+		},
+		// 1
+		{
+			cacheSize: 0,
+			expected: `
+    tune.ssl.cachesize 0`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		var h *hatypes.Host
+		var b *hatypes.Backend
+
+		b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h = c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+
+		ssl := &c.config.Global().SSL
+		cacheSize := test.cacheSize
+		ssl.SessionCacheSize = &cacheSize
+		ssl.SessionLifetime = test.lifetime
+
+		c.Update()
+		c.checkConfig(`
+global
+    daemon
+    unix-bind mode 0600
+    stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
+    maxconn 2000
+    hard-stop-after 15m
+    lua-prepend-path /etc/haproxy/lua/?.lua
+    lua-load /etc/haproxy/lua/auth-request.lua
+    lua-load /etc/haproxy/lua/services.lua
+    ssl-dh-param-file /var/haproxy/tls/dhparam.pem` + test.expected + `
+    ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
+    ssl-default-bind-options no-sslv3
+    ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
+    ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
 		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
 	}
 }
 
-func TestInstanceSyslog(t *testing.T) {
+func TestInstanceSSLOptions(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
 
@@ -3735,19 +6284,7 @@ func TestInstanceSyslog(t *testing.T) {
 	h = c.config.Hosts().AcquireHost("d1.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
 
-	tcpPort1, tcpHost1 := c.config.tcpservices.AcquireTCPService("<default>:7001")
-	tcpPort1.LogFormat = "default"
-	tcpHost1.Backend = b.BackendID()
-
-	tcpPort2, tcpHost2 := c.config.tcpservices.AcquireTCPService("<default>:7002")
-	tcpPort2.LogFormat = "%[src]"
-	tcpHost2.Backend = b.BackendID()
-
-	syslog := &c.config.Global().Syslog
-	syslog.Endpoint = "127.0.0.1:1514"
-	syslog.Format = "rfc3164"
-	syslog.Length = 2048
-	syslog.Tag = "ingress"
+	c.config.Global().SSL.Options = "no-sslv3 no-tlsv10 no-tlsv11"
 
 	c.Update()
 	c.checkConfig(`
@@ -3757,15 +6294,13 @@ global
     stats socket /var/run/haproxy.sock level admin expose-fd listeners mode 600
     maxconn 2000
     hard-stop-after 15m
-    log 127.0.0.1:1514 len 2048 format rfc3164 local0
-    log-tag ingress
     lua-prepend-path /etc/haproxy/lua/?.lua
     lua-load /etc/haproxy/lua/auth-request.lua
     lua-load /etc/haproxy/lua/services.lua
     ssl-dh-param-file /var/haproxy/tls/dhparam.pem
     ssl-default-bind-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
     ssl-default-bind-ciphersuites TLS_AES_128_GCM_SHA256
-    ssl-default-bind-options no-sslv3
+    ssl-default-bind-options no-sslv3 no-tlsv10 no-tlsv11
     ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
     ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256
 <<defaults>>
@@ -3773,20 +6308,256 @@ backend d1_app_8080
     mode http
     server s1 172.17.0.11:8080 weight 100
 <<backends-default>>
-frontend _front_tcp_7001
-    bind :7001
-    mode tcp
-    option tcplog
-    default_backend d1_app_8080
-frontend _front_tcp_7002
-    bind :7002
-    mode tcp
-    log-format %[src]
-    default_backend d1_app_8080
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceCache(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	b.Cache = hatypes.BackendCache{Name: "static", MaxAge: 300, MaxObjectSize: 524288, TotalMaxSize: 16}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.Cache = hatypes.BackendCache{Name: "static", MaxAge: 60, TotalMaxSize: 4}
+	h.AddPath(b, "/app", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d3", "api", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS31}
+	b.Cache = hatypes.BackendCache{Name: "api", MaxAge: 10, TotalMaxSize: 8}
+	h.AddPath(b, "/api", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+cache api
+    total-max-size 8
+    max-age 10
+cache static
+    total-max-size 16
+    max-object-size 524288
+    max-age 300
+backend d1_app_8080
+    mode http
+    http-request cache-use static
+    http-response cache-store static
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    http-request cache-use static
+    http-response cache-store static
+    server s21 172.17.0.121:8080 weight 100
+backend d3_api_8080
+    mode http
+    http-request cache-use api
+    http-response cache-store api
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceFastCGI(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "php", "9000")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	b.Server.Protocol = "fcgi"
+	b.FastCGI = hatypes.BackendFastCGI{Name: "d1_php_9000", DocRoot: "/var/www/html", Index: "index.php", PathInfo: `^(/.+\.php)(/.*)?$`}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "php", "9000")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.Server.Protocol = "fcgi"
+	b.FastCGI = hatypes.BackendFastCGI{Name: "d2_php_9000", DocRoot: "/srv/app"}
+	h.AddPath(b, "/app", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+fcgi-app d1_php_9000
+    docroot /var/www/html
+    index index.php
+    path-info ^(/.+\.php)(/.*)?$
+fcgi-app d2_php_9000
+    docroot /srv/app
+backend d1_php_9000
+    mode http
+    use-fcgi-app d1_php_9000
+    server s1 172.17.0.11:8080 weight 100 proto fcgi
+backend d2_php_9000
+    mode http
+    use-fcgi-app d2_php_9000
+    server s21 172.17.0.121:8080 weight 100 proto fcgi
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceClientTCPKeepAlive(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.config.Global().ClientTCPKeepAlive = true
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+defaults tcp
+    log global
+    maxconn 2000
+    mode tcp
+    option redispatch
+    option dontlognull
+    option clitcpka
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
+    log global
+    maxconn 2000
+    option redispatch
+    option dontlognull
+    option http-server-close
+    option http-keep-alive
+    option clitcpka
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout http-keep-alive 1m
+    timeout http-request    5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstanceUniqueID(t *testing.T) {
+	testCases := []struct {
+		format string
+		header string
+		expect string
+	}{
+		// 0
+		{
+			format: "%[uuid()]",
+			expect: `
+    unique-id-format %[uuid()]`,
+		},
+		// 1
+		{
+			format: "%ci:%cp_%fi:%fp_%Ts_%rt:%pid",
+			header: "X-Request-ID",
+			expect: `
+    unique-id-format %ci:%cp_%fi:%fp_%Ts_%rt:%pid
+    unique-id-header X-Request-ID`,
+		},
+	}
+	for _, test := range testCases {
+		c := setup(t)
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h := c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+		c.config.Global().UniqueIDFormat = test.format
+		c.config.Global().UniqueIDHeader = test.header
+
+		c.Update()
+		c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+frontend _front_http
+    mode http
+    bind :80` + test.expect + `
+    <<set-req-base>>
+    <<http-headers>>
+    http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
+    use_backend %[var(req.backend)] if { var(req.backend) -m found }
+    default_backend _error404
+frontend _front_https
+    mode http
+    bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all` + test.expect + `
+    <<set-req-base>>
+    http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
+    <<https-headers>>
+    use_backend %[var(req.hostbackend)] if { var(req.hostbackend) -m found }
+    default_backend _error404
+<<support>>
+`)
+		c.logger.CompareLogging(defaultLogging)
+		c.teardown()
+	}
+}
+
+func TestInstanceNormalizeURI(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h := c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	c.config.Global().NormalizeURI = []string{"path-merge-slashes", "path-strip-dotdot full"}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
 frontend _front_http
     mode http
     bind :80
-    option httplog
+    http-request normalize-uri path-merge-slashes
+    http-request normalize-uri path-strip-dotdot full
     <<set-req-base>>
     <<http-headers>>
     http-request set-var(req.backend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_http_host__begin.map)
@@ -3795,7 +6566,8 @@ frontend _front_http
 frontend _front_https
     mode http
     bind :443 ssl alpn h2,http/1.1 crt-list /etc/haproxy/maps/_front_bind_crt.list ca-ignore-err all crt-ignore-err all
-    option httplog
+    http-request normalize-uri path-merge-slashes
+    http-request normalize-uri path-strip-dotdot full
     <<set-req-base>>
     http-request set-var(req.hostbackend) var(req.base),lower,map_beg(/etc/haproxy/maps/_front_https_host__begin.map)
     <<https-headers>>
@@ -3806,6 +6578,35 @@ frontend _front_https
 	c.logger.CompareLogging(defaultLogging)
 }
 
+func TestInstanceErrorFiles(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	h := c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	c.config.Global().ErrorFiles = []*hatypes.ErrorFile{
+		{Code: 503, Filename: "/etc/haproxy/errors/503.http"},
+		{Code: 504, Filename: "/etc/haproxy/errors/504.http"},
+	}
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+    errorfile 503 /etc/haproxy/errors/503.http
+    errorfile 504 /etc/haproxy/errors/504.http
+backend d1_app_8080
+    mode http
+    server s1 172.17.0.11:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
 func TestDNS(t *testing.T) {
 	c := setup(t)
 	defer c.teardown()
@@ -3837,18 +6638,39 @@ func TestDNS(t *testing.T) {
 				HoldValid:           "1s",
 				TimeoutRetry:        "2s",
 			},
+			{
+				Name: "dns",
+				Nameservers: []*hatypes.DNSNameserver{
+					{
+						Name:     "ns01",
+						Endpoint: "10.0.2.11:53",
+					},
+				},
+				AcceptedPayloadSize: 8192,
+				HoldNX:              "5s",
+				HoldObsolete:        "0s",
+				HoldOther:           "15s",
+				HoldRefused:         "20s",
+				HoldTimeout:         "25s",
+				HoldValid:           "10s",
+				ResolveRetries:      3,
+				TimeoutResolve:      "1s",
+				TimeoutRetry:        "1s",
+			},
 		},
 	}
 
 	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
 	b.Endpoints = []*hatypes.Endpoint{endpointS21, endpointS22}
 	b.Resolver = "k8s"
+	b.InitAddr = "none"
 	h = c.config.Hosts().AcquireHost("d1.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
 
 	b = c.config.Backends().AcquireBackend("d2", "app", "http")
 	b.Endpoints = []*hatypes.Endpoint{endpointS21, endpointS22}
 	b.Resolver = "k8s"
+	b.InitAddr = "none"
 	h = c.config.Hosts().AcquireHost("d2.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
 
@@ -3856,9 +6678,26 @@ func TestDNS(t *testing.T) {
 	b.DNSPort = "named"
 	b.Endpoints = []*hatypes.Endpoint{endpointS21, endpointS22}
 	b.Resolver = "k8s"
+	b.InitAddr = "last,libc,none"
 	h = c.config.Hosts().AcquireHost("d3.local")
 	h.AddPath(b, "/", hatypes.MatchBegin)
 
+	// numeric backend port, SRV record query configured by the port name
+	b = c.config.Backends().AcquireBackend("d4", "app", "8080")
+	b.DNSPort = "web"
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.Resolver = "dns"
+	h = c.config.Hosts().AcquireHost("d4.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	// empty init-addr, haproxy's default is used
+	b = c.config.Backends().AcquireBackend("d5", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.Resolver = "k8s"
+	b.InitAddr = ""
+	h = c.config.Hosts().AcquireHost("d5.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
 	c.Update()
 	c.checkConfig(`
 <<global>>
@@ -3871,6 +6710,18 @@ resolvers k8s
     hold obsolete         0s
     hold valid            1s
     timeout retry         2s
+resolvers dns
+    nameserver ns01 10.0.2.11:53
+    accepted_payload_size 8192
+    hold nx               5s
+    hold obsolete         0s
+    hold other            15s
+    hold refused          20s
+    hold timeout          25s
+    hold valid            10s
+    resolve_retries       3
+    timeout resolve       1s
+    timeout retry         1s
 backend d1_app_8080
     mode http
     server-template srv 2 app.d1.svc.cluster.local:8080 resolvers k8s resolve-prefer ipv4 init-addr none weight 1
@@ -3879,7 +6730,76 @@ backend d2_app_http
     server-template srv 2 _http._tcp.app.d2.svc.cluster.local resolvers k8s resolve-prefer ipv4 init-addr none weight 1
 backend d3_app_http
     mode http
-    server-template srv 2 _named._tcp.app.d3.svc.cluster.local resolvers k8s resolve-prefer ipv4 init-addr none weight 1
+    server-template srv 2 _named._tcp.app.d3.svc.cluster.local resolvers k8s resolve-prefer ipv4 init-addr last,libc,none weight 1
+backend d4_app_8080
+    mode http
+    server-template srv 1 _web._tcp.app.d4.svc.cluster.local resolvers dns resolve-prefer ipv4 weight 1
+backend d5_app_8080
+    mode http
+    server-template srv 1 app.d5.svc.cluster.local:8080 resolvers k8s resolve-prefer ipv4 weight 1
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(defaultLogging)
+}
+
+func TestInstancePeers(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var h *hatypes.Host
+	var b *hatypes.Backend
+
+	c.config.Global().Peers = hatypes.PeersConfig{
+		SectionName: "_peers",
+		Peers: []*hatypes.Peer{
+			{Name: "haproxy-ingress-0", Endpoint: "10.0.1.11:10000"},
+			{Name: "haproxy-ingress-1", Endpoint: "10.0.1.12:10000"},
+		},
+	}
+
+	b = c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	b.Limit.RPS = 20
+	h = c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	b = c.config.Backends().AcquireBackend("d2", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS21}
+	b.SourceAffinity = hatypes.SourceAffinity{Size: "100k", Expire: "30m"}
+	b.Limit.PathRPS = 50
+	b.Limit.SessionKey = "req.cook(sessionid)"
+	b.Limit.SessionRPS = 10
+	h = c.config.Hosts().AcquireHost("d2.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+
+	c.Update()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+peers _peers
+    peer haproxy-ingress-0 10.0.1.11:10000
+    peer haproxy-ingress-1 10.0.1.12:10000
+backend d1_app_8080
+    mode http
+    stick-table type ip size 200k expire 5m peers _peers store conn_cur,conn_rate(1s)
+    http-request track-sc1 src
+    http-request deny deny_status 429 if { sc1_conn_rate gt 20 }
+    server s1 172.17.0.11:8080 weight 100
+backend d2_app_8080
+    mode http
+    stick-table type ip size 100k expire 30m peers _peers
+    stick on src
+    http-request track-sc2 base table _limit_path_d2_app_8080
+    http-request track-sc0 req.cook(sessionid) table _limit_session_d2_app_8080
+    http-request deny deny_status 429 if { sc2_http_req_rate gt 50 }
+    http-request deny deny_status 429 if { sc0_http_req_rate gt 10 }
+    server s21 172.17.0.121:8080 weight 100
+backend _limit_path_d2_app_8080
+    stick-table type string len 128 size 200k expire 5m peers _peers store http_req_rate(1s)
+backend _limit_session_d2_app_8080
+    stick-table type string len 128 size 200k expire 5m peers _peers store http_req_rate(1s)
 <<backends-default>>
 <<frontends-default>>
 <<support>>
@@ -4284,7 +7204,7 @@ func TestModSecurity(t *testing.T) {
 		var modsec string
 		if test.modsecExp != "" {
 			modsec = `
-backend spoe-modsecurity
+backend spoe-modsecurity from tcp
     mode tcp` + test.modsecExp
 		}
 		c.checkConfig(`
@@ -4424,6 +7344,322 @@ d1.local#/ d1_app_8080
 	c.logger.CompareLogging(defaultLogging)
 }
 
+func TestInstanceCheckMaps(t *testing.T) {
+	testCases := []struct {
+		content string
+		expErr  string
+	}{
+		// 0
+		{
+			content: `
+# comment
+d1.local/ d1_app_8080
+
+d2.local/ d2_app_8080
+`,
+		},
+		// 1
+		{
+			content: `
+d1.local/ d1_app_8080
+d2.local/
+`,
+			expErr: "invalid map file '/etc/haproxy/maps/_custom.map' on line 3: missing value of key 'd2.local/'",
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		h := c.config.Hosts().AcquireHost("d1.local")
+		h.AddPath(b, "/", hatypes.MatchBegin)
+		c.Update()
+		c.logger.CompareLogging(defaultLogging)
+		mapFile := filepath.Join(c.tempdir, "_custom.map")
+		if err := ioutil.WriteFile(mapFile, []byte(test.content), 0644); err != nil {
+			t.Errorf("error writing map file: %v", err)
+		}
+		var errStr string
+		if err := c.instance.check(); err != nil {
+			errStr = strings.Replace(err.Error(), c.tempdir, "/etc/haproxy/maps", -1)
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d - expected: %q, actual: %q", i, test.expErr, errStr)
+		}
+		if test.expErr == "" {
+			c.logger.CompareLogging("INFO (test) check was skipped")
+		}
+		c.teardown()
+	}
+}
+
+func TestInstanceUpdateWindow(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	var mutex sync.Mutex
+	c.instance.options.UpdateLocker = &mutex
+	c.instance.options.UpdateWindow = 50 * time.Millisecond
+
+	b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+	h := c.config.Hosts().AcquireHost("d1.local")
+	h.AddPath(b, "/", hatypes.MatchBegin)
+	for _, ep := range []*hatypes.Endpoint{endpointS1, endpointS21, endpointS31} {
+		mutex.Lock()
+		b.Endpoints = []*hatypes.Endpoint{ep}
+		c.Update()
+		mutex.Unlock()
+	}
+	c.logger.CompareLogging(`
+INFO-V(2) coalescing update, already scheduled
+INFO-V(2) coalescing update, already scheduled`)
+
+	time.Sleep(200 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	c.checkConfig(`
+<<global>>
+<<defaults>>
+backend d1_app_8080
+    mode http
+    server s31 172.17.0.131:8080 weight 100
+<<backends-default>>
+<<frontends-default>>
+<<support>>
+`)
+	c.logger.CompareLogging(`
+INFO-V(2) update delayed by 50ms, applying the last config state` + defaultLogging + `
+INFO finish scheduled haproxy update`)
+}
+
+func TestInstanceUpdateWindowShutdown(t *testing.T) {
+	c := setup(t)
+	defer c.teardown()
+
+	c.instance.options.UpdateWindow = 50 * time.Millisecond
+
+	b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+	b.Endpoints = []*hatypes.Endpoint{endpointS1}
+	c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+	c.Update()
+	if err := c.instance.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+	if c.instance.updatePend != nil {
+		t.Errorf("expected pending update to be cleared")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	c.logger.CompareLogging(`WARN canceling scheduled haproxy update, the last config state was not applied`)
+}
+
+func TestInstanceShutdown(t *testing.T) {
+	testCases := []struct {
+		cmd     string
+		fake    bool
+		timeout time.Duration
+		expFile bool
+		expErr  string
+		logging string
+	}{
+		// 0
+		{},
+		// 1
+		{
+			cmd:     "touch <dir>/shutdown",
+			fake:    true,
+			logging: `INFO (test) shutdown was skipped`,
+		},
+		// 2
+		{
+			cmd:     "touch <dir>/shutdown",
+			expFile: true,
+			logging: `INFO shutting down haproxy`,
+		},
+		// 3
+		{
+			cmd:     "false",
+			expErr:  "error running shutdown command: exit status 1",
+			logging: `INFO shutting down haproxy`,
+		},
+		// 4
+		{
+			cmd:     "sleep 10",
+			timeout: 100 * time.Millisecond,
+			expErr:  "haproxy shutdown did not finish: context deadline exceeded",
+			logging: `INFO shutting down haproxy`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.instance.options.fake = test.fake
+		c.instance.options.ShutdownCmd = strings.Replace(test.cmd, "<dir>", c.tempdir, -1)
+		timeout := test.timeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		err := c.instance.Shutdown(ctx)
+		elapsed := time.Since(start)
+		cancel()
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d, expected '%s' but was '%s'", i, test.expErr, errStr)
+		}
+		if elapsed > timeout+time.Second {
+			t.Errorf("shutdown on %d should respect the %v deadline, but took %v", i, timeout, elapsed)
+		}
+		_, errStat := os.Stat(filepath.Join(c.tempdir, "shutdown"))
+		if hasFile := errStat == nil; hasFile != test.expFile {
+			t.Errorf("shutdown command invocation differs on %d, expected %t but was %t", i, test.expFile, hasFile)
+		}
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestInstanceSyncReload(t *testing.T) {
+	testCases := []struct {
+		cmd     string
+		sync    bool
+		expErr  string
+		logging string
+	}{
+		// 0
+		{
+			cmd:  "true",
+			sync: true,
+			logging: `
+INFO-V(2) updating 1 host(s): [d1.local]
+INFO-V(2) updating 1 backend(s): [d1_app_8080]
+INFO haproxy successfully reloaded (embedded)`,
+		},
+		// 1
+		{
+			cmd:    "false",
+			sync:   true,
+			expErr: "error reloading server: exit status 1",
+			logging: `
+INFO-V(2) updating 1 host(s): [d1.local]
+INFO-V(2) updating 1 backend(s): [d1_app_8080]
+ERROR error reloading server:
+exit status 1
+ERROR haproxy failed to reload, first occurence at 2021-06-01 00:00:00 +0000 UTC`,
+		},
+		// 2
+		{
+			cmd: "false",
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.instance.options.fake = false
+		c.instance.options.SyncReload = test.sync
+		c.instance.options.UpdateWindow = time.Hour
+		c.instance.reloadCmd = test.cmd
+		// a previous failure, so the failure log has a predictable date
+		failedSince := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		c.instance.failedSince = &failedSince
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		err := c.instance.Update(utils.NewTimer(nil))
+		if c.instance.updatePend != nil {
+			c.instance.updatePend.Stop()
+		}
+		var errStr string
+		if err != nil {
+			errStr = err.Error()
+		}
+		if errStr != test.expErr {
+			t.Errorf("error differs on %d, expected '%s' but was '%s'", i, test.expErr, errStr)
+		}
+		c.logger.CompareLogging(test.logging)
+		c.teardown()
+	}
+}
+
+func TestInstanceReloadStrategy(t *testing.T) {
+	testCases := []struct {
+		strategy string
+		socket   bool
+		expected string
+	}{
+		// 0
+		{
+			strategy: "reusesocket",
+			socket:   true,
+			expected: "-f <dir> -p /var/run/haproxy/haproxy.pid -D -sf -x <dir>/admin.sock",
+		},
+		// 1
+		{
+			strategy: "native",
+			socket:   true,
+			expected: "-f <dir> -p /var/run/haproxy/haproxy.pid -D -sf",
+		},
+		// 2
+		{
+			// first start, there is no socket to read the listeners from
+			strategy: "reusesocket",
+			expected: "-f <dir> -p /var/run/haproxy/haproxy.pid -D -sf",
+		},
+	}
+	reloadCmd, err := filepath.Abs("../../rootfs/haproxy-reload.sh")
+	if err != nil {
+		t.Fatalf("error reading reload script path: %v", err)
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		adminSocket := filepath.Join(c.tempdir, "admin.sock")
+		if test.socket {
+			l, err := net.Listen("unix", adminSocket)
+			if err != nil {
+				t.Errorf("error creating admin socket on %d: %v", i, err)
+			} else {
+				defer l.Close()
+			}
+		}
+		// a fake haproxy, found first in the PATH, saves the command line
+		haproxyCmd := "#!/bin/sh\necho \"$@\" >" + c.tempdir + "/haproxy.args\n"
+		if err := ioutil.WriteFile(filepath.Join(c.tempdir, "haproxy"), []byte(haproxyCmd), 0755); err != nil {
+			t.Errorf("error writing fake haproxy on %d: %v", i, err)
+		}
+		c.instance.options.fake = false
+		c.instance.options.AdminSocket = adminSocket
+		c.instance.options.ReloadEnv = []string{"PATH=" + c.tempdir + ":" + os.Getenv("PATH")}
+		c.instance.options.ReloadStrategy = test.strategy
+		c.instance.reloadCmd = reloadCmd
+		c.config.Global().AdminSocket = adminSocket
+
+		b := c.config.Backends().AcquireBackend("d1", "app", "8080")
+		b.Endpoints = []*hatypes.Endpoint{endpointS1}
+		c.config.Hosts().AcquireHost("d1.local").AddPath(b, "/", hatypes.MatchBegin)
+
+		if err := c.instance.Update(utils.NewTimer(nil)); err != nil {
+			t.Errorf("error reloading on %d: %v", i, err)
+		}
+		args, _ := ioutil.ReadFile(filepath.Join(c.tempdir, "haproxy.args"))
+		expected := strings.Replace(test.expected, "<dir>", c.tempdir, -1)
+		c.compareText(fmt.Sprintf("haproxy args %d", i), strings.TrimSpace(string(args)), expected)
+		// the listeners are only passed if exposed by the admin socket
+		statsSocket := "stats socket " + adminSocket + " level admin expose-fd listeners mode 600"
+		if config := c.readConfig(filepath.Join(c.tempdir, "haproxy.cfg")); !strings.Contains(config, statsSocket) {
+			t.Errorf("missing '%s' on %d", statsSocket, i)
+		}
+		c.logger.CompareLogging(`
+INFO-V(2) updating 1 host(s): [d1.local]
+INFO-V(2) updating 1 backend(s): [d1_app_8080]
+INFO haproxy successfully reloaded (embedded)`)
+		c.teardown()
+	}
+}
+
 func TestShards(t *testing.T) {
 	c := setupOptions(testOptions{
 		t:          t,
@@ -4492,8 +7728,9 @@ type testConfig struct {
 }
 
 type testOptions struct {
-	t          *testing.T
-	shardCount int
+	t           *testing.T
+	adminSocket string
+	shardCount  int
 }
 
 func setup(t *testing.T) *testConfig {
@@ -4510,6 +7747,7 @@ func setupOptions(options testOptions) *testConfig {
 	instance := CreateInstance(logger, InstanceOptions{
 		HAProxyCfgDir:  tempdir,
 		HAProxyMapsDir: tempdir,
+		AdminSocket:    options.adminSocket,
 		Metrics:        helper_test.NewMetricsMock(),
 		BackendShards:  options.shardCount,
 		//
@@ -4572,6 +7810,7 @@ func (c *testConfig) configGlobal(global *hatypes.Global) {
 	global.SSL.HeadersPrefix = "X-SSL"
 	global.SSL.Options = "no-sslv3"
 	global.Stats.Port = 1936
+	global.Syslog.DontLogNull = true
 	global.Timeout.Client = "50s"
 	global.Timeout.ClientFin = "50s"
 	global.Timeout.Connect = "5s"
@@ -4685,7 +7924,20 @@ func (c *testConfig) checkConfigFile(expected, fileName string) {
     ssl-default-bind-options no-sslv3
     ssl-default-server-ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES128-GCM-SHA256
     ssl-default-server-ciphersuites TLS_AES_128_GCM_SHA256`,
-		"<<defaults>>": `defaults
+		"<<defaults>>": `defaults tcp
+    log global
+    maxconn 2000
+    mode tcp
+    option redispatch
+    option dontlognull
+    timeout client          50s
+    timeout client-fin      50s
+    timeout connect         5s
+    timeout queue           5s
+    timeout server          50s
+    timeout server-fin      50s
+    timeout tunnel          1h
+defaults http
     log global
     maxconn 2000
     option redispatch
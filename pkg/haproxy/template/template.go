@@ -18,10 +18,12 @@ package template
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
 	gotemplate "text/template"
+	"time"
 )
 
 // CreateConfig ...
@@ -31,7 +33,18 @@ func CreateConfig() *Config {
 
 // Config ...
 type Config struct {
-	templates []*template
+	templates       []*template
+	version         string
+	headerTimestamp bool
+}
+
+// SetVersionHeader configures a comment block, with the controller version,
+// that is added on top of the outputs. The generation timestamp is also added
+// if timestamp is true. The header isn't used when checking if the content of
+// a non rotating output changed, so the timestamp doesn't force a rewrite.
+func (c *Config) SetVersionHeader(version string, timestamp bool) {
+	c.version = version
+	c.headerTimestamp = timestamp
 }
 
 // ClearTemplates ...
@@ -50,10 +63,26 @@ func (c *Config) NewTemplate(name, file, output string, rotate, startingBufferSi
 		output:    output,
 		rotate:    rotate,
 		rawConfig: bytes.NewBuffer(make([]byte, 0, startingBufferSize)),
+		hashes:    map[string][sha256.Size]byte{},
+		written:   map[string]struct{}{},
 	})
 	return nil
 }
 
+// PurgeHashes removes the cached content hash of the non rotating outputs
+// that weren't written since the last call, so the cache doesn't grow with
+// outputs that aren't used anymore. Should be called after each write cycle.
+func (c *Config) PurgeHashes() {
+	for _, t := range c.templates {
+		for output := range t.hashes {
+			if _, found := t.written[output]; !found {
+				delete(t.hashes, output)
+			}
+		}
+		t.written = map[string]struct{}{}
+	}
+}
+
 // Write ...
 func (c *Config) Write(data interface{}) error {
 	return c.WriteOutput(data, "")
@@ -67,29 +96,56 @@ func (c *Config) WriteOutput(data interface{}, output string) error {
 			return err
 		}
 	}
+	header := c.buildHeader()
 	for _, t := range c.templates {
-		if err := t.writeToDisk(output); err != nil {
+		if err := t.writeToDisk(output, header); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (c *Config) buildHeader() []byte {
+	if c.version == "" {
+		return nil
+	}
+	header := fmt.Sprintf("# HAProxy Ingress Controller version %s\n", c.version)
+	if c.headerTimestamp {
+		header += fmt.Sprintf("# generated at %s\n", time.Now().UTC().Format(time.RFC3339))
+	}
+	return []byte(header)
+}
+
 type template struct {
 	tmpl        *gotemplate.Template
 	output      string
 	rotate      int
 	rawConfig   *bytes.Buffer
 	configFiles []string
+	hashes      map[string][sha256.Size]byte
+	written     map[string]struct{}
 }
 
-func (t *template) writeToDisk(output string) error {
+func (t *template) writeToDisk(output string, header []byte) error {
 	if output == "" {
 		output = t.output
 	}
 	if output == "" {
 		return fmt.Errorf("output file is empty, configure on NewTemplate() or use WriteOutput()")
 	}
+	// non rotating outputs, like haproxy maps, are only rewritten if the
+	// content changed, avoiding disk churn and preserving the mtime of
+	// the unchanged files
+	hash := sha256.Sum256(t.rawConfig.Bytes())
+	if t.rotate == 0 {
+		t.written[output] = struct{}{}
+		if h, found := t.hashes[output]; found && h == hash {
+			if _, err := os.Stat(output); err == nil {
+				return nil
+			}
+		}
+		delete(t.hashes, output)
+	}
 	if t.rotate > 0 {
 		// Include timestamp in rotated config file names to aid troubleshooting.
 		// When using a single, ever-changing config file it was difficult
@@ -114,8 +170,11 @@ func (t *template) writeToDisk(output string) error {
 			t.configFiles = t.configFiles[1:]
 		}
 	}
-	if err := ioutil.WriteFile(output, t.rawConfig.Bytes(), 0644); err != nil {
+	if err := ioutil.WriteFile(output, append(header, t.rawConfig.Bytes()...), 0644); err != nil {
 		return fmt.Errorf("cannot write %s: %v", output, err)
 	}
+	if t.rotate == 0 {
+		t.hashes[output] = hash
+	}
 	return nil
 }
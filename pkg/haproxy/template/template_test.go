@@ -21,6 +21,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -275,6 +279,178 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteUnchanged(t *testing.T) {
+	type data struct {
+		Name string
+	}
+	c := setup(t)
+	defer c.teardown()
+	c.newTemplate("{{ .Name }}", 0)
+	out1 := c.tempdir + string(os.PathSeparator) + "out1.map"
+	out2 := c.tempdir + string(os.PathSeparator) + "out2.map"
+	modTime := func(file string) time.Time {
+		f, err := os.Stat(file)
+		if err != nil {
+			t.Errorf("error reading %s: %v", file, err)
+			return time.Time{}
+		}
+		return f.ModTime()
+	}
+	write := func(d interface{}, output string) {
+		if err := c.templateConfig.WriteOutput(d, output); err != nil {
+			t.Errorf("error writing %s: %v", output, err)
+		}
+	}
+	write(data{Name: "jack1"}, out1)
+	write(data{Name: "jane1"}, out2)
+	mod1 := modTime(out1)
+	mod2 := modTime(out2)
+	time.Sleep(10 * time.Millisecond)
+
+	// out1 unchanged, out2 changed
+	write(data{Name: "jack1"}, out1)
+	write(data{Name: "jane2"}, out2)
+	if m := modTime(out1); !m.Equal(mod1) {
+		t.Errorf("expected unchanged %s not being rewritten, mtime changed from %v to %v", out1, mod1, m)
+	}
+	if m := modTime(out2); m.Equal(mod2) {
+		t.Errorf("expected changed %s being rewritten, but mtime is still %v", out2, m)
+	}
+	if cnt, _ := ioutil.ReadFile(out2); string(cnt) != "jane2" {
+		t.Errorf("expected content 'jane2' on %s, but found '%s'", out2, string(cnt))
+	}
+
+	// out1 removed from disk should be written again
+	if err := os.Remove(out1); err != nil {
+		t.Errorf("error removing %s: %v", out1, err)
+	}
+	write(data{Name: "jack1"}, out1)
+	if cnt, _ := ioutil.ReadFile(out1); string(cnt) != "jack1" {
+		t.Errorf("expected content 'jack1' on %s, but found '%s'", out1, string(cnt))
+	}
+}
+
+func TestWriteVersionHeader(t *testing.T) {
+	type data struct {
+		Name string
+	}
+	testCases := []struct {
+		version   string
+		timestamp bool
+		expected  string
+	}{
+		// 0
+		{
+			expected: `^jack1$`,
+		},
+		// 1
+		{
+			version:  "v0.14",
+			expected: `^# HAProxy Ingress Controller version v0.14\njack1$`,
+		},
+		// 2
+		{
+			version:   "v0.14",
+			timestamp: true,
+			expected:  `^# HAProxy Ingress Controller version v0.14\n# generated at [0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9:]{8}Z\njack1$`,
+		},
+	}
+	for i, test := range testCases {
+		c := setup(t)
+		c.templateConfig.SetVersionHeader(test.version, test.timestamp)
+		c.newTemplate("{{ .Name }}", 0)
+		out := c.tempdir + string(os.PathSeparator) + "h1.cfg"
+		if err := c.templateConfig.Write(data{Name: "jack1"}); err != nil {
+			t.Errorf("error writing %s on %d: %v", out, i, err)
+		}
+		cnt, _ := ioutil.ReadFile(out)
+		if !regexp.MustCompile(test.expected).Match(cnt) {
+			t.Errorf("expected content matching '%s' on %d, but found '%s'", test.expected, i, string(cnt))
+		}
+		c.teardown()
+	}
+}
+
+func TestWriteVersionHeaderUnchanged(t *testing.T) {
+	type data struct {
+		Name string
+	}
+	c := setup(t)
+	defer c.teardown()
+	c.templateConfig.SetVersionHeader("v0.14", true)
+	c.newTemplate("{{ .Name }}", 0)
+	out := c.tempdir + string(os.PathSeparator) + "h1.cfg"
+	write := func(d interface{}) []byte {
+		if err := c.templateConfig.Write(d); err != nil {
+			t.Errorf("error writing %s: %v", out, err)
+		}
+		cnt, _ := ioutil.ReadFile(out)
+		return cnt
+	}
+	cnt1 := write(data{Name: "jack1"})
+	// timestamp has a one second resolution
+	time.Sleep(1100 * time.Millisecond)
+
+	// the timestamp doesn't make an unchanged content to be rewritten
+	if cnt2 := write(data{Name: "jack1"}); string(cnt2) != string(cnt1) {
+		t.Errorf("expected unchanged '%s' not being rewritten, but found '%s'", string(cnt1), string(cnt2))
+	}
+	// a changed content is rewritten along with a new timestamp
+	cnt3 := write(data{Name: "jack2"})
+	header1 := strings.Split(string(cnt1), "\n")[1]
+	header3 := strings.Split(string(cnt3), "\n")[1]
+	if header1 == header3 {
+		t.Errorf("expected timestamp being updated on a changed content, but found '%s'", header3)
+	}
+	if !strings.HasSuffix(string(cnt3), "\njack2") {
+		t.Errorf("expected content 'jack2' on %s, but found '%s'", out, string(cnt3))
+	}
+}
+
+func TestPurgeHashes(t *testing.T) {
+	type data struct {
+		Name string
+	}
+	c := setup(t)
+	defer c.teardown()
+	c.newTemplate("{{ .Name }}", 0)
+	out1 := c.tempdir + string(os.PathSeparator) + "out1.map"
+	out2 := c.tempdir + string(os.PathSeparator) + "out2.map"
+	write := func(output string) {
+		if err := c.templateConfig.WriteOutput(data{Name: "jack"}, output); err != nil {
+			t.Errorf("error writing %s: %v", output, err)
+		}
+	}
+	hashes := func() []string {
+		var outputs []string
+		for output := range c.templateConfig.templates[0].hashes {
+			outputs = append(outputs, output)
+		}
+		sort.Strings(outputs)
+		return outputs
+	}
+
+	write(out1)
+	write(out2)
+	c.templateConfig.PurgeHashes()
+	if h := hashes(); !reflect.DeepEqual(h, []string{out1, out2}) {
+		t.Errorf("expected hashes of %s and %s, but found %v", out1, out2, h)
+	}
+
+	// out1 unchanged and skipped, out2 not written on this cycle
+	write(out1)
+	c.templateConfig.PurgeHashes()
+	if h := hashes(); !reflect.DeepEqual(h, []string{out1}) {
+		t.Errorf("expected only the hash of %s, but found %v", out1, h)
+	}
+
+	// nothing written on this cycle
+	c.templateConfig.PurgeHashes()
+	if h := hashes(); len(h) > 0 {
+		t.Errorf("expected empty hashes, but found %v", h)
+	}
+}
+
 func (c *testConfig) newTemplate(content string, rotate int) {
 	cnt := len(c.templateConfig.templates) + 1
 	templateFileName := fmt.Sprintf("h%d.tmpl", cnt)
@@ -205,6 +205,40 @@ func (b *Backends) buildSortedItems(backendItems map[string]*Backend) []*Backend
 	return items
 }
 
+// BuildSortedCaches returns the distinct cache storages used by the backends.
+// Backends sharing the same cache name share the same storage, so the config
+// of the first backend, sorted by its ID, is used.
+func (b *Backends) BuildSortedCaches() []*BackendCache {
+	var caches []*BackendCache
+	names := map[string]bool{}
+	for _, backend := range b.buildSortedItems(b.items) {
+		cache := &backend.Cache
+		if cache.Name != "" && !names[cache.Name] {
+			names[cache.Name] = true
+			caches = append(caches, cache)
+		}
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].Name < caches[j].Name
+	})
+	return caches
+}
+
+// BuildSortedFastCGIApps returns the fcgi-app configurations used by the
+// backends, sorted by their names.
+func (b *Backends) BuildSortedFastCGIApps() []*BackendFastCGI {
+	var apps []*BackendFastCGI
+	for _, backend := range b.buildSortedItems(b.items) {
+		if backend.FastCGI.Name != "" {
+			apps = append(apps, &backend.FastCGI)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+	return apps
+}
+
 // BuildUsedAuthBackends ...
 func (b *Backends) BuildUsedAuthBackends() map[string]bool {
 	usedNames := map[string]bool{}
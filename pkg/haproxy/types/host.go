@@ -196,6 +196,16 @@ func (h *Hosts) HasVarNamespace() bool {
 	return false
 }
 
+// HasEarlyData ...
+func (h *Hosts) HasEarlyData() bool {
+	for _, host := range h.items {
+		if host.TLS.EarlyData {
+			return true
+		}
+	}
+	return false
+}
+
 // FindPath ...
 func (h *Host) FindPath(path string, match ...MatchType) (paths []*HostPath) {
 	for _, p := range h.Paths {
@@ -98,6 +98,16 @@ func convertWildcardToRegex(hostname string) (h string, hasWildcard bool) {
 	return "^[^.]+" + regexp.QuoteMeta(hostname[1:]) + "$", true
 }
 
+// HostPathRegex builds a regex that matches the `<hostname>#<path>`
+// string used by the frontend to find the backend of a request.
+func HostPathRegex(hostname string, hostPath *HostPath) string {
+	hostname, hasWildcard := convertWildcardToRegex(strings.ToLower(hostname))
+	if !hasWildcard {
+		hostname = "^" + regexp.QuoteMeta(hostname) + "$"
+	}
+	return buildMapKey(MatchRegex, hostname, convertPathToRegex(hostPath))
+}
+
 // convertPathToRegex converts a path of any match type that
 // needs to be added to a regex list, eg when a alias regex
 // or a wildcard hostname is used.
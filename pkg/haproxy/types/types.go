@@ -53,9 +53,12 @@ type Acme struct {
 // Global ...
 type Global struct {
 	Bind                    GlobalBindConfig
+	Bucket                  BucketConfig
 	Procs                   ProcsConfig
 	Syslog                  SyslogConfig
 	MaxConn                 int
+	MaxConnRate             int
+	MaxSessRate             int
 	Timeout                 TimeoutConfig
 	SSL                     SSLConfig
 	DNS                     DNSConfig
@@ -63,19 +66,28 @@ type Global struct {
 	Cookie                  CookieConfig
 	DrainSupport            DrainConfig
 	Acme                    Acme
+	ErrorFiles              []*ErrorFile
 	ForwardFor              string
+	GeoIP                   GeoIPConfig
 	LoadServerState         bool
 	AdminSocket             string
+	AdminSocketPerms        AdminSocketPermsConfig
 	External                ExternalConfig
 	Healthz                 HealthzConfig
 	Master                  MasterConfig
 	MatchOrder              []MatchType
+	NormalizeURI            []string
+	Peers                   PeersConfig
 	Prometheus              PromConfig
 	Security                SecurityConfig
 	Stats                   StatsConfig
+	Tune                    TuneConfig
+	ClientTCPKeepAlive      bool
 	CloseSessionsDuration   time.Duration
 	TimeoutStopDuration     time.Duration
 	StrictHost              bool
+	UniqueIDFormat          string
+	UniqueIDHeader          string
 	UseHTX                  bool
 	UseZipkin               bool
 	DefaultBackendRedir     string
@@ -88,6 +100,19 @@ type Global struct {
 	CustomTCP               []string
 }
 
+// AdminSocketPermsConfig ...
+type AdminSocketPermsConfig struct {
+	Group string
+	Mode  string
+	User  string
+}
+
+// TuneConfig ...
+type TuneConfig struct {
+	H2MaxConcurrentStreams int
+	MaxAccept              int
+}
+
 // GlobalBindConfig ...
 type GlobalBindConfig struct {
 	AcceptProxy      bool
@@ -99,6 +124,12 @@ type GlobalBindConfig struct {
 	FrontingUseProto bool
 }
 
+// BucketConfig ...
+type BucketConfig struct {
+	Cookie string
+	Count  int
+}
+
 // ProcsConfig ...
 type ProcsConfig struct {
 	Nbproc          int
@@ -112,10 +143,12 @@ type ProcsConfig struct {
 
 // SyslogConfig ...
 type SyslogConfig struct {
-	Endpoint string
-	Format   string
-	Length   int
-	Tag      string
+	DontLogNull bool
+	Endpoint    string
+	Format      string
+	Length      int
+	Ring        SyslogRing
+	Tag         string
 	//
 	AuthLogFormat  string
 	HTTPLogFormat  string
@@ -123,6 +156,12 @@ type SyslogConfig struct {
 	TCPLogFormat   string
 }
 
+// SyslogRing ...
+type SyslogRing struct {
+	Name string
+	Size int64
+}
+
 // TimeoutConfig ...
 type TimeoutConfig struct {
 	BackendTimeoutConfig
@@ -142,9 +181,12 @@ type SSLConfig struct {
 	DHParam             DHParamConfig
 	Engine              string
 	HeadersPrefix       string
+	MinVersion          string
 	ModeAsync           bool
 	Options             string
 	RedirectCode        int
+	SessionCacheSize    *int // nil if not configured, 0 disables the cache
+	SessionLifetime     string
 }
 
 // DHParamConfig ...
@@ -164,8 +206,14 @@ type DNSResolver struct {
 	Name                string
 	Nameservers         []*DNSNameserver
 	AcceptedPayloadSize int
+	HoldNX              string
 	HoldObsolete        string
+	HoldOther           string
+	HoldRefused         string
+	HoldTimeout         string
 	HoldValid           string
+	ResolveRetries      int
+	TimeoutResolve      string
 	TimeoutRetry        string
 }
 
@@ -175,6 +223,18 @@ type DNSNameserver struct {
 	Endpoint string
 }
 
+// PeersConfig ...
+type PeersConfig struct {
+	SectionName string
+	Peers       []*Peer
+}
+
+// Peer ...
+type Peer struct {
+	Name     string
+	Endpoint string
+}
+
 // ModSecurityConfig ...
 type ModSecurityConfig struct {
 	Endpoints []string
@@ -198,6 +258,18 @@ type ExternalConfig struct {
 	MasterSocket string
 }
 
+// ErrorFile ...
+type ErrorFile struct {
+	Code     int
+	Filename string
+}
+
+// GeoIPConfig ...
+type GeoIPConfig struct {
+	ActionMapFile  string
+	CountryMapFile string
+}
+
 // HealthzConfig ...
 type HealthzConfig struct {
 	BindIP string
@@ -255,11 +327,17 @@ type TCPServicePort struct {
 	port         int
 	hosts        map[string]*TCPServiceHost
 	defaultHost  *TCPServiceHost
+	AllowedIP    AccessConfig
 	CustomConfig []string
+	InspectDelay string
 	LogFormat    string
 	ProxyProt    bool
+	SetDst       string
+	SetDstPort   string
 	TLS          TLSConfig
 	//
+	TimeoutClientFin string
+	//
 	SNIMap *HostsMap
 }
 
@@ -279,6 +357,8 @@ type TLSConfig struct {
 	CipherSuites     string
 	CRLFilename      string
 	CRLHash          string
+	EarlyData        bool
+	MinVersion       string
 	Options          string
 	TLSCommonName    string
 	TLSFilename      string
@@ -370,8 +450,13 @@ type FrontendMaps struct {
 	HTTPSSNIMap  *HostsMap
 	//
 	RedirFromRootMap  *HostsMap
+	RedirPathPfxMap   *HostsMap
 	RedirFromMap      *HostsMap
 	RedirToMap        *HostsMap
+	RedirToApexList   *HostsMap
+	RedirToWWWList    *HostsMap
+	BucketMap         *HostsMap
+	CSPMap            *HostsMap
 	SSLPassthroughMap *HostsMap
 	VarNamespaceMap   *HostsMap
 	//
@@ -413,8 +498,43 @@ type Frontend struct {
 	DefaultCrtHash string
 	CrtListFile    string
 	//
+	BlockedPaths     []string
+	BlockedPathsFile string
+	//
+	Description      string
+	HTTPIgnoreProbes bool
+	MaxConn          int
 	RedirectFromCode int
 	RedirectToCode   int
+	SocketStats      bool
+	//
+	CanaryRoutes   []*CanaryRoute
+	FallbackRoutes []*FallbackRoute
+	HeaderRoutes   []*HeaderRoute
+}
+
+// CanaryRoute ...
+type CanaryRoute struct {
+	Backend   string
+	Canary    string
+	Weight    int
+	BaseRegex string
+}
+
+// FallbackRoute ...
+type FallbackRoute struct {
+	Backend   string
+	Fallback  string
+	BaseRegex string
+}
+
+// HeaderRoute ...
+type HeaderRoute struct {
+	ID        string
+	Backend   string
+	BaseRegex string
+	HTTPS     bool
+	Match     HeaderMatch
 }
 
 // DefaultHost ...
@@ -439,6 +559,9 @@ type Host struct {
 	Paths    []*HostPath
 	//
 	Alias                  HostAliasConfig
+	Buckets                []*HostBucket
+	CanonicalPathPrefix    string
+	ContentSecurityPolicy  string
 	Redirect               HostRedirectConfig
 	HTTPPassthroughBackend string
 	RootRedirect           string
@@ -503,10 +626,18 @@ type HostAliasConfig struct {
 	AliasRegex string
 }
 
+// HostBucket ...
+type HostBucket struct {
+	First   int
+	Last    int
+	Backend string
+}
+
 // HostRedirectConfig ...
 type HostRedirectConfig struct {
 	RedirectHost      string
 	RedirectHostRegex string
+	WWW               bool
 }
 
 // HostTLSConfig ...
@@ -580,23 +711,42 @@ type Backend struct {
 	//
 	// per backend config
 	//
-	AgentCheck       AgentCheck
-	AllowedIPTCP     AccessConfig
-	BalanceAlgorithm string
-	BlueGreen        BlueGreenConfig
-	Cookie           Cookie
-	CustomConfig     []string
-	DeniedIPTCP      AccessConfig
-	Dynamic          DynBackendConfig
-	EpCookieStrategy EndpointCookieStrategy
-	Headers          []*BackendHeader
-	HealthCheck      HealthCheck
-	Limit            BackendLimit
-	ModeTCP          bool
-	Resolver         string
-	Server           ServerConfig
-	Timeout          BackendTimeoutConfig
-	TLS              BackendTLSConfig
+	AgentCheck         AgentCheck
+	AllowedIPTCP       AccessConfig
+	BalanceAlgorithm   string
+	BlueGreen          BlueGreenConfig
+	Cache              BackendCache
+	ConnectionMode     string
+	Cookie             Cookie
+	CustomConfig       []string
+	DeniedIPTCP        AccessConfig
+	Description        string
+	Dynamic            DynBackendConfig
+	EpCookieStrategy   EndpointCookieStrategy
+	FastCGI            BackendFastCGI
+	ForwardForDisabled bool
+	HashType           string
+	Headers            []*BackendHeader
+	HeadersRemove      []string
+	HeadersReplace     []*BackendHeaderReplace
+	HealthCheck        HealthCheck
+	IndependentStreams bool
+	InitAddr           string
+	Limit              BackendLimit
+	ModeTCP            bool
+	Persist            BackendPersist
+	Redispatch         BackendRedispatch
+	RequiredHeader     string
+	ResolveDst         BackendResolveDst
+	Resolver           string
+	ResponseStatus     []*BackendResponseStatus
+	Server             ServerConfig
+	SourceAffinity     SourceAffinity
+	TCPKeepAlive       bool
+	Timeout            BackendTimeoutConfig
+	TLS                BackendTLSConfig
+	UpstreamHost       string
+	WaitForBody        BackendWaitForBody
 }
 
 // Endpoint ...
@@ -647,16 +797,46 @@ type BackendPath struct {
 	//
 	// config fields
 	//
-	AllowedIPHTTP AccessConfig
-	AuthHTTP      AuthHTTP
-	AuthExternal  AuthExternal
-	Cors          Cors
-	DeniedIPHTTP  AccessConfig
-	HSTS          HSTS
-	MaxBodySize   int64
-	RewriteURL    string
-	SSLRedirect   bool
-	WAF           WAF
+	AllowedIPHTTP   AccessConfig
+	AllowedMethods  []string
+	AuthHTTP        AuthHTTP
+	AuthExternal    AuthExternal
+	Canary          BackendCanary
+	Cors            Cors
+	DeniedIPHTTP    AccessConfig
+	FallbackBackend string
+	HSTS            HSTS
+	HeaderMatch     HeaderMatch
+	LogLevel        string
+	MaxBodySize     int64
+	Priority        Priority
+	QoS             QoS
+	RewriteURL      string
+	SSLRedirect     bool
+	StaticResponse  StaticResponse
+	WAF             WAF
+}
+
+// BackendCanary ...
+type BackendCanary struct {
+	Backend string
+	Weight  int
+}
+
+// StaticResponse ...
+type StaticResponse struct {
+	Status      int
+	ContentType string
+	// Body is escaped and should be rendered between double quotes
+	Body string
+	File string
+}
+
+// HeaderMatch ...
+type HeaderMatch struct {
+	Name  string
+	Value string
+	Regex bool
 }
 
 // BackendHeader ...
@@ -665,6 +845,19 @@ type BackendHeader struct {
 	Value string
 }
 
+// BackendHeaderReplace ...
+type BackendHeaderReplace struct {
+	Name    string
+	Match   string
+	Replace string
+}
+
+// BackendResponseStatus ...
+type BackendResponseStatus struct {
+	Code      int
+	Condition string
+}
+
 // AgentCheck ...
 type AgentCheck struct {
 	Addr     string
@@ -683,18 +876,71 @@ type DynBackendConfig struct {
 // HealthCheck ...
 type HealthCheck struct {
 	Addr      string
+	Disabled  bool
 	FallCount int
 	Interval  string
+	Log       bool
 	Port      int
 	RiseCount int
+	SNI       string
+	SSLMode   string
 	URI       string
 }
 
+// BackendCache ...
+type BackendCache struct {
+	Name          string
+	MaxAge        int
+	MaxObjectSize int64
+	TotalMaxSize  int
+}
+
+// BackendFastCGI ...
+type BackendFastCGI struct {
+	Name     string
+	DocRoot  string
+	Index    string
+	PathInfo string
+}
+
 // BackendLimit ...
 type BackendLimit struct {
-	Connections int
-	RPS         int
-	Whitelist   []string
+	Action        string
+	Connections   int
+	DenyStatus    int
+	GPC0Increment string
+	GPC0Threshold int
+	PathRPS       int
+	RPS           int
+	SessionKey    string
+	SessionRPS    int
+	Whitelist     []string
+}
+
+// BackendPersist ...
+type BackendPersist struct {
+	Enabled bool
+	Force   []string
+	Ignore  []string
+}
+
+// BackendRedispatch ...
+type BackendRedispatch struct {
+	Interval int
+	Retries  int
+}
+
+// BackendResolveDst ...
+type BackendResolveDst struct {
+	Domains  []string
+	Header   string
+	Resolver string
+}
+
+// BackendWaitForBody ...
+type BackendWaitForBody struct {
+	AtLeast int64
+	Time    string
 }
 
 // AccessConfig ...
@@ -702,6 +948,7 @@ type AccessConfig struct {
 	Rule         []string
 	Exception    []string
 	SourceHeader string
+	DenyStatus   int
 }
 
 // ServerConfig ...
@@ -718,6 +965,8 @@ type ServerConfig struct {
 	MaxConn       int
 	MaxQueue      int
 	Options       string
+	PoolMaxConn   *int // nil if not configured, 0 is a valid value
+	PoolPurge     string
 	Protocol      string
 	Secure        bool
 	SendProxy     string
@@ -727,12 +976,14 @@ type ServerConfig struct {
 
 // BackendTimeoutConfig ...
 type BackendTimeoutConfig struct {
+	Check       string
 	Connect     string
 	HTTPRequest string
 	KeepAlive   string
 	Queue       string
 	Server      string
 	ServerFin   string
+	Tarpit      string
 	Tunnel      string
 }
 
@@ -760,6 +1011,12 @@ type Cookie struct {
 	Keywords string
 }
 
+// SourceAffinity ...
+type SourceAffinity struct {
+	Expire string
+	Size   string
+}
+
 // AuthExternal ...
 type AuthExternal struct {
 	AllowedPath     string
@@ -800,6 +1057,18 @@ type HSTS struct {
 	Preload    bool
 }
 
+// Priority ...
+type Priority struct {
+	Class  int
+	Offset int
+}
+
+// QoS ...
+type QoS struct {
+	Mark *int64 // nil if not configured, 0 is a valid value
+	TOS  *int64 // nil if not configured, 0 is a valid value
+}
+
 // WAF Defines the WAF Config structure for the Backend
 type WAF struct {
 	// Mode defines On or DetectionOnly
@@ -17,7 +17,7 @@
 #
 # A script to help with haproxy reloads. Needs sudo if haproxy uses :80 / :443.
 #
-# ./haproxy-reload.sh <strategy> <cfg> [<need-state>]
+# ./haproxy-reload.sh <strategy> <cfg> [<need-state> [<socket>]]
 #
 # <strategy>: `native`
 #    Uses native HAProxy soft restart. Running it for the first time starts
@@ -30,6 +30,10 @@
 #
 # <need-state>: optional, defaults to `false`, anything != 0 means `true`
 #
+# <socket>: optional, admin socket with `expose-fd listeners`, used to read
+#    the server state and the listening sockets from the old HAProxy process.
+#    Defaults to `/var/run/haproxy/admin.sock`
+#
 # HAProxy options:
 #  -f config file
 #  -p pid file
@@ -44,8 +48,9 @@ set -e
 PARAM_STRATEGY="$1"
 PARAM_CFG="$2"
 PARAM_STATE="${3:-0}"
+PARAM_SOCKET="${4:-/var/run/haproxy/admin.sock}"
 
-HAPROXY_SOCKET=/var/run/haproxy/admin.sock
+HAPROXY_SOCKET="$PARAM_SOCKET"
 HAPROXY_STATE=/var/lib/haproxy/state-global
 HAPROXY_PID=/var/run/haproxy/haproxy.pid
 OLD_PID=$(cat "$HAPROXY_PID" 2>/dev/null || :)
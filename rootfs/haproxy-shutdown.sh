@@ -0,0 +1,39 @@
+#!/bin/sh
+#
+# Copyright 2021 The HAProxy Ingress Controller Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+#
+# A script to help with haproxy graceful shutdown.
+#
+# ./haproxy-shutdown.sh
+#
+# Sends a soft-stop signal (SIGUSR1) to the running HAProxy processes, so
+# they stop listening and finish handling the remaining connections, and
+# waits for all of them to exit.
+#
+
+HAPROXY_PID=/var/run/haproxy/haproxy.pid
+PIDS=$(cat "$HAPROXY_PID" 2>/dev/null || :)
+
+if [ -z "$PIDS" ]; then
+    exit 0
+fi
+
+kill -USR1 $PIDS 2>/dev/null || :
+for pid in $PIDS; do
+    while kill -0 "$pid" 2>/dev/null; do
+        sleep 1
+    done
+done